@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"time"
+)
+
+// http://gafferongames.com/game-physics/fix-your-timestep/
+type App struct {
+	renderer *Renderer
+
+	// callbacks
+	updateCallback func(delta time.Duration) // fixed timestep simulation
+	renderCallback func(alpha float64)       // before every rendered frame
+
+	// timing
+	timestep     time.Duration
+	maxFrameTime time.Duration
+	frameLimit   time.Duration
+	frameTime    time.Duration // fixed, for offline capturing
+	timeScale    float64
+	paused       bool
+	accumulator  time.Duration
+
+	stats FrameStats
+}
+
+type FrameStats struct {
+	Frames  uint64 // rendered frames
+	Updates uint64 // simulation steps
+
+	FrameTime     time.Duration // duration of the last frame
+	MeanFrameTime time.Duration // smoothed frame duration
+	FPS           float64       // based on MeanFrameTime
+
+	Time  time.Duration // simulated time
+	Alpha float64       // interpolation factor between the last two simulation steps
+}
+
+func NewApp(r *Renderer) *App {
+	return &App{
+		renderer: r,
+
+		timestep:     time.Second / 60,
+		maxFrameTime: time.Second / 4,
+		timeScale:    1.0,
+	}
+}
+
+func (a *App) SetUpdateCallback(f func(delta time.Duration)) {
+	a.updateCallback = f
+}
+
+func (a *App) SetRenderCallback(f func(alpha float64)) {
+	a.renderCallback = f
+}
+
+func (a *App) SetTimestep(d time.Duration) {
+	if d > 0 {
+		a.timestep = d
+	}
+}
+
+func (a *App) Timestep() time.Duration {
+	return a.timestep
+}
+
+// frames longer than d are clamped to avoid a spiral of death after stalls
+func (a *App) SetMaxFrameTime(d time.Duration) {
+	a.maxFrameTime = d
+}
+
+func (a *App) SetVSync(b bool) {
+	a.renderer.SetVSync(b)
+}
+
+// limit rendering to fps frames per second, 0 disables the limit
+func (a *App) SetFrameLimit(fps float64) {
+	if fps <= 0 {
+		a.frameLimit = 0
+		return
+	}
+
+	a.frameLimit = time.Duration(float64(time.Second) / fps)
+}
+
+// advance every frame by d instead of the measured time, for frame capturing at a
+// constant rate independent of the rendering speed, 0 uses the real time
+func (a *App) SetFixedFrameTime(d time.Duration) {
+	a.frameTime = d
+}
+
+func (a *App) Pause() {
+	a.paused = true
+}
+
+func (a *App) Resume() {
+	a.paused = false
+}
+
+func (a *App) Paused() bool {
+	return a.paused
+}
+
+func (a *App) SetTimeScale(s float64) {
+	if s >= 0 {
+		a.timeScale = s
+	}
+}
+
+func (a *App) TimeScale() float64 {
+	return a.timeScale
+}
+
+func (a *App) Stats() FrameStats {
+	return a.stats
+}
+
+// main loop, returns when the window is closed
+func (a *App) Run() {
+	last := time.Now()
+
+	for a.renderer.Running() {
+		start := time.Now()
+		if a.frameTime > 0 {
+			a.tick(a.frameTime)
+		} else {
+			a.tick(start.Sub(last))
+		}
+		last = start
+
+		// draw
+		if a.renderCallback != nil {
+			a.renderCallback(a.stats.Alpha)
+		}
+		a.renderer.Render()
+
+		// frame limiter
+		if a.frameLimit > 0 {
+			if d := a.frameLimit - time.Since(start); d > 0 {
+				time.Sleep(d)
+			}
+		}
+	}
+}
+
+// advance the simulation by the duration of the last frame
+func (a *App) tick(frame time.Duration) {
+	if a.maxFrameTime > 0 && frame > a.maxFrameTime {
+		frame = a.maxFrameTime
+	}
+
+	// stats
+	a.stats.Frames++
+	a.stats.FrameTime = frame
+	if a.stats.MeanFrameTime == 0 {
+		a.stats.MeanFrameTime = frame
+	} else {
+		a.stats.MeanFrameTime = time.Duration(float64(a.stats.MeanFrameTime)*0.9 + float64(frame)*0.1)
+	}
+	if a.stats.MeanFrameTime > 0 {
+		a.stats.FPS = float64(time.Second) / float64(a.stats.MeanFrameTime)
+	}
+
+	if a.paused {
+		return
+	}
+
+	// fixed timestep updates
+	a.accumulator += time.Duration(float64(frame) * a.timeScale)
+
+	for a.accumulator >= a.timestep {
+		if a.updateCallback != nil {
+			a.updateCallback(a.timestep)
+		}
+
+		a.accumulator -= a.timestep
+		a.stats.Updates++
+		a.stats.Time += a.timestep
+	}
+
+	a.stats.Alpha = float64(a.accumulator) / float64(a.timestep)
+}
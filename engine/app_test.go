@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApp_tick(t *testing.T) {
+	a := NewApp(nil)
+	a.SetTimestep(10 * time.Millisecond)
+
+	var updates int
+	a.SetUpdateCallback(func(delta time.Duration) {
+		if delta != 10*time.Millisecond {
+			t.Errorf("update delta != 10ms (got %v)", delta)
+		}
+		updates++
+	})
+
+	tests := []struct {
+		Frame      time.Duration
+		Paused     bool
+		Scale      float64
+		ExpUpdates int
+		ExpAlpha   float64
+	}{
+		{5 * time.Millisecond, false, 1, 0, 0.5},
+		{5 * time.Millisecond, false, 1, 1, 0},
+		{25 * time.Millisecond, false, 1, 2, 0.5},
+		{40 * time.Millisecond, true, 1, 0, 0.5},
+		{10 * time.Millisecond, false, 0.5, 1, 0},
+		{10 * time.Millisecond, false, 2, 2, 0},
+		{time.Second, false, 1, 25, 0}, // clamped to 250ms
+	}
+
+	for i, c := range tests {
+		updates = 0
+		a.paused = c.Paused
+		a.SetTimeScale(c.Scale)
+
+		a.tick(c.Frame)
+		if updates != c.ExpUpdates {
+			t.Errorf("%v: tick(%v) ran %v updates, expected %v", i, c.Frame, updates, c.ExpUpdates)
+		}
+		if a.stats.Alpha != c.ExpAlpha {
+			t.Errorf("%v: tick(%v) alpha != %v (got %v)", i, c.Frame, c.ExpAlpha, a.stats.Alpha)
+		}
+	}
+
+	if s := a.Stats(); s.Frames != uint64(len(tests)) || s.Time != 310*time.Millisecond {
+		t.Errorf("Stats() != %v frames, 310ms (got %v, %v)", len(tests), s.Frames, s.Time)
+	}
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"errors"
+	"image"
+
+	"github.com/go-gl/gl"
+)
+
+// stack of equally sized images uploaded with TexImage3D
+type layeredTexture struct {
+	sampler
+
+	target      gl.GLenum
+	buffer      gl.Texture
+	initialized bool
+
+	width, height, depth int
+	pix                  []byte // rgba, layer after layer
+}
+
+func newLayeredTexture(target gl.GLenum, s sampler, layers []image.Image) (*layeredTexture, error) {
+	if len(layers) == 0 {
+		return nil, errors.New("no layers")
+	}
+
+	t := &layeredTexture{
+		sampler: s,
+		target:  target,
+		width:   layers[0].Bounds().Dx(),
+		height:  layers[0].Bounds().Dy(),
+		depth:   len(layers),
+	}
+
+	size := t.width * t.height * 4
+	t.pix = make([]byte, 0, size*t.depth)
+
+	for _, l := range layers {
+		if l.Bounds().Dx() != t.width || l.Bounds().Dy() != t.height {
+			return nil, errors.New("layers have to be of the same size")
+		}
+		t.pix = append(t.pix, toRGBA(l).Pix...)
+	}
+
+	return t, nil
+}
+
+func (t *layeredTexture) Size() (w, h, d int) {
+	return t.width, t.height, t.depth
+}
+
+// replace the content of a single layer
+func (t *layeredTexture) SetLayer(i int, img image.Image) error {
+	if i < 0 || i >= t.depth {
+		return errors.New("layer out of range")
+	}
+	if img.Bounds().Dx() != t.width || img.Bounds().Dy() != t.height {
+		return errors.New("layers have to be of the same size")
+	}
+
+	size := t.width * t.height * 4
+	copy(t.pix[i*size:(i+1)*size], toRGBA(img).Pix)
+	t.needsUpdate = true
+
+	return nil
+}
+
+func (t *layeredTexture) init() {
+	t.buffer = gl.GenTexture()
+
+	t.initialized = true
+}
+
+func (t *layeredTexture) update() {
+	if !t.initialized {
+		t.init()
+	}
+
+	t.buffer.Bind(t.target)
+	t.apply(t.target)
+
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexImage3D(t.target, 0, t.internalFormat(),
+		t.width, t.height, t.depth,
+		0, gl.RGBA, gl.UNSIGNED_BYTE, t.pix)
+
+	if t.mipmapped() {
+		gl.GenerateMipmap(t.target)
+	}
+
+	t.needsUpdate = false
+}
+
+func (t *layeredTexture) Dispose() {
+	if t.buffer != 0 {
+		t.buffer.Delete()
+	}
+}
+
+func (t *layeredTexture) Bind(slot int) {
+	gl.ActiveTexture(gl.TEXTURE0 + gl.GLenum(slot))
+
+	if t.needsUpdate {
+		t.update()
+	} else {
+		t.buffer.Bind(t.target)
+	}
+}
+
+func (t *layeredTexture) Unbind() {
+	if t.initialized {
+		t.buffer.Unbind(t.target)
+	}
+}
+
+// sampler2DArray, layers are selected by the third texture coordinate without filtering
+// between them, e.g. terrain splatting or sprite atlases
+type ArrayTexture struct {
+	*layeredTexture
+}
+
+func NewArrayTexture(layers []image.Image) (*ArrayTexture, error) {
+	t, err := newLayeredTexture(gl.TEXTURE_2D_ARRAY, newSampler(RepeatWrapping, LinearMipMapLinearFilter), layers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ArrayTexture{t}, nil
+}
+
+// sampler3D, slices are interpolated, e.g. color grading lookup tables or volumes
+type VolumeTexture struct {
+	*layeredTexture
+}
+
+func NewVolumeTexture(slices []image.Image) (*VolumeTexture, error) {
+	t, err := newLayeredTexture(gl.TEXTURE_3D, newSampler(ClampToEdgeWrapping, LinearFilter), slices)
+	if err != nil {
+		return nil, err
+	}
+
+	// lookup tables store data
+	t.colorSpace = Linear
+
+	return &VolumeTexture{t}, nil
+}
+
+// identity color lookup table of size³ entries, red along x, green along y, blue along the slices
+func NewColorLUT(size int) *VolumeTexture {
+	if size < 2 {
+		size = 2
+	}
+	slices := make([]image.Image, size)
+
+	for b := range slices {
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				o := img.PixOffset(r, g)
+				img.Pix[o+0] = uint8(r * 255 / (size - 1))
+				img.Pix[o+1] = uint8(g * 255 / (size - 1))
+				img.Pix[o+2] = uint8(b * 255 / (size - 1))
+				img.Pix[o+3] = 255
+			}
+		}
+		slices[b] = img
+	}
+
+	t, _ := NewVolumeTexture(slices)
+	return t
+}
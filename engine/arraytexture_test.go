@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"image"
+	"testing"
+)
+
+func TestNewArrayTexture(t *testing.T) {
+	square := func(s int) image.Image { return image.NewRGBA(image.Rect(0, 0, s, s)) }
+
+	tests := []struct {
+		layers []image.Image
+		err    bool
+	}{
+		{[]image.Image{square(4), square(4), square(4)}, false},
+		{[]image.Image{square(4), square(8)}, true},
+		{nil, true},
+	}
+
+	for _, c := range tests {
+		tex, err := NewArrayTexture(c.layers)
+		if (err != nil) != c.err {
+			t.Errorf("NewArrayTexture(%v layers) error %v (got %v)", len(c.layers), c.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if w, h, d := tex.Size(); w != 4 || h != 4 || d != len(c.layers) || len(tex.pix) != w*h*d*4 {
+			t.Errorf("NewArrayTexture(%v layers).Size() != 4, 4, %v (got %v, %v, %v)", len(c.layers), len(c.layers), w, h, d)
+		}
+		if err := tex.SetLayer(len(c.layers), square(4)); err == nil {
+			t.Errorf("SetLayer(%v) out of range != error", len(c.layers))
+		}
+	}
+
+	if _, err := NewCubeTexture([6]image.Image{square(4), square(4), square(4), square(4), square(4), square(2)}); err == nil {
+		t.Errorf("NewCubeTexture(mixed sizes) != error")
+	}
+	if _, err := NewCubeTexture([6]image.Image{nil, square(4), square(4), square(4), square(4), square(4)}); err == nil {
+		t.Errorf("NewCubeTexture(missing face) != error")
+	}
+}
+
+func TestNewColorLUT(t *testing.T) {
+	lut := NewColorLUT(16)
+	size := 16 * 16 * 4
+
+	// last slice, last entry is white
+	if p := lut.pix[len(lut.pix)-4:]; p[0] != 255 || p[1] != 255 || p[2] != 255 {
+		t.Errorf("NewColorLUT(16) last entry != white (got %v)", p)
+	}
+	// slice 15, first row, entry 1
+	if p := lut.pix[15*size+4:]; p[0] != 17 || p[1] != 0 || p[2] != 255 {
+		t.Errorf("NewColorLUT(16) entry 1,0,15 != 17,0,255 (got %v)", p[:3])
+	}
+	if lut.ColorSpace() != Linear {
+		t.Errorf("NewColorLUT(16) is not linear")
+	}
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"image"
+	"sort"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+const atlasMinSize = 64
+
+// position of a rectangle in the pages of an atlas
+type atlasSlot struct {
+	page, x, y int
+}
+
+// indices of rectangles, highest and then widest first
+type atlasOrder struct {
+	sizes   []image.Point
+	indices []int
+}
+
+func (o atlasOrder) Len() int      { return len(o.indices) }
+func (o atlasOrder) Swap(i, j int) { o.indices[i], o.indices[j] = o.indices[j], o.indices[i] }
+func (o atlasOrder) Less(i, j int) bool {
+	a, b := o.sizes[o.indices[i]], o.sizes[o.indices[j]]
+	if a.Y != b.Y {
+		return a.Y > b.Y
+	}
+	return a.X > b.X
+}
+
+// shelf packing of rectangles, sorted by height, into power of two pages that grow up to
+// maxSize before further pages are started
+func packAtlas(sizes []image.Point, maxSize int) (slots []atlasSlot, pages []image.Point) {
+	slots = make([]atlasSlot, len(sizes))
+
+	order := atlasOrder{sizes: sizes, indices: make([]int, len(sizes))}
+	for i := range order.indices {
+		order.indices[i] = i
+	}
+	sort.Stable(order)
+
+	remaining := order.indices
+	for len(remaining) > 0 {
+		page := len(pages)
+		size := atlasMinSize
+
+		var rest []int
+		var height int
+		for {
+			rest, height = packShelves(sizes, remaining, size, page, slots)
+			if len(rest) == 0 || size >= maxSize {
+				break
+			}
+			size *= 2
+		}
+
+		if len(rest) == len(remaining) {
+			// larger than a page, gets its own
+			s := sizes[remaining[0]]
+			slots[remaining[0]] = atlasSlot{page, 0, 0}
+			pages = append(pages, image.Point{math.NextHighestPowerOfTwo(s.X), math.NextHighestPowerOfTwo(s.Y)})
+			remaining = remaining[1:]
+			continue
+		}
+
+		// cut unused rows
+		h := size
+		for h/2 >= height && h/2 >= atlasMinSize {
+			h /= 2
+		}
+		pages = append(pages, image.Point{size, h})
+		remaining = rest
+	}
+
+	return slots, pages
+}
+
+// fills one square page row by row, returns what did not fit and the used height
+func packShelves(sizes []image.Point, order []int, size, page int, slots []atlasSlot) (rest []int, height int) {
+	var x, y, shelf int
+
+	for _, i := range order {
+		s := sizes[i]
+
+		if x+s.X > size {
+			// next shelf
+			if y+shelf+s.Y > size || s.X > size {
+				rest = append(rest, i)
+				continue
+			}
+			x, y, shelf = 0, y+shelf, 0
+		}
+		if y+s.Y > size {
+			rest = append(rest, i)
+			continue
+		}
+
+		slots[i] = atlasSlot{page, x, y}
+		x += s.X
+		if s.Y > shelf {
+			shelf = s.Y
+		}
+		if y+shelf > height {
+			height = y + shelf
+		}
+	}
+
+	return rest, height
+}
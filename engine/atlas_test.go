@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+)
+
+func TestPackAtlas(t *testing.T) {
+	random := func(n, max int) []image.Point {
+		r := rand.New(rand.NewSource(int64(n)))
+		sizes := make([]image.Point, n)
+		for i := range sizes {
+			sizes[i] = image.Point{1 + r.Intn(max), 1 + r.Intn(max)}
+		}
+		return sizes
+	}
+
+	tests := []struct {
+		sizes   []image.Point
+		maxSize int
+		pages   int
+	}{
+		{[]image.Point{{10, 10}}, 1024, 1},
+		{[]image.Point{{64, 64}, {64, 64}, {64, 64}, {64, 64}}, 128, 1},
+		{[]image.Point{{64, 64}, {64, 64}, {64, 64}, {64, 64}, {1, 1}}, 128, 2},
+		{[]image.Point{{300, 20}}, 256, 1}, // larger than a page
+		{[]image.Point{{0, 0}, {5, 5}}, 64, 1},
+		{random(95, 40), 1024, 1},
+		{random(2000, 40), 512, 4},
+	}
+
+	for _, c := range tests {
+		slots, pages := packAtlas(c.sizes, c.maxSize)
+
+		if len(pages) != c.pages {
+			t.Errorf("packAtlas(%v rects, %v) pages != %v (got %v)", len(c.sizes), c.maxSize, c.pages, len(pages))
+		}
+		for _, p := range pages {
+			if p.X&(p.X-1) != 0 || p.Y&(p.Y-1) != 0 {
+				t.Errorf("packAtlas(%v rects, %v) page size %v is not a power of two", len(c.sizes), c.maxSize, p)
+			}
+		}
+
+		rects := make([]image.Rectangle, len(slots))
+		for i, s := range slots {
+			rects[i] = image.Rect(s.x, s.y, s.x+c.sizes[i].X, s.y+c.sizes[i].Y)
+			if s.page < 0 || s.page >= len(pages) || !rects[i].In(image.Rectangle{Max: pages[s.page]}) {
+				t.Errorf("packAtlas(%v rects, %v) %v is outside of page %v", len(c.sizes), c.maxSize, rects[i], s.page)
+			}
+		}
+
+		for i := range rects {
+			for j := i + 1; j < len(rects); j++ {
+				if slots[i].page == slots[j].page && rects[i].Overlaps(rects[j]) {
+					t.Errorf("packAtlas(%v rects, %v) %v overlaps %v", len(c.sizes), c.maxSize, rects[i], rects[j])
+				}
+			}
+		}
+	}
+}
+
+func TestFontOptions_Runes(t *testing.T) {
+	latin := []rune("aäöüß")
+
+	tests := []struct {
+		opts     FontOptions
+		length   int
+		contains []rune
+	}{
+		{FontOptions{}.withDefaults(), 95, []rune(" ~Aa0")},
+		{FontOptions{Runes: append(latin, latin...)}, 5, latin},
+		{FontOptions{Runes: []rune{'\n', '\t', 'x'}}, 1, []rune("x")},
+		{FontOptions{Runes: RuneRange(0x400, 0x40f)}, 16, []rune("ЀЏ")},
+	}
+
+	for _, c := range tests {
+		runes := c.opts.runes()
+		if len(runes) != c.length {
+			t.Errorf("runes(%+v) length != %v (got %v)", c.opts, c.length, len(runes))
+		}
+
+		set := make(map[rune]bool)
+		for i, r := range runes {
+			if i > 0 && runes[i-1] >= r {
+				t.Errorf("runes(%+v) are not sorted", c.opts)
+			}
+			set[r] = true
+		}
+		for _, r := range c.contains {
+			if !set[r] {
+				t.Errorf("runes(%+v) is missing %q", c.opts, r)
+			}
+		}
+	}
+}
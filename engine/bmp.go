@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+)
+
+// windows bitmap decoder, uncompressed palette (1/4/8 bit) and
+// true color (16/24/32 bit, optional bitfields) images
+// http://msdn.microsoft.com/en-us/library/dd183391(v=vs.85).aspx
+
+const (
+	bmpRGB       = 0
+	bmpBitfields = 3
+
+	bmpFileHeaderSize = 14
+	bmpMaxDimensions  = 1 << 14 // checked before allocating the image
+)
+
+type bmpFileHeader struct {
+	Magic    [2]byte
+	Size     uint32
+	Reserved uint32
+	Offset   uint32
+}
+
+type bmpInfoHeader struct {
+	Size        uint32
+	Width       int32
+	Height      int32 // negative for top-down images
+	Planes      uint16
+	BitCount    uint16
+	Compression uint32
+	ImageSize   uint32
+	XPerMeter   int32
+	YPerMeter   int32
+	ColorsUsed  uint32
+	Important   uint32
+}
+
+type bmpHeader struct {
+	bmpInfoHeader
+
+	offset   int
+	topDown  bool
+	masks    [4]uint32 // r, g, b, a
+	palette  color.Palette
+	consumed int // bytes read after the file header
+}
+
+func init() {
+	image.RegisterFormat("bmp", "BM", decodeBMP, decodeBMPConfig)
+}
+
+func readBMPHeader(r io.Reader) (*bmpHeader, error) {
+	var fh bmpFileHeader
+	if err := binary.Read(r, binary.LittleEndian, &fh); err != nil {
+		return nil, err
+	}
+	if fh.Magic != [2]byte{'B', 'M'} {
+		return nil, errors.New("bmp: invalid magic number")
+	}
+
+	h := &bmpHeader{offset: int(fh.Offset)}
+	if err := binary.Read(r, binary.LittleEndian, &h.bmpInfoHeader); err != nil {
+		return nil, err
+	}
+
+	// BITMAPINFOHEADER, V2 to V5
+	if h.Size < 40 {
+		return nil, fmt.Errorf("bmp: unsupported header size %v", h.Size)
+	}
+	h.consumed = 40
+
+	if h.Height < 0 {
+		h.Height = -h.Height // stays negative for the minimum int32
+		h.topDown = true
+	}
+	if h.Width <= 0 || h.Height <= 0 || h.Planes != 1 {
+		return nil, errors.New("bmp: invalid dimensions")
+	}
+	if h.Width > bmpMaxDimensions || h.Height > bmpMaxDimensions {
+		return nil, fmt.Errorf("bmp: dimensions %vx%v exceed %v", h.Width, h.Height, bmpMaxDimensions)
+	}
+
+	switch h.Compression {
+	case bmpRGB:
+		switch h.BitCount {
+		case 16:
+			h.masks = [4]uint32{0x7c00, 0x03e0, 0x001f, 0}
+		case 32:
+			h.masks = [4]uint32{0xff0000, 0xff00, 0xff, 0}
+		}
+
+	case bmpBitfields:
+		if h.BitCount != 16 && h.BitCount != 32 {
+			return nil, errors.New("bmp: bitfields need 16 or 32 bits per pixel")
+		}
+
+		// masks follow the 40 byte header or are part of it
+		n := 3
+		if h.Size >= 56 {
+			n = 4
+		}
+		if err := binary.Read(r, binary.LittleEndian, h.masks[:n]); err != nil {
+			return nil, err
+		}
+		h.consumed += 4 * n
+
+	default:
+		return nil, fmt.Errorf("bmp: unsupported compression %v", h.Compression)
+	}
+
+	// skip remaining header
+	if skip := int(h.Size) - h.consumed; skip > 0 {
+		if _, err := io.CopyN(ioutil.Discard, r, int64(skip)); err != nil {
+			return nil, err
+		}
+		h.consumed += skip
+	}
+
+	switch h.BitCount {
+	case 1, 4, 8:
+		n := int(h.ColorsUsed)
+		if n == 0 || n > 1<<h.BitCount {
+			n = 1 << h.BitCount
+		}
+
+		entries := make([]byte, 4*n)
+		if _, err := io.ReadFull(r, entries); err != nil {
+			return nil, err
+		}
+		h.consumed += len(entries)
+
+		h.palette = make(color.Palette, n)
+		for i := range h.palette {
+			e := entries[4*i:]
+			h.palette[i] = color.RGBA{e[2], e[1], e[0], 255}
+		}
+
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("bmp: unsupported bit count %v", h.BitCount)
+	}
+
+	return h, nil
+}
+
+func decodeBMPConfig(r io.Reader) (image.Config, error) {
+	h, err := readBMPHeader(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	var m color.Model = color.NRGBAModel
+	if h.palette != nil {
+		m = h.palette
+	}
+
+	return image.Config{
+		ColorModel: m,
+		Width:      int(h.Width),
+		Height:     int(h.Height),
+	}, nil
+}
+
+func decodeBMP(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+
+	h, err := readBMPHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	// seek to pixel data
+	if skip := h.offset - bmpFileHeaderSize - h.consumed; skip > 0 {
+		if _, err := io.CopyN(ioutil.Discard, br, int64(skip)); err != nil {
+			return nil, err
+		}
+	}
+
+	w, ht := int(h.Width), int(h.Height)
+	stride := ((w*int(h.BitCount) + 31) / 32) * 4 // rows are 4 byte aligned
+	row := make([]byte, stride)
+
+	var img image.Image
+	var paletted *image.Paletted
+	var nrgba *image.NRGBA
+
+	if h.palette != nil {
+		paletted = image.NewPaletted(image.Rect(0, 0, w, ht), h.palette)
+		img = paletted
+	} else {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, w, ht))
+		img = nrgba
+	}
+
+	for y := 0; y < ht; y++ {
+		if _, err := io.ReadFull(br, row); err != nil {
+			return nil, err
+		}
+
+		// default origin is bottom left
+		dy := ht - 1 - y
+		if h.topDown {
+			dy = y
+		}
+
+		if paletted != nil {
+			bits := uint(h.BitCount)
+			mask := byte(1<<bits - 1)
+			d := paletted.Pix[dy*paletted.Stride:]
+
+			for x := 0; x < w; x++ {
+				bit := uint(x) * bits
+				i := row[bit/8] >> (8 - bits - bit%8) & mask
+				if int(i) >= len(h.palette) {
+					i = 0
+				}
+				d[x] = i
+			}
+			continue
+		}
+
+		d := nrgba.Pix[dy*nrgba.Stride:]
+		for x := 0; x < w; x++ {
+			o := d[4*x:]
+
+			switch h.BitCount {
+			case 24:
+				s := row[3*x:]
+				o[0], o[1], o[2], o[3] = s[2], s[1], s[0], 255
+			case 16:
+				h.unpack(uint32(binary.LittleEndian.Uint16(row[2*x:])), o)
+			case 32:
+				h.unpack(binary.LittleEndian.Uint32(row[4*x:]), o)
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// extract channels with bitmasks, missing alpha is opaque
+func (h *bmpHeader) unpack(v uint32, o []byte) {
+	for c, m := range h.masks {
+		if m == 0 {
+			o[c] = 255
+			continue
+		}
+
+		shift := uint(0)
+		for m>>shift&1 == 0 {
+			shift++
+		}
+
+		max := m >> shift
+		o[c] = byte((v & m >> shift) * 255 / max)
+	}
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func testBMP(bits uint16, height int32, compression uint32, extra, pixels []byte) []byte {
+	info := bmpInfoHeader{
+		Size:        40,
+		Width:       2,
+		Height:      height,
+		Planes:      1,
+		BitCount:    bits,
+		Compression: compression,
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, bmpFileHeader{
+		Magic:  [2]byte{'B', 'M'},
+		Offset: uint32(bmpFileHeaderSize + 40 + len(extra)),
+	})
+	binary.Write(&buf, binary.LittleEndian, info)
+	buf.Write(extra)
+	buf.Write(pixels)
+
+	return buf.Bytes()
+}
+
+func TestDecodeBMP(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       []byte
+		topLeft    color.Color
+		bottomLeft color.Color
+	}{
+		{
+			"24 bit bottom up",
+			testBMP(24, 2, bmpRGB, nil, []byte{
+				255, 0, 0, 255, 0, 0, 0, 0, // blue row, padding
+				0, 0, 255, 0, 0, 255, 0, 0, // red row
+			}),
+			color.NRGBA{255, 0, 0, 255},
+			color.NRGBA{0, 0, 255, 255},
+		},
+		{
+			"32 bit top down",
+			testBMP(32, -2, bmpRGB, nil, []byte{
+				0, 255, 0, 0, 0, 255, 0, 0,
+				0, 0, 255, 0, 0, 0, 255, 0,
+			}),
+			color.NRGBA{0, 255, 0, 255},
+			color.NRGBA{255, 0, 0, 255},
+		},
+		{
+			"16 bit bitfields 565",
+			testBMP(16, 2, bmpBitfields, []byte{0x00, 0xf8, 0, 0, 0xe0, 0x07, 0, 0, 0x1f, 0, 0, 0}, []byte{
+				0x1f, 0x00, 0x1f, 0x00, // blue
+				0xe0, 0x07, 0xe0, 0x07, // green
+			}),
+			color.NRGBA{0, 255, 0, 255},
+			color.NRGBA{0, 0, 255, 255},
+		},
+		{
+			"4 bit palette",
+			testBMP(4, 2, bmpRGB, append(make([]byte, 4), append([]byte{0, 0, 255, 0}, make([]byte, 56)...)...), []byte{
+				0x01, 0, 0, 0, // black, red
+				0x10, 0, 0, 0, // red, black
+			}),
+			color.RGBA{255, 0, 0, 255},
+			color.RGBA{0, 0, 0, 255},
+		},
+	}
+
+	for _, c := range tests {
+		img, format, err := image.Decode(bytes.NewReader(c.file))
+		if err != nil || format != "bmp" {
+			t.Errorf("image.Decode(%v) != bmp (got %v, %v)", c.name, format, err)
+			continue
+		}
+
+		if tl := img.At(0, 0); tl != c.topLeft {
+			t.Errorf("%v top left != %v (got %v)", c.name, c.topLeft, tl)
+		}
+		if bl := img.At(0, 1); bl != c.bottomLeft {
+			t.Errorf("%v bottom left != %v (got %v)", c.name, c.bottomLeft, bl)
+		}
+	}
+}
+
+func TestDecodeBMP_Dimensions(t *testing.T) {
+	wide := testBMP(24, 2, bmpRGB, nil, nil)
+	binary.LittleEndian.PutUint32(wide[bmpFileHeaderSize+4:], 1<<20)
+
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"minimum height", testBMP(24, -1<<31, bmpRGB, nil, nil)},
+		{"zero height", testBMP(24, 0, bmpRGB, nil, nil)},
+		{"oversized", testBMP(24, 1<<20, bmpRGB, nil, nil)},
+		{"oversized width", wide},
+	}
+
+	for _, c := range tests {
+		if _, _, err := image.Decode(bytes.NewReader(c.file)); err == nil {
+			t.Errorf("image.Decode(%v) != error", c.name)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+package engine
+
+import (
+	"github.com/der-antikeks/gisp/math"
+)
+
+// dynamic bounding volume hierarchy of renderables, leaf boxes are inflated
+// so that small movements do not require a reinsertion
+// http://box2d.org/ (b2DynamicTree)
+
+const (
+	bvhNull   = -1
+	bvhMargin = 0.1 // leaf inflation, relative to the bounding sphere radius
+)
+
+type bvhNode struct {
+	bounds math.Boundary
+
+	// hierarchy
+	parent      int // next free node if unused
+	left, right int
+	height      int // leaf 0, free -1
+
+	// leaf cache
+	object Renderable
+	center math.Vector // world bounding sphere
+	radius float64
+	dirty  bool
+}
+
+func (n *bvhNode) isLeaf() bool {
+	return n.left == bvhNull
+}
+
+type bvh struct {
+	nodes []bvhNode
+	root  int
+	free  int
+
+	leaves  map[Renderable]int // bvhNull if pending
+	pending []Renderable       // objects without geometry
+	dirty   []int              // moved leaves
+
+	stack []int // traversal cache
+}
+
+func newBVH() *bvh {
+	return &bvh{
+		root:   bvhNull,
+		free:   bvhNull,
+		leaves: make(map[Renderable]int),
+	}
+}
+
+func (t *bvh) allocate() int {
+	if t.free == bvhNull {
+		t.nodes = append(t.nodes, bvhNode{})
+		t.free = len(t.nodes) - 1
+		t.nodes[t.free].parent = bvhNull
+	}
+
+	i := t.free
+	t.free = t.nodes[i].parent
+	t.nodes[i] = bvhNode{
+		parent: bvhNull,
+		left:   bvhNull,
+		right:  bvhNull,
+	}
+
+	return i
+}
+
+func (t *bvh) release(i int) {
+	t.nodes[i] = bvhNode{
+		parent: t.free,
+		left:   bvhNull,
+		right:  bvhNull,
+		height: -1,
+	}
+	t.free = i
+}
+
+func (t *bvh) contains(o Renderable) bool {
+	_, found := t.leaves[o]
+	return found
+}
+
+func (t *bvh) insert(o Renderable) {
+	if leaf, found := t.leaves[o]; found && leaf != bvhNull {
+		return
+	}
+
+	if o.Geometry() == nil {
+		if _, found := t.leaves[o]; !found {
+			t.leaves[o] = bvhNull
+			t.pending = append(t.pending, o)
+		}
+		return
+	}
+
+	leaf := t.allocate()
+	t.nodes[leaf].object = o
+	t.fitLeaf(leaf)
+	t.insertLeaf(leaf)
+
+	t.leaves[o] = leaf
+}
+
+func (t *bvh) remove(o Renderable) {
+	leaf, found := t.leaves[o]
+	if !found {
+		return
+	}
+	delete(t.leaves, o)
+
+	if leaf == bvhNull {
+		for i, p := range t.pending {
+			if p == o {
+				copy(t.pending[i:], t.pending[i+1:])
+				t.pending[len(t.pending)-1] = nil
+				t.pending = t.pending[:len(t.pending)-1]
+				break
+			}
+		}
+		return
+	}
+
+	if t.nodes[leaf].dirty {
+		for i, d := range t.dirty {
+			if d == leaf {
+				t.dirty[i] = t.dirty[len(t.dirty)-1]
+				t.dirty = t.dirty[:len(t.dirty)-1]
+				break
+			}
+		}
+	}
+
+	t.removeLeaf(leaf)
+	t.release(leaf)
+}
+
+// mark the object for an update of its bounding sphere
+func (t *bvh) touch(o Renderable) {
+	leaf, found := t.leaves[o]
+	if !found || leaf == bvhNull || t.nodes[leaf].dirty {
+		return
+	}
+
+	t.nodes[leaf].dirty = true
+	t.dirty = append(t.dirty, leaf)
+}
+
+// reinsert objects that moved out of their inflated boxes
+func (t *bvh) update() {
+	for _, i := range t.dirty {
+		n := &t.nodes[i]
+		n.dirty = false
+
+		if n.object.Geometry() == nil {
+			// lost its geometry, wait for a new one
+			o := n.object
+			t.removeLeaf(i)
+			t.release(i)
+			t.leaves[o] = bvhNull
+			t.pending = append(t.pending, o)
+			continue
+		}
+
+		c, r := worldSphere(n.object)
+		n.center, n.radius = c, r
+
+		if n.bounds.ContainsBoundary(math.BoundaryFromSphere(c, r)) {
+			continue
+		}
+
+		t.removeLeaf(i)
+		t.fitLeaf(i)
+		t.insertLeaf(i)
+	}
+	t.dirty = t.dirty[:0]
+
+	// late geometry
+	if len(t.pending) > 0 {
+		pending := t.pending
+		t.pending = nil
+
+		for _, o := range pending {
+			if o.Geometry() == nil {
+				t.pending = append(t.pending, o)
+				continue
+			}
+			t.insert(o)
+		}
+	}
+}
+
+func worldSphere(o Renderable) (math.Vector, float64) {
+	c, r := o.Geometry().Boundary().Sphere()
+
+	// transform sphere with modelmatrix
+	mw := o.MatrixWorld()
+	return mw.Transform(c), r * mw.MaxScaleOnAxis()
+}
+
+func (t *bvh) fitLeaf(leaf int) {
+	n := &t.nodes[leaf]
+	n.center, n.radius = worldSphere(n.object)
+	n.bounds = math.BoundaryFromSphere(n.center, n.radius).Expand(n.radius * bvhMargin)
+}
+
+func (t *bvh) insertLeaf(leaf int) {
+	if t.root == bvhNull {
+		t.root = leaf
+		t.nodes[leaf].parent = bvhNull
+		return
+	}
+
+	// find the best sibling, surface area heuristic
+	bounds := t.nodes[leaf].bounds
+	index := t.root
+
+	for !t.nodes[index].isLeaf() {
+		n := &t.nodes[index]
+
+		area := n.bounds.SurfaceArea()
+		combined := n.bounds.Union(bounds).SurfaceArea()
+
+		// cost of creating a new parent for this node and the new leaf
+		cost := 2.0 * combined
+
+		// minimum cost of pushing the leaf further down the tree
+		inheritance := 2.0 * (combined - area)
+		costLeft := t.descendCost(n.left, bounds) + inheritance
+		costRight := t.descendCost(n.right, bounds) + inheritance
+
+		if cost < costLeft && cost < costRight {
+			break
+		}
+
+		if costLeft < costRight {
+			index = n.left
+		} else {
+			index = n.right
+		}
+	}
+
+	// create a new parent
+	sibling := index
+	oldParent := t.nodes[sibling].parent
+	newParent := t.allocate()
+
+	t.nodes[newParent].parent = oldParent
+	t.nodes[newParent].left = sibling
+	t.nodes[newParent].right = leaf
+	t.nodes[newParent].bounds = bounds.Union(t.nodes[sibling].bounds)
+	t.nodes[newParent].height = t.nodes[sibling].height + 1
+
+	if oldParent != bvhNull {
+		if t.nodes[oldParent].left == sibling {
+			t.nodes[oldParent].left = newParent
+		} else {
+			t.nodes[oldParent].right = newParent
+		}
+	} else {
+		t.root = newParent
+	}
+
+	t.nodes[sibling].parent = newParent
+	t.nodes[leaf].parent = newParent
+
+	t.refit(newParent)
+}
+
+func (t *bvh) descendCost(i int, b math.Boundary) float64 {
+	n := &t.nodes[i]
+	combined := n.bounds.Union(b).SurfaceArea()
+
+	if n.isLeaf() {
+		return combined
+	}
+	return combined - n.bounds.SurfaceArea()
+}
+
+func (t *bvh) removeLeaf(leaf int) {
+	if leaf == t.root {
+		t.root = bvhNull
+		return
+	}
+
+	parent := t.nodes[leaf].parent
+	grandParent := t.nodes[parent].parent
+
+	sibling := t.nodes[parent].left
+	if sibling == leaf {
+		sibling = t.nodes[parent].right
+	}
+
+	if grandParent != bvhNull {
+		// connect sibling to grand parent
+		if t.nodes[grandParent].left == parent {
+			t.nodes[grandParent].left = sibling
+		} else {
+			t.nodes[grandParent].right = sibling
+		}
+		t.nodes[sibling].parent = grandParent
+		t.release(parent)
+
+		t.refit(grandParent)
+	} else {
+		t.root = sibling
+		t.nodes[sibling].parent = bvhNull
+		t.release(parent)
+	}
+
+	t.nodes[leaf].parent = bvhNull
+}
+
+// walk back up the tree fixing heights and boxes
+func (t *bvh) refit(index int) {
+	for index != bvhNull {
+		index = t.balance(index)
+		t.fit(index)
+		index = t.nodes[index].parent
+	}
+}
+
+func (t *bvh) fit(i int) {
+	n := &t.nodes[i]
+	l, r := &t.nodes[n.left], &t.nodes[n.right]
+
+	n.bounds = l.bounds.Union(r.bounds)
+	if l.height > r.height {
+		n.height = l.height + 1
+	} else {
+		n.height = r.height + 1
+	}
+}
+
+// perform a rotation if the subtree of a is imbalanced, returns the new subtree root
+func (t *bvh) balance(a int) int {
+	n := &t.nodes[a]
+	if n.isLeaf() || n.height < 2 {
+		return a
+	}
+
+	switch d := t.nodes[n.right].height - t.nodes[n.left].height; {
+	case d > 1:
+		return t.rotate(a, n.right)
+	case d < -1:
+		return t.rotate(a, n.left)
+	}
+
+	return a
+}
+
+// promote child up to the position of a
+func (t *bvh) rotate(a, up int) int {
+	f, g := t.nodes[up].left, t.nodes[up].right
+
+	// swap a and up
+	t.nodes[up].left = a
+	t.nodes[up].parent = t.nodes[a].parent
+	t.nodes[a].parent = up
+
+	if p := t.nodes[up].parent; p != bvhNull {
+		if t.nodes[p].left == a {
+			t.nodes[p].left = up
+		} else {
+			t.nodes[p].right = up
+		}
+	} else {
+		t.root = up
+	}
+
+	// higher grandchild stays, lower one moves to a
+	keep, move := f, g
+	if t.nodes[g].height > t.nodes[f].height {
+		keep, move = g, f
+	}
+
+	t.nodes[up].right = keep
+	if t.nodes[a].left == up {
+		t.nodes[a].left = move
+	} else {
+		t.nodes[a].right = move
+	}
+	t.nodes[move].parent = a
+
+	t.fit(a)
+	t.fit(up)
+
+	return up
+}
+
+// traverse all nodes whose box passes the test, call leaf for every reached leaf
+func (t *bvh) query(test func(b math.Boundary) bool, leaf func(n *bvhNode)) {
+	if t.root == bvhNull {
+		return
+	}
+
+	t.stack = append(t.stack[:0], t.root)
+	for len(t.stack) > 0 {
+		i := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+
+		n := &t.nodes[i]
+		if !test(n.bounds) {
+			continue
+		}
+
+		if n.isLeaf() {
+			leaf(n)
+		} else {
+			t.stack = append(t.stack, n.left, n.right)
+		}
+	}
+}
+
+func (t *bvh) intersectFrustum(f math.Frustum, fn func(o Renderable)) {
+	t.query(f.IntersectsBoundary, func(n *bvhNode) {
+		if f.IntersectsSphere(n.center, n.radius) {
+			fn(n.object)
+		}
+	})
+}
+
+func (t *bvh) intersectRay(r math.Ray, fn func(o Renderable, distance float64)) {
+	t.query(func(b math.Boundary) bool {
+		_, hit := r.IntersectsBoundary(b)
+		return hit
+	}, func(n *bvhNode) {
+		if d, hit := r.IntersectsSphere(n.center, n.radius); hit {
+			fn(n.object, d)
+		}
+	})
+}
+
+func (t *bvh) intersectBoundary(b math.Boundary, fn func(o Renderable)) {
+	t.query(b.IntersectsBoundary, func(n *bvhNode) {
+		if b.IntersectsBoundary(math.BoundaryFromSphere(n.center, n.radius)) {
+			fn(n.object)
+		}
+	})
+}
+
+// height of the tree, 0 if empty
+func (t *bvh) height() int {
+	if t.root == bvhNull {
+		return 0
+	}
+	return t.nodes[t.root].height
+}
@@ -0,0 +1,264 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// culling loop without spatial index
+func linearVisibleObjects(s *Scene, f math.Frustum) (opaque, transparent []Renderable) {
+	for _, o := range s.objects {
+		c, r := o.Geometry().Boundary().Sphere()
+
+		if f.IntersectsSphere(o.MatrixWorld().Transform(c), r*o.MatrixWorld().MaxScaleOnAxis()) {
+			if o.Material().Opaque() {
+				opaque = append(opaque, o)
+			} else {
+				transparent = append(transparent, o)
+			}
+		}
+	}
+
+	return opaque, transparent
+}
+
+func testScene(n int, size float64) (*Scene, []*Mesh) {
+	rnd := rand.New(rand.NewSource(42))
+	geo := NewCubeGeometry(1)
+	opaque := &Material{uniforms: map[string]interface{}{"opacity": 1.0}}
+	transparent := &Material{uniforms: map[string]interface{}{"opacity": 0.5}}
+
+	scene := NewScene()
+	meshes := make([]*Mesh, n)
+
+	for i := range meshes {
+		mat := opaque
+		if i%4 == 0 {
+			mat = transparent
+		}
+
+		meshes[i] = NewMesh(geo, mat)
+		meshes[i].SetPosition(math.Vector{
+			(rnd.Float64() - 0.5) * size,
+			(rnd.Float64() - 0.5) * size,
+			(rnd.Float64() - 0.5) * size,
+		})
+		scene.AddChild(meshes[i])
+	}
+
+	scene.UpdateMatrixWorld(false)
+	return scene, meshes
+}
+
+func testFrustum() math.Frustum {
+	camera := NewPerspectiveCamera(45.0, 4.0/3.0, 0.1, 100.0)
+	camera.SetPosition(math.Vector{0, 0, 0})
+	camera.LookAt(math.Vector{0, 0, -1})
+	camera.UpdateMatrixWorld(false)
+
+	return math.FrustumFromMatrix(camera.ProjectionMatrix().Mul(camera.MatrixWorld().Inverse()))
+}
+
+func sameObjects(a, b []Renderable) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	found := make(map[Renderable]bool, len(a))
+	for _, o := range a {
+		found[o] = true
+	}
+	for _, o := range b {
+		if !found[o] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func checkBVH(tree *bvh, t *testing.T) {
+	var leaves int
+
+	for i, n := range tree.nodes {
+		if n.height < 0 {
+			continue
+		}
+
+		if n.isLeaf() {
+			leaves++
+			if tree.leaves[n.object] != i {
+				t.Errorf("leaf %v not registered", i)
+			}
+		} else {
+			l, r := tree.nodes[n.left], tree.nodes[n.right]
+
+			if l.parent != i || r.parent != i {
+				t.Errorf("children of node %v have wrong parents", i)
+			}
+			if !n.bounds.ContainsBoundary(l.bounds) || !n.bounds.ContainsBoundary(r.bounds) {
+				t.Errorf("node %v does not contain its children", i)
+			}
+			if d := l.height - r.height; d > 1 || d < -1 {
+				t.Errorf("node %v is imbalanced (%v, %v)", i, l.height, r.height)
+			}
+		}
+
+		if n.parent == bvhNull && i != tree.root {
+			t.Errorf("node %v has no parent", i)
+		}
+	}
+
+	if registered := len(tree.leaves) - len(tree.pending); leaves != registered {
+		t.Errorf("%v leaves in tree, %v registered", leaves, registered)
+	}
+}
+
+func TestScene_VisibleObjects(t *testing.T) {
+	scene, meshes := testScene(2000, 200)
+	f := testFrustum()
+
+	checkBVH(scene.tree, t)
+	if h := scene.tree.height(); h > 30 {
+		t.Errorf("tree of %v objects too high: %v", len(meshes), h)
+	}
+
+	expOp, expTr := linearVisibleObjects(scene, f)
+	op, tr := scene.VisibleObjects(f)
+	if !sameObjects(op, expOp) || !sameObjects(tr, expTr) {
+		t.Errorf("VisibleObjects returned %v/%v objects, expected %v/%v", len(op), len(tr), len(expOp), len(expTr))
+	}
+
+	// move, remove and add objects
+	rnd := rand.New(rand.NewSource(7))
+	for i, m := range meshes {
+		switch i % 10 {
+		case 0:
+			m.SetPosition(m.Position().Add(math.Vector{0.01, 0, 0}))
+		case 1:
+			m.SetPosition(math.Vector{
+				(rnd.Float64() - 0.5) * 200,
+				(rnd.Float64() - 0.5) * 200,
+				(rnd.Float64() - 0.5) * 200,
+			})
+		case 2:
+			m.SetScale(math.Vector{5, 5, 5})
+		case 3:
+			scene.RemoveChild(m)
+		}
+	}
+
+	late := NewMesh(nil, meshes[1].Material())
+	scene.AddChild(late)
+	scene.UpdateMatrixWorld(false)
+
+	late.SetGeometry(meshes[1].Geometry())
+	late.SetPosition(math.Vector{0, 0, -10})
+	scene.UpdateMatrixWorld(false)
+
+	expOp, expTr = linearVisibleObjects(scene, f)
+	op, tr = scene.VisibleObjects(f)
+	if !sameObjects(op, expOp) || !sameObjects(tr, expTr) {
+		t.Errorf("VisibleObjects after update returned %v/%v objects, expected %v/%v", len(op), len(tr), len(expOp), len(expTr))
+	}
+
+	checkBVH(scene.tree, t)
+}
+
+func TestScene_Raycast(t *testing.T) {
+	geo := NewCubeGeometry(1)
+	mat := &Material{uniforms: map[string]interface{}{"opacity": 1.0}}
+
+	scene := NewScene()
+	near, far, off := NewMesh(geo, mat), NewMesh(geo, mat), NewMesh(geo, mat)
+	near.SetPosition(math.Vector{0, 0, -5})
+	far.SetPosition(math.Vector{0, 0, -20})
+	off.SetPosition(math.Vector{5, 0, -10})
+	scene.AddChild(far, off, near)
+	scene.UpdateMatrixWorld(false)
+
+	hits := scene.Raycast(math.NewRay(math.Vector{0, 0, 0}, math.Vector{0, 0, -1}))
+	if len(hits) != 2 || hits[0] != near || hits[1] != far {
+		t.Errorf("Raycast() != [near far] (got %v)", hits)
+	}
+
+	if hits := scene.Raycast(math.NewRay(math.Vector{0, 0, 0}, math.Vector{0, 0, 1})); len(hits) != 0 {
+		t.Errorf("Raycast() behind origin != [] (got %v)", hits)
+	}
+}
+
+func TestScene_ObjectsInBoundary(t *testing.T) {
+	scene, meshes := testScene(500, 100)
+
+	region := math.BoundaryFromPoints(math.Vector{-20, -20, -20}, math.Vector{20, 10, 0})
+	var expected []Renderable
+	for _, m := range meshes {
+		c, r := worldSphere(m)
+		if region.IntersectsBoundary(math.BoundaryFromSphere(c, r)) {
+			expected = append(expected, m)
+		}
+	}
+
+	if r := scene.ObjectsInBoundary(region); !sameObjects(r, expected) {
+		t.Errorf("ObjectsInBoundary() returned %v objects, expected %v", len(r), len(expected))
+	}
+}
+
+func TestScene_DynamicGeometry(t *testing.T) {
+	geo := NewPlaneGeometry(2, 2)
+	mesh := NewMesh(geo, &Material{uniforms: map[string]interface{}{"opacity": 1.0}})
+	scene := NewScene()
+	scene.AddChild(mesh)
+	scene.UpdateMatrixWorld(false)
+
+	far := math.BoundaryFromPoints(math.Vector{49, 49, 49}, math.Vector{51, 51, 51})
+	near := math.BoundaryFromPoints(math.Vector{-1, -1, -1}, math.Vector{1, 1, 1})
+
+	tests := []struct {
+		offset    math.Vector
+		near, far bool
+	}{
+		{math.Vector{50, 50, 50}, false, true},
+		{math.Vector{0, 0, 0}, true, false},
+	}
+
+	for _, c := range tests {
+		positions := make([]math.Vector, len(geo.vertices))
+		for i, v := range NewPlaneGeometry(2, 2).vertices {
+			positions[i] = v.position.Add(c.offset)
+		}
+		geo.SetPositions(0, positions...)
+		scene.UpdateMatrixWorld(false)
+		checkBVH(scene.tree, t)
+
+		if r := scene.ObjectsInBoundary(near); (len(r) == 1) != c.near {
+			t.Errorf("SetPositions(%v) near region returned %v objects", c.offset, len(r))
+		}
+		if r := scene.ObjectsInBoundary(far); (len(r) == 1) != c.far {
+			t.Errorf("SetPositions(%v) far region returned %v objects", c.offset, len(r))
+		}
+	}
+}
+
+func benchmarkVisibleObjects(b *testing.B, n int, linear bool) {
+	b.StopTimer()
+	scene, _ := testScene(n, 1000)
+	f := testFrustum()
+	scene.VisibleObjects(f)
+
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		if linear {
+			linearVisibleObjects(scene, f)
+		} else {
+			scene.VisibleObjects(f)
+		}
+	}
+}
+
+func BenchmarkScene_VisibleObjects_Linear10k(b *testing.B) { benchmarkVisibleObjects(b, 10000, true) }
+func BenchmarkScene_VisibleObjects_BVH10k(b *testing.B)    { benchmarkVisibleObjects(b, 10000, false) }
+func BenchmarkScene_VisibleObjects_Linear50k(b *testing.B) { benchmarkVisibleObjects(b, 50000, true) }
+func BenchmarkScene_VisibleObjects_BVH50k(b *testing.B)    { benchmarkVisibleObjects(b, 50000, false) }
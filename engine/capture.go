@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/go-gl/gl"
+)
+
+type frameCapture struct {
+	path   string
+	every  int
+	target *RenderTarget
+
+	frame  int // rendered frames since start
+	images chan *image.RGBA
+	done   chan struct{}
+	err    error
+}
+
+// current frame of the window, the passes are rendered into the back buffer
+// and read before a swap, the front buffer is undefined after presenting
+func (r *Renderer) Screenshot() image.Image {
+	r.renderPasses()
+
+	r.bindRenderTarget(nil)
+	return readPixels(r.width, r.height, true)
+}
+
+// current content of the rendertarget
+func (r *Renderer) ScreenshotTarget(target *RenderTarget) image.Image {
+	if target == nil {
+		return r.Screenshot()
+	}
+
+	r.bindRenderTarget(target)
+	return readPixels(target.width, target.height, false)
+}
+
+// write every nth rendered frame of the window or target (if not nil) as numbered png files into path
+func (r *Renderer) StartCapture(path string, every int, target *RenderTarget) error {
+	if r.capture != nil {
+		if err := r.StopCapture(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+
+	if every < 1 {
+		every = 1
+	}
+
+	c := &frameCapture{
+		path:   path,
+		every:  every,
+		target: target,
+
+		images: make(chan *image.RGBA, 8),
+		done:   make(chan struct{}),
+	}
+
+	// encode in the background, keeps the render loop going
+	go func() {
+		var n int
+		for img := range c.images {
+			if c.err == nil {
+				c.err = writePNG(filepath.Join(c.path, fmt.Sprintf("%06d.png", n)), img)
+			}
+			n++
+		}
+		close(c.done)
+	}()
+
+	r.capture = c
+	return nil
+}
+
+// stop capturing and wait for all frames to be written
+func (r *Renderer) StopCapture() error {
+	c := r.capture
+	if c == nil {
+		return nil
+	}
+	r.capture = nil
+
+	close(c.images)
+	<-c.done
+
+	return c.err
+}
+
+func (r *Renderer) Capturing() bool {
+	return r.capture != nil
+}
+
+func (r *Renderer) captureFrame() {
+	c := r.capture
+	if c.frame++; (c.frame-1)%c.every != 0 {
+		return
+	}
+
+	if c.target == nil {
+		// not swapped yet
+		r.bindRenderTarget(nil)
+		c.images <- readPixels(r.width, r.height, true)
+	} else {
+		r.bindRenderTarget(c.target)
+		c.images <- readPixels(c.target.width, c.target.height, false)
+	}
+}
+
+func readPixels(width, height int, opaque bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, width, height, gl.RGBA, gl.UNSIGNED_BYTE, img.Pix)
+
+	// blended alpha of the window is meaningless
+	if opaque {
+		for i := 3; i < len(img.Pix); i += 4 {
+			img.Pix[i] = 0xff
+		}
+	}
+
+	flipVertical(img)
+	return img
+}
+
+// opengl rows start at the bottom
+func flipVertical(img *image.RGBA) {
+	h := img.Bounds().Dy()
+	row := make([]uint8, img.Stride)
+
+	for y := 0; y < h/2; y++ {
+		top := img.Pix[y*img.Stride : (y+1)*img.Stride]
+		bottom := img.Pix[(h-1-y)*img.Stride : (h-y)*img.Stride]
+
+		copy(row, top)
+		copy(top, bottom)
+		copy(bottom, row)
+	}
+}
+
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,28 @@
+package engine
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestFlipVertical(t *testing.T) {
+	tests := []struct {
+		Height   int
+		Pix      []uint8
+		Expected []uint8
+	}{
+		{1, []uint8{1, 2, 3, 4}, []uint8{1, 2, 3, 4}},
+		{2, []uint8{1, 2, 3, 4, 5, 6, 7, 8}, []uint8{5, 6, 7, 8, 1, 2, 3, 4}},
+		{3, []uint8{1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3}, []uint8{3, 3, 3, 3, 2, 2, 2, 2, 1, 1, 1, 1}},
+	}
+
+	for _, c := range tests {
+		img := image.NewRGBA(image.Rect(0, 0, 1, c.Height))
+		copy(img.Pix, c.Pix)
+
+		if flipVertical(img); !reflect.DeepEqual(img.Pix, c.Expected) {
+			t.Errorf("flipVertical(%v) != %v (got %v)", c.Pix, c.Expected, img.Pix)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+
+	"github.com/go-gl/gl"
+)
+
+// six square faces in the order +x, -x, +y, -y, +z, -z for skyboxes and reflections
+type CubeTexture struct {
+	sampler
+
+	buffer      gl.Texture
+	initialized bool
+
+	faces [6]*image.RGBA
+}
+
+func NewCubeTexture(faces [6]image.Image) (*CubeTexture, error) {
+	t := &CubeTexture{
+		sampler: newSampler(ClampToEdgeWrapping, LinearMipMapLinearFilter),
+	}
+
+	for i, f := range faces {
+		if f == nil {
+			return nil, fmt.Errorf("cube face %v is missing", i)
+		}
+	}
+
+	size := faces[0].Bounds().Dx()
+	for i, f := range faces {
+		if f.Bounds().Dx() != size || f.Bounds().Dy() != size {
+			return nil, errors.New("cube faces have to be square and of the same size")
+		}
+		t.faces[i] = toRGBA(f)
+	}
+
+	return t, nil
+}
+
+func LoadCubeTexture(paths [6]string) (*CubeTexture, error) {
+	var faces [6]image.Image
+
+	for i, p := range paths {
+		file, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+
+		faces[i], _, err = image.Decode(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return NewCubeTexture(faces)
+}
+
+// edge length of a face
+func (t *CubeTexture) Size() int {
+	return t.faces[0].Bounds().Dx()
+}
+
+func (t *CubeTexture) init() {
+	t.buffer = gl.GenTexture()
+
+	// filter across face edges
+	gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+
+	t.initialized = true
+}
+
+func (t *CubeTexture) update() {
+	if !t.initialized {
+		t.init()
+	}
+
+	t.buffer.Bind(gl.TEXTURE_CUBE_MAP)
+	t.apply(gl.TEXTURE_CUBE_MAP)
+
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	for i, f := range t.faces {
+		gl.TexImage2D(gl.TEXTURE_CUBE_MAP_POSITIVE_X+gl.GLenum(i), 0, t.internalFormat(),
+			f.Bounds().Dx(), f.Bounds().Dy(),
+			0, gl.RGBA, gl.UNSIGNED_BYTE, f.Pix)
+	}
+
+	if t.mipmapped() {
+		gl.GenerateMipmap(gl.TEXTURE_CUBE_MAP)
+	}
+
+	t.needsUpdate = false
+}
+
+func (t *CubeTexture) Dispose() {
+	if t.buffer != 0 {
+		t.buffer.Delete()
+	}
+}
+
+func (t *CubeTexture) Bind(slot int) {
+	gl.ActiveTexture(gl.TEXTURE0 + gl.GLenum(slot))
+
+	if t.needsUpdate {
+		t.update()
+	} else {
+		t.buffer.Bind(gl.TEXTURE_CUBE_MAP)
+	}
+}
+
+func (t *CubeTexture) Unbind() {
+	if t.initialized {
+		t.buffer.Unbind(gl.TEXTURE_CUBE_MAP)
+	}
+}
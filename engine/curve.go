@@ -0,0 +1,167 @@
+package engine
+
+import (
+	m "math"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// parametric 3d curve, t in [0, 1]
+type Curve interface {
+	Point(t float64) math.Vector
+}
+
+// adapter to use ordinary functions as curves
+type CurveFunc func(t float64) math.Vector
+
+func (f CurveFunc) Point(t float64) math.Vector {
+	return f(t)
+}
+
+// centripetal catmull-rom spline through all points
+type CatmullRomCurve struct {
+	points []math.Vector
+	closed bool
+}
+
+func NewCatmullRomCurve(points []math.Vector, closed bool) *CatmullRomCurve {
+	return &CatmullRomCurve{
+		points: points,
+		closed: closed,
+	}
+}
+
+func (c *CatmullRomCurve) Point(t float64) math.Vector {
+	n := len(c.points)
+	switch n {
+	case 0:
+		return math.Vector{}
+	case 1:
+		return c.points[0]
+	}
+
+	segments := n - 1
+	if c.closed {
+		segments = n
+	}
+
+	p := m.Max(0, m.Min(1, t)) * float64(segments)
+	i := int(p)
+	if i >= segments {
+		i = segments - 1
+	}
+	w := p - float64(i)
+
+	at := func(j int) math.Vector {
+		if c.closed {
+			return c.points[(j%n+n)%n]
+		}
+		switch {
+		case j < 0:
+			// extrapolate the ends
+			return c.points[0].MulScalar(2).Sub(c.points[1])
+		case j >= n:
+			return c.points[n-1].MulScalar(2).Sub(c.points[n-2])
+		}
+		return c.points[j]
+	}
+	p0, p1, p2, p3 := at(i-1), at(i), at(i+1), at(i+2)
+
+	// knot intervals by the square root of the distance
+	interval := func(a, b math.Vector) float64 {
+		d := m.Sqrt(a.DistanceTo(b))
+		if d < 1e-4 {
+			return 1
+		}
+		return d
+	}
+	dt0, dt1, dt2 := interval(p0, p1), interval(p1, p2), interval(p2, p3)
+
+	// tangents of the non uniform hermite segment
+	t1 := p1.Sub(p0).MulScalar(1 / dt0).Sub(p2.Sub(p0).MulScalar(1 / (dt0 + dt1))).Add(p2.Sub(p1).MulScalar(1 / dt1)).MulScalar(dt1)
+	t2 := p2.Sub(p1).MulScalar(1 / dt1).Sub(p3.Sub(p1).MulScalar(1 / (dt1 + dt2))).Add(p3.Sub(p2).MulScalar(1 / dt2)).MulScalar(dt1)
+
+	w2, w3 := w*w, w*w*w
+	h0 := 2*w3 - 3*w2 + 1
+	h1 := w3 - 2*w2 + w
+	h2 := -2*w3 + 3*w2
+	h3 := w3 - w2
+
+	r := p1.MulScalar(h0).Add(t1.MulScalar(h1)).Add(p2.MulScalar(h2)).Add(t2.MulScalar(h3))
+	r[3] = 0
+	return r
+}
+
+// rotation minimizing frames along a curve, sampled at segments+1 points
+func curveFrames(c Curve, segments int, closed bool) (points, tangents, normals, binormals []math.Vector) {
+	points = make([]math.Vector, segments+1)
+	tangents = make([]math.Vector, segments+1)
+	normals = make([]math.Vector, segments+1)
+	binormals = make([]math.Vector, segments+1)
+
+	const delta = 1e-4
+	for i := range points {
+		t := float64(i) / float64(segments)
+		points[i] = c.Point(t)
+		points[i][3] = 0
+
+		t0, t1 := m.Max(0, t-delta), m.Min(1, t+delta)
+		if closed {
+			t0, t1 = m.Mod(t-delta+1, 1), m.Mod(t+delta, 1)
+		}
+		a, b := c.Point(t0), c.Point(t1)
+		tan := b.Sub(a)
+		tan[3] = 0
+		tangents[i] = tan.Normalize()
+	}
+
+	// initial normal perpendicular to the smallest tangent component
+	t0 := tangents[0]
+	axis := math.Vector{1, 0, 0}
+	if ax, ay, az := m.Abs(t0[0]), m.Abs(t0[1]), m.Abs(t0[2]); ay <= ax && ay <= az {
+		axis = math.Vector{0, 1, 0}
+	} else if az <= ax && az <= ay {
+		axis = math.Vector{0, 0, 1}
+	}
+	binormals[0] = t0.Cross(axis).Normalize()
+	normals[0] = binormals[0].Cross(t0).Normalize()
+
+	// parallel transport
+	for i := 1; i <= segments; i++ {
+		n := normals[i-1]
+		if v := tangents[i-1].Cross(tangents[i]); v.Length() > 1e-9 {
+			v = v.Normalize()
+			angle := m.Acos(m.Max(-1, m.Min(1, tangents[i-1].Dot(tangents[i]))))
+			n = rotateAround(n, v, angle)
+		}
+		// remove drift
+		n = n.Sub(tangents[i].MulScalar(tangents[i].Dot(n))).Normalize()
+
+		normals[i] = n
+		binormals[i] = tangents[i].Cross(n)
+		binormals[i][3] = 0
+	}
+
+	// distribute the twist of closed curves along the length
+	if closed {
+		theta := m.Acos(m.Max(-1, m.Min(1, normals[0].Dot(normals[segments])))) / float64(segments)
+		if tangents[0].Dot(normals[0].Cross(normals[segments])) > 0 {
+			theta = -theta
+		}
+		for i := 1; i <= segments; i++ {
+			normals[i] = rotateAround(normals[i], tangents[i], theta*float64(i))
+			binormals[i] = tangents[i].Cross(normals[i])
+			binormals[i][3] = 0
+		}
+	}
+
+	return points, tangents, normals, binormals
+}
+
+// rodrigues rotation of v around the unit axis k
+func rotateAround(v, k math.Vector, angle float64) math.Vector {
+	s, c := m.Sin(angle), m.Cos(angle)
+	r := v.MulScalar(c).Add(k.Cross(v).MulScalar(s)).Add(k.MulScalar(k.Dot(v) * (1 - c)))
+	r[3] = 0
+	return r
+}
@@ -0,0 +1,282 @@
+package engine
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/go-gl/gl"
+)
+
+// DirectDraw Surface container with S3TC compressed mipmaps
+// http://msdn.microsoft.com/en-us/library/windows/desktop/bb943991(v=vs.85).aspx
+
+type ddsFormat int
+
+const (
+	dxt1 ddsFormat = iota + 1
+	dxt3
+	dxt5
+)
+
+const (
+	ddsMagic         = "DDS "
+	ddsHeaderSize    = 124
+	ddsFormatSize    = 32
+	ddsFlagMipCount  = 0x20000
+	ddsFlagFourCC    = 0x4
+	ddsMaxDimensions = 1 << 15
+)
+
+type ddsHeader struct {
+	Size              uint32
+	Flags             uint32
+	Height            uint32
+	Width             uint32
+	PitchOrLinearSize uint32
+	Depth             uint32
+	MipMapCount       uint32
+	Reserved1         [11]uint32
+
+	PixelFormat struct {
+		Size        uint32
+		Flags       uint32
+		FourCC      [4]byte
+		RGBBitCount uint32
+		RBitMask    uint32
+		GBitMask    uint32
+		BBitMask    uint32
+		ABitMask    uint32
+	}
+
+	Caps      [4]uint32
+	Reserved2 uint32
+}
+
+// compressed mipmap level
+type ddsLevel struct {
+	width, height int
+	data          []byte
+}
+
+func (f ddsFormat) blockSize() int {
+	if f == dxt1 {
+		return 8
+	}
+	return 16
+}
+
+func (f ddsFormat) levelSize(w, h int) int {
+	return ((w + 3) / 4) * ((h + 3) / 4) * f.blockSize()
+}
+
+func (f ddsFormat) glFormat(c ColorSpace) gl.GLenum {
+	switch {
+	case f == dxt1 && c == SRGB:
+		return gl.COMPRESSED_SRGB_ALPHA_S3TC_DXT1_EXT
+	case f == dxt1:
+		return gl.COMPRESSED_RGBA_S3TC_DXT1_EXT
+	case f == dxt3 && c == SRGB:
+		return gl.COMPRESSED_SRGB_ALPHA_S3TC_DXT3_EXT
+	case f == dxt3:
+		return gl.COMPRESSED_RGBA_S3TC_DXT3_EXT
+	case c == SRGB:
+		return gl.COMPRESSED_SRGB_ALPHA_S3TC_DXT5_EXT
+	default:
+		return gl.COMPRESSED_RGBA_S3TC_DXT5_EXT
+	}
+}
+
+// read header and mipmap chain
+func decodeDDS(r io.Reader) (ddsFormat, []ddsLevel, error) {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return 0, nil, err
+	}
+	if string(magic) != ddsMagic {
+		return 0, nil, errors.New("dds: invalid magic number")
+	}
+
+	var h ddsHeader
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return 0, nil, err
+	}
+	if h.Size != ddsHeaderSize || h.PixelFormat.Size != ddsFormatSize {
+		return 0, nil, errors.New("dds: invalid header size")
+	}
+	if h.Width == 0 || h.Height == 0 || h.Width > ddsMaxDimensions || h.Height > ddsMaxDimensions {
+		return 0, nil, fmt.Errorf("dds: invalid dimensions %vx%v", h.Width, h.Height)
+	}
+	if h.PixelFormat.Flags&ddsFlagFourCC == 0 {
+		return 0, nil, errors.New("dds: uncompressed formats are not supported")
+	}
+
+	var format ddsFormat
+	switch string(h.PixelFormat.FourCC[:]) {
+	case "DXT1":
+		format = dxt1
+	case "DXT3":
+		format = dxt3
+	case "DXT5":
+		format = dxt5
+	default:
+		return 0, nil, fmt.Errorf("dds: unsupported format %q", h.PixelFormat.FourCC[:])
+	}
+
+	count := 1
+	if h.Flags&ddsFlagMipCount != 0 && h.MipMapCount > 1 {
+		count = int(h.MipMapCount)
+	}
+
+	levels := make([]ddsLevel, 0, count)
+	w, ht := int(h.Width), int(h.Height)
+
+	for i := 0; i < count; i++ {
+		l := ddsLevel{
+			width:  w,
+			height: ht,
+			data:   make([]byte, format.levelSize(w, ht)),
+		}
+
+		if _, err := io.ReadFull(r, l.data); err != nil {
+			if i == 0 {
+				return 0, nil, err
+			}
+			break // truncated mipmap chain, keep complete levels
+		}
+		levels = append(levels, l)
+
+		if w == 1 && ht == 1 {
+			break
+		}
+		if w > 1 {
+			w /= 2
+		}
+		if ht > 1 {
+			ht /= 2
+		}
+	}
+
+	return format, levels, nil
+}
+
+// decompress a level for drivers without s3tc support
+func decodeDXT(f ddsFormat, l ddsLevel) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, l.width, l.height))
+	bs := f.blockSize()
+	bw := (l.width + 3) / 4
+
+	var block [16][4]byte
+	for i := 0; i*bs < len(l.data); i++ {
+		b := l.data[i*bs : (i+1)*bs]
+		bx, by := (i%bw)*4, (i/bw)*4
+
+		switch f {
+		case dxt1:
+			decodeColorBlock(b, &block, true)
+		case dxt3:
+			decodeColorBlock(b[8:], &block, false)
+			for p := 0; p < 16; p++ {
+				a := b[p/2] >> (uint(p%2) * 4) & 0x0f
+				block[p][3] = a<<4 | a
+			}
+		case dxt5:
+			decodeColorBlock(b[8:], &block, false)
+			decodeAlphaBlock(b, &block)
+		}
+
+		for p := 0; p < 16; p++ {
+			x, y := bx+p%4, by+p/4
+			if x >= l.width || y >= l.height {
+				continue
+			}
+			copy(img.Pix[img.PixOffset(x, y):], block[p][:])
+		}
+	}
+
+	return img
+}
+
+func unpack565(c uint16) [4]byte {
+	r, g, b := byte(c>>11&0x1f), byte(c>>5&0x3f), byte(c&0x1f)
+	return [4]byte{r<<3 | r>>2, g<<2 | g>>4, b<<3 | b>>2, 255}
+}
+
+// hasAlpha enables the punch-through alpha mode of dxt1 blocks with c0 <= c1
+func decodeColorBlock(b []byte, block *[16][4]byte, hasAlpha bool) {
+	c0 := binary.LittleEndian.Uint16(b[0:])
+	c1 := binary.LittleEndian.Uint16(b[2:])
+
+	var palette [4][4]byte
+	palette[0], palette[1] = unpack565(c0), unpack565(c1)
+
+	if c0 > c1 || !hasAlpha {
+		for i := 0; i < 3; i++ {
+			palette[2][i] = byte((2*int(palette[0][i]) + int(palette[1][i])) / 3)
+			palette[3][i] = byte((int(palette[0][i]) + 2*int(palette[1][i])) / 3)
+		}
+		palette[2][3], palette[3][3] = 255, 255
+	} else {
+		// three colors and transparent black
+		for i := 0; i < 3; i++ {
+			palette[2][i] = byte((int(palette[0][i]) + int(palette[1][i])) / 2)
+		}
+		palette[2][3] = 255
+	}
+
+	indices := binary.LittleEndian.Uint32(b[4:])
+	for p := uint(0); p < 16; p++ {
+		block[p] = palette[indices>>(2*p)&0x03]
+	}
+}
+
+func decodeAlphaBlock(b []byte, block *[16][4]byte) {
+	a0, a1 := int(b[0]), int(b[1])
+
+	var palette [8]byte
+	palette[0], palette[1] = byte(a0), byte(a1)
+
+	if a0 > a1 {
+		for i := 1; i < 7; i++ {
+			palette[i+1] = byte(((7-i)*a0 + i*a1) / 7)
+		}
+	} else {
+		for i := 1; i < 5; i++ {
+			palette[i+1] = byte(((5-i)*a0 + i*a1) / 5)
+		}
+		palette[6], palette[7] = 0, 255
+	}
+
+	var indices uint64
+	for i := 0; i < 6; i++ {
+		indices |= uint64(b[2+i]) << (8 * uint(i))
+	}
+	for p := uint(0); p < 16; p++ {
+		block[p][3] = palette[indices>>(3*p)&0x07]
+	}
+}
+
+var s3tcSupport struct {
+	checked, supported bool
+}
+
+// needs a current context
+func s3tcSupported() bool {
+	if !s3tcSupport.checked {
+		var n [1]int32
+		gl.GetIntegerv(gl.NUM_EXTENSIONS, n[:])
+
+		for i := 0; i < int(n[0]); i++ {
+			if strings.Contains(gl.GetStringi(gl.EXTENSIONS, gl.GLuint(i)), "texture_compression_s3tc") {
+				s3tcSupport.supported = true
+				break
+			}
+		}
+		s3tcSupport.checked = true
+	}
+
+	return s3tcSupport.supported
+}
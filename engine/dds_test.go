@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func testDDS(fourCC string, w, h, mipmaps int, data []byte) []byte {
+	var hd ddsHeader
+	hd.Size = ddsHeaderSize
+	hd.Flags = 0x1007 // caps, height, width, pixelformat
+	hd.Width, hd.Height = uint32(w), uint32(h)
+	if mipmaps > 0 {
+		hd.Flags |= ddsFlagMipCount
+		hd.MipMapCount = uint32(mipmaps)
+	}
+	hd.PixelFormat.Size = ddsFormatSize
+	hd.PixelFormat.Flags = ddsFlagFourCC
+	copy(hd.PixelFormat.FourCC[:], fourCC)
+
+	var buf bytes.Buffer
+	buf.WriteString(ddsMagic)
+	binary.Write(&buf, binary.LittleEndian, &hd)
+	buf.Write(data)
+
+	return buf.Bytes()
+}
+
+func TestDecodeDDS(t *testing.T) {
+	tests := []struct {
+		fourCC  string
+		w, h    int
+		mipmaps int
+		data    int
+
+		format ddsFormat
+		sizes  []int
+		err    bool
+	}{
+		{"DXT1", 8, 8, 4, 64, dxt1, []int{32, 8, 8, 8}, false},
+		{"DXT5", 8, 4, 0, 32, dxt5, []int{32}, false},
+		{"DXT3", 5, 5, 2, 80, dxt3, []int{64, 16}, false},
+		{"DXT1", 8, 8, 4, 40, dxt1, []int{32, 8}, false}, // truncated chain
+		{"DXT1", 8, 8, 1, 16, 0, nil, true},
+		{"ATI2", 4, 4, 1, 16, 0, nil, true},
+	}
+
+	for _, c := range tests {
+		f, levels, err := decodeDDS(bytes.NewReader(testDDS(c.fourCC, c.w, c.h, c.mipmaps, make([]byte, c.data))))
+		if (err != nil) != c.err {
+			t.Errorf("decodeDDS(%v %vx%v) error %v (got %v)", c.fourCC, c.w, c.h, c.err, err)
+			continue
+		}
+		if f != c.format || len(levels) != len(c.sizes) {
+			t.Errorf("decodeDDS(%v %vx%v) != %v with %v levels (got %v with %v levels)", c.fourCC, c.w, c.h, c.format, len(c.sizes), f, len(levels))
+			continue
+		}
+		for i, l := range levels {
+			if len(l.data) != c.sizes[i] {
+				t.Errorf("decodeDDS(%v %vx%v) level %v != %v bytes (got %v)", c.fourCC, c.w, c.h, i, c.sizes[i], len(l.data))
+			}
+		}
+	}
+
+	if _, _, err := decodeDDS(bytes.NewReader([]byte("PNG 1234"))); err == nil {
+		t.Errorf("decodeDDS(invalid magic) != error")
+	}
+}
+
+func TestDecodeDXT(t *testing.T) {
+	// red and blue endpoints, first row color0, second color1, third 2/3 red, fourth 1/3 red
+	color := []byte{0x00, 0xf8, 0x1f, 0x00, 0x00, 0x55, 0xaa, 0xff}
+	// alpha 255 to 0, first row alpha0, rest alpha1
+	alpha := []byte{0xff, 0x00, 0x00, 0x90, 0x24, 0x49, 0x92, 0x24}
+
+	tests := []struct {
+		format ddsFormat
+		data   []byte
+		x, y   int
+		pixel  [4]byte
+	}{
+		{dxt1, color, 0, 0, [4]byte{255, 0, 0, 255}},
+		{dxt1, color, 3, 1, [4]byte{0, 0, 255, 255}},
+		{dxt1, color, 1, 2, [4]byte{170, 0, 85, 255}},
+		{dxt1, color, 2, 3, [4]byte{85, 0, 170, 255}},
+		{dxt1, []byte{0x1f, 0x00, 0x00, 0xf8, 0xff, 0xff, 0xff, 0xff}, 0, 0, [4]byte{0, 0, 0, 0}}, // transparent
+		{dxt3, append([]byte{0xf0, 0, 0, 0, 0, 0, 0, 0}, color...), 1, 0, [4]byte{255, 0, 0, 255}},
+		{dxt3, append([]byte{0xf0, 0, 0, 0, 0, 0, 0, 0}, color...), 0, 0, [4]byte{255, 0, 0, 0}},
+		{dxt5, append(alpha, color...), 2, 0, [4]byte{255, 0, 0, 255}},
+		{dxt5, append(alpha, color...), 2, 1, [4]byte{0, 0, 255, 0}},
+	}
+
+	for _, c := range tests {
+		img := decodeDXT(c.format, ddsLevel{width: 4, height: 4, data: c.data})
+		o := img.PixOffset(c.x, c.y)
+
+		var p [4]byte
+		copy(p[:], img.Pix[o:o+4])
+		if p != c.pixel {
+			t.Errorf("decodeDXT(%v) at %v,%v != %v (got %v)", c.format, c.x, c.y, c.pixel, p)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// renderable with its transformation relative to the parent of the exported root
+type exportedMesh struct {
+	mesh   Renderable
+	matrix math.Matrix
+}
+
+// renderables of an object tree in depth first order
+func exportMeshes(o Object, parent math.Matrix) []exportedMesh {
+	mat := parent.Mul(o.Matrix())
+
+	var meshes []exportedMesh
+	if r, ok := o.(Renderable); ok && r.Geometry() != nil {
+		meshes = append(meshes, exportedMesh{r, mat})
+	}
+	for _, c := range o.Children() {
+		meshes = append(meshes, exportMeshes(c, mat)...)
+	}
+	return meshes
+}
+
+// vertices and faces with the transformation applied, mirroring keeps the face winding
+func bakeGeometry(g *Geometry, mat math.Matrix) ([]Vertex, []Face) {
+	vertices := make([]Vertex, len(g.vertices))
+	normal := mat.Normal()
+	for i, v := range g.vertices {
+		vertices[i] = transformVertex(v, mat, normal)
+	}
+
+	faces := append([]Face(nil), g.faces...)
+	if mat.Determinant() < 0 {
+		for i, f := range faces {
+			faces[i] = Face{f.A, f.C, f.B}
+		}
+	}
+	return vertices, faces
+}
+
+// color uniform of a material, def if missing or of another type
+func materialColor(mat *Material, name string, def math.Color) math.Color {
+	if mat == nil {
+		return def
+	}
+	if c, ok := mat.Uniform(name).(math.Color); ok {
+		return c
+	}
+	return def
+}
+
+func materialFloat(mat *Material, name string, def float64) float64 {
+	if mat == nil {
+		return def
+	}
+	if f, ok := mat.Uniform(name).(float64); ok {
+		return f
+	}
+	return def
+}
+
+// diffuse map of a material, nil if missing or not an image texture
+func materialTexture(mat *Material) *ImageTexture {
+	if mat == nil {
+		return nil
+	}
+	t, _ := mat.Uniform("diffuseMap").(*ImageTexture)
+	return t
+}
+
+// path of a file relative to dir, unchanged if there is no relative path
+func exportPath(dir, path string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return path
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(absDir, absPath); err == nil {
+		return rel
+	}
+	return path
+}
+
+// base level of a texture as png
+func encodeTexturePNG(t *ImageTexture) ([]byte, error) {
+	img := t.Image(0)
+	if img == nil {
+		return nil, fmt.Errorf("texture without image data can not be exported")
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// write the renderables of an object tree to an obj file and a mtl file next to it,
+// transformations are baked into the vertices. Generated textures are saved as png files,
+// a single geometry can be exported with NewMesh(geo, nil)
+func ExportOBJ(path string, o Object) error {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	mtlPath := base + ".mtl"
+	dir := filepath.Dir(path)
+
+	var generated int
+	texture := func(t *ImageTexture) (string, error) {
+		if t.Path() != "" {
+			return exportPath(dir, t.Path()), nil
+		}
+
+		data, err := encodeTexturePNG(t)
+		if err != nil {
+			return "", err
+		}
+		generated++
+		p := fmt.Sprintf("%v_texture%v.png", base, generated)
+		if err := ioutil.WriteFile(p, data, 0644); err != nil {
+			return "", err
+		}
+		return filepath.Base(p), nil
+	}
+
+	var obj, mtl bytes.Buffer
+	if err := writeOBJ(&obj, &mtl, o, filepath.Base(mtlPath), texture); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, obj.Bytes(), 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(mtlPath, mtl.Bytes(), 0644)
+}
+
+// texture returns the path a texture is referenced with
+func writeOBJ(w, mtl io.Writer, o Object, mtlName string, texture func(*ImageTexture) (string, error)) error {
+	meshes := exportMeshes(o, math.Identity())
+
+	// materials by first use
+	names := map[*Material]string{}
+	var materials []*Material
+	for _, em := range meshes {
+		mat := em.mesh.Material()
+		if _, found := names[mat]; found {
+			continue
+		}
+		if mat == nil {
+			names[mat] = "default"
+		} else {
+			names[mat] = fmt.Sprintf("material%v", len(materials)+1)
+		}
+		materials = append(materials, mat)
+	}
+
+	fmt.Fprintln(w, "# gisp obj file")
+	if len(materials) > 0 {
+		fmt.Fprintf(w, "mtllib %v\n", mtlName)
+	}
+
+	offset := 1
+	for i, em := range meshes {
+		vertices, faces := bakeGeometry(em.mesh.Geometry(), em.matrix)
+
+		fmt.Fprintf(w, "g mesh%v\n", i+1)
+		fmt.Fprintf(w, "usemtl %v\n", names[em.mesh.Material()])
+
+		// colors only if used
+		colored := false
+		for _, v := range vertices {
+			if v.color != (math.Color{1, 1, 1}) {
+				colored = true
+				break
+			}
+		}
+
+		for _, v := range vertices {
+			p := v.position
+			if colored {
+				fmt.Fprintf(w, "v %v %v %v %v %v %v\n", p[0], p[1], p[2], v.color.R, v.color.G, v.color.B)
+			} else {
+				fmt.Fprintf(w, "v %v %v %v\n", p[0], p[1], p[2])
+			}
+		}
+		for _, v := range vertices {
+			fmt.Fprintf(w, "vt %v %v\n", v.uv[0], 1-v.uv[1])
+		}
+		for _, v := range vertices {
+			fmt.Fprintf(w, "vn %v %v %v\n", v.normal[0], v.normal[1], v.normal[2])
+		}
+
+		// vertex, uv and normal share the index
+		for _, f := range faces {
+			a, b, c := f.A+offset, f.B+offset, f.C+offset
+			fmt.Fprintf(w, "f %v/%v/%v %v/%v/%v %v/%v/%v\n", a, a, a, b, b, b, c, c, c)
+		}
+
+		offset += len(vertices)
+	}
+
+	for _, mat := range materials {
+		if err := writeMTL(mtl, names[mat], mat, texture); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMTL(w io.Writer, name string, mat *Material, texture func(*ImageTexture) (string, error)) error {
+	color := func(key string, c math.Color) {
+		fmt.Fprintf(w, "%v %v %v %v\n", key, c.R, c.G, c.B)
+	}
+
+	fmt.Fprintf(w, "newmtl %v\n", name)
+	color("Ka", materialColor(mat, "ambient", math.Color{1, 1, 1}))
+	color("Kd", materialColor(mat, "diffuse", math.Color{1, 1, 1}))
+
+	specular := materialColor(mat, "specular", math.Color{0, 0, 0})
+	color("Ks", specular)
+	if e := materialColor(mat, "emissive", math.Color{0, 0, 0}); e != (math.Color{0, 0, 0}) {
+		color("Ke", e)
+	}
+	fmt.Fprintf(w, "Ns %v\n", materialFloat(mat, "shininess", 30))
+	fmt.Fprintf(w, "d %v\n", materialFloat(mat, "opacity", 1))
+
+	// color only, without highlights or phong
+	switch {
+	case mat != nil && mat.Shader() == "basic":
+		fmt.Fprintln(w, "illum 0")
+	case specular == (math.Color{0, 0, 0}):
+		fmt.Fprintln(w, "illum 1")
+	default:
+		fmt.Fprintln(w, "illum 2")
+	}
+
+	if t := materialTexture(mat); t != nil {
+		path, err := texture(t)
+		if err != nil {
+			return err
+		}
+
+		// inverse of mtlMap.transform
+		options := ""
+		if tr, ok := mat.Uniform("mapTransform").(math.Vector); ok && tr != (math.Vector{1, 1, 0, 0}) {
+			options = fmt.Sprintf("-s %v %v 1 -o %v %v 0 ", tr[0], tr[1], tr[2], 1-tr[1]-tr[3])
+		}
+		if s, r := t.Wrap(); s == ClampToEdgeWrapping && r == ClampToEdgeWrapping {
+			options += "-clamp on "
+		}
+
+		fmt.Fprintf(w, "map_Kd %v%v\n", options, path)
+	}
+
+	fmt.Fprintln(w)
+	return nil
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+func TestWriteOBJ(t *testing.T) {
+	tex := NewTextureFromImage(image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	tex.path = "textures/wood.png"
+	tex.SetWrap(ClampToEdgeWrapping, ClampToEdgeWrapping)
+
+	mat := &Material{
+		shader: "phong",
+		uniforms: map[string]interface{}{
+			"diffuse":      math.Color{0.5, 0.25, 1},
+			"specular":     math.Color{0.5, 0.5, 0.5},
+			"emissive":     math.Color{0, 0, 0},
+			"shininess":    64.0,
+			"opacity":      0.5,
+			"diffuseMap":   tex,
+			"mapTransform": mtlMap{scale: math.Vector{2, 2, 1}, offset: math.Vector{0.5, 0.25}}.transform(),
+		},
+	}
+
+	// transformed group with a textured and a mirrored, colored cube
+	root := NewGroup()
+	root.SetPosition(math.Vector{10, 0, 0})
+
+	textured := NewMesh(NewCubeGeometry(2), mat)
+	textured.SetScale(math.Vector{2, 2, 2})
+
+	colored := NewCubeGeometry(2)
+	for i := range colored.vertices {
+		colored.vertices[i].color = math.Color{1, 0, 0}
+	}
+	mirrored := NewMesh(colored, nil)
+	mirrored.SetScale(math.Vector{-1, 1, 1})
+
+	root.AddChild(textured, mirrored)
+
+	var obj, mtl bytes.Buffer
+	texture := func(t *ImageTexture) (string, error) { return t.Path(), nil }
+	if err := writeOBJ(&obj, &mtl, root, "test.mtl", texture); err != nil {
+		t.Fatalf("writeOBJ failed: %v", err)
+	}
+
+	// geometry
+	file, err := parseOBJ(&obj, "test.obj")
+	if err != nil {
+		t.Fatalf("parseOBJ failed: %v", err)
+	}
+	if len(file.warnings) != 0 || len(file.libs) != 1 || file.libs[0] != "test.mtl" {
+		t.Errorf("parseOBJ() warnings %v, libs %v", file.warnings, file.libs)
+	}
+	if len(file.meshes) != 2 {
+		t.Fatalf("parseOBJ() != 2 meshes (got %v)", len(file.meshes))
+	}
+
+	tests := []struct {
+		mesh     *objMesh
+		original *Geometry
+		matrix   math.Matrix
+		material string
+		color    math.Color
+	}{
+		{file.meshes[0], textured.Geometry(), root.Matrix().Mul(textured.Matrix()), "material1", math.Color{1, 1, 1}},
+		{file.meshes[1], colored, root.Matrix().Mul(mirrored.Matrix()), "default", math.Color{1, 0, 0}},
+	}
+
+	for i, c := range tests {
+		geo := c.mesh.geo
+		if c.mesh.material != c.material {
+			t.Errorf("mesh %v material != %v (got %v)", i, c.material, c.mesh.material)
+		}
+		if len(geo.vertices) != len(c.original.vertices) || len(geo.faces) != len(c.original.faces) {
+			t.Errorf("mesh %v != %v vertices, %v faces (got %v, %v)", i,
+				len(c.original.vertices), len(c.original.faces), len(geo.vertices), len(geo.faces))
+			continue
+		}
+
+		// vertices are numbered in the order the faces use them
+		for j, o := range c.original.vertices {
+			p := o.position
+			p[3] = 1
+			p = c.matrix.Transform(p)
+			p[3] = 0
+
+			found := false
+			for _, v := range geo.vertices {
+				if v.position.DistanceTo(p) < 1e-9 && v.uv.DistanceTo(o.uv) < 1e-9 && v.color == c.color {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("mesh %v vertex %v with position %v, uv %v and color %v is missing", i, j, p, o.uv, c.color)
+			}
+		}
+
+		if v := testVolume(geo); v <= 0 {
+			t.Errorf("mesh %v is inside out, volume %v", i, v)
+		}
+	}
+
+	// materials
+	materials, warnings, err := parseMTL(&mtl, "test.mtl")
+	if err != nil {
+		t.Fatalf("parseMTL failed: %v", err)
+	}
+	if len(warnings) != 0 || len(materials) != 2 {
+		t.Fatalf("parseMTL() warnings %v, %v materials", warnings, len(materials))
+	}
+
+	m1, m2 := materials[0], materials[1]
+	values := []struct {
+		name     string
+		got      interface{}
+		expected interface{}
+	}{
+		{"name", m1.name, "material1"},
+		{"kd", *m1.kd, math.Color{0.5, 0.25, 1}},
+		{"ks", *m1.ks, math.Color{0.5, 0.5, 0.5}},
+		{"ke", m1.ke == nil, true},
+		{"ns", m1.ns, 64.0},
+		{"d", m1.d, 0.5},
+		{"illum", m1.illum, 2},
+		{"map_kd", m1.maps["map_kd"].path, "textures/wood.png"},
+		{"map_kd -clamp", m1.maps["map_kd"].clamp, true},
+		{"map_kd transform", m1.maps["map_kd"].transform(), mat.Uniform("mapTransform")},
+		{"default", m2.name, "default"},
+		{"default kd", *m2.kd, math.Color{1, 1, 1}},
+		{"default illum", m2.illum, 1},
+	}
+	for _, c := range values {
+		if c.got != c.expected {
+			t.Errorf("writeOBJ() material %v != %v (got %v)", c.name, c.expected, c.got)
+		}
+	}
+}
+
+func TestWriteOBJ_Empty(t *testing.T) {
+	var obj, mtl bytes.Buffer
+	if err := writeOBJ(&obj, &mtl, NewGroup(), "test.mtl", nil); err != nil {
+		t.Fatalf("writeOBJ failed: %v", err)
+	}
+	if strings.Contains(obj.String(), "mtllib") || mtl.Len() != 0 {
+		t.Errorf("writeOBJ() of an empty group references materials:\n%v", obj.String())
+	}
+}
@@ -0,0 +1,281 @@
+package engine
+
+import (
+	"fmt"
+	m "math"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// neighbouring side faces meeting at a sharper angle keep a hard edge
+const shapeCreaseAngle = math.Pi / 4
+
+type ExtrudeOptions struct {
+	Depth float64 // along +z
+	Steps int     // subdivisions along the depth
+
+	BevelThickness float64 // added in front of and behind the depth
+	BevelSize      float64 // outward offset of the outline
+	BevelSegments  int     // no bevel if zero
+}
+
+// outward unit offsets of a closed ring, counter clockwise rings grow, clockwise rings shrink
+func bevelVectors(ring []math.Vector) []math.Vector {
+	vecs := make([]math.Vector, len(ring))
+	n := len(ring)
+
+	for i, p := range ring {
+		prev, next := ring[(i+n-1)%n], ring[(i+1)%n]
+		e1 := math.Vector{p[0] - prev[0], p[1] - prev[1], 0}.Normalize()
+		e2 := math.Vector{next[0] - p[0], next[1] - p[1], 0}.Normalize()
+		n1 := math.Vector{e1[1], -e1[0], 0}
+		n2 := math.Vector{e2[1], -e2[0], 0}
+
+		b := n1.Add(n2)
+		if b.Length() < 1e-9 {
+			vecs[i] = n1
+			continue
+		}
+		b = b.Normalize()
+
+		// keep the offset distance of both edges, limited at spikes
+		s := 1 / m.Max(b.Dot(n1), 0.25)
+		vecs[i] = b.MulScalar(s)
+	}
+
+	return vecs
+}
+
+// solid of a shape pushed along z from 0 to depth with optionally rounded edges,
+// caps are uv mapped with the shape coordinates, sides by outline length and z,
+// fails if it exceeds the vertex limit
+func NewExtrudeGeometry(shape *Shape, opts ExtrudeOptions) (*Geometry, error) {
+	geo := NewGeometry()
+
+	contour, holes := shape.extract()
+	if len(contour) < 3 {
+		return geo, nil
+	}
+	if opts.Steps < 1 {
+		opts.Steps = 1
+	}
+
+	bevel := opts.BevelSegments > 0 && (opts.BevelThickness > 0 || opts.BevelSize > 0)
+	front, back := opts.Depth, 0.0
+	if bevel {
+		front, back = opts.Depth+opts.BevelThickness, -opts.BevelThickness
+	}
+
+	// z and outline offset of each ring layer from back to front
+	type layer struct{ z, offset float64 }
+	var layers []layer
+	var size float64
+
+	if bevel {
+		size = opts.BevelSize
+		for b := 0; b < opts.BevelSegments; b++ {
+			t := float64(b) / float64(opts.BevelSegments) * math.Pi / 2
+			layers = append(layers, layer{-opts.BevelThickness * m.Cos(t), size * m.Sin(t)})
+		}
+	}
+	for s := 0; s <= opts.Steps; s++ {
+		layers = append(layers, layer{opts.Depth * float64(s) / float64(opts.Steps), size})
+	}
+	if bevel {
+		for b := opts.BevelSegments - 1; b >= 0; b-- {
+			t := float64(b) / float64(opts.BevelSegments) * math.Pi / 2
+			layers = append(layers, layer{opts.Depth + opts.BevelThickness*m.Cos(t), size * m.Sin(t)})
+		}
+	}
+
+	color := math.Color{1, 1, 1}
+
+	// caps
+	points := append([]math.Vector(nil), contour...)
+	for _, h := range holes {
+		points = append(points, h...)
+	}
+	faces := Triangulate(contour, holes)
+
+	for _, c := range []struct {
+		z       float64
+		normal  math.Vector
+		reverse bool
+	}{
+		{front, math.Vector{0, 0, 1}, false},
+		{back, math.Vector{0, 0, -1}, true},
+	} {
+		offset := len(geo.vertices)
+		for _, p := range points {
+			geo.AddVertex(NewVertex(math.Vector{p[0], p[1], c.z}, c.normal, math.Vector{p[0], p[1]}, color))
+		}
+		for _, f := range faces {
+			if c.reverse {
+				f.B, f.C = f.C, f.B
+			}
+			geo.AddIndexedFace(f.A+offset, f.B+offset, f.C+offset)
+		}
+	}
+
+	// sides, one closed ring per layer with a duplicated seam vertex
+	sides := NewGeometry()
+	for _, ring := range append([][]math.Vector{contour}, holes...) {
+		vecs := bevelVectors(ring)
+		n := len(ring)
+
+		u := make([]float64, n+1)
+		for k := 1; k <= n; k++ {
+			u[k] = u[k-1] + ring[k-1].DistanceTo(ring[k%n])
+		}
+
+		offset := len(sides.vertices)
+		for _, l := range layers {
+			for k := 0; k <= n; k++ {
+				p := ring[k%n].Add(vecs[k%n].MulScalar(l.offset))
+				sides.AddVertex(NewVertex(math.Vector{p[0], p[1], l.z}, math.Vector{}, math.Vector{u[k], l.z}, color))
+			}
+		}
+
+		for i := 0; i < len(layers)-1; i++ {
+			for k := 0; k < n; k++ {
+				a := offset + i*(n+1) + k
+				b, c, d := a+1, a+n+2, a+n+1
+				sides.AddIndexedFace(a, b, c)
+				sides.AddIndexedFace(a, c, d)
+			}
+		}
+	}
+	sides.ComputeVertexNormals(shapeCreaseAngle)
+
+	if err := geo.Merge(sides, math.Identity()); err != nil {
+		return nil, err
+	}
+
+	return geo, nil
+}
+
+// surface of revolution of a profile of x (radius) and y (height) points around the y axis,
+// the profile runs from bottom to top, sharp profile corners keep a hard edge.
+// Fails if the vertices exceed the limit of the 16 bit indices
+func NewLatheGeometry(points []math.Vector, segments int, phiStart, phiLength float64) (*Geometry, error) {
+	geo := NewGeometry()
+	if len(points) < 2 {
+		return geo, nil
+	}
+	if segments < 3 {
+		segments = 3
+	}
+	if segments+1 > (m.MaxUint16+1)/len(points) {
+		return nil, fmt.Errorf("too many vertices: %v segments of %v points", segments, len(points))
+	}
+	phiLength = m.Max(-2*math.Pi, m.Min(2*math.Pi, phiLength))
+	closed := m.Abs(phiLength) == 2*math.Pi
+
+	color := math.Color{1, 1, 1}
+	rows := len(points)
+
+	for i := 0; i <= segments; i++ {
+		phi := phiStart + float64(i)/float64(segments)*phiLength
+		sin, cos := m.Sin(phi), m.Cos(phi)
+
+		for j, p := range points {
+			pos := math.Vector{p[0] * sin, p[1], p[0] * cos}
+			if closed && i == segments {
+				// identical seam positions for smooth normals
+				pos = geo.vertices[j].position
+			}
+			uv := math.Vector{float64(i) / float64(segments), float64(j) / float64(rows-1)}
+			geo.AddVertex(NewVertex(pos, math.Vector{}, uv, color))
+		}
+	}
+
+	for i := 0; i < segments; i++ {
+		for j := 0; j < rows-1; j++ {
+			a := i*rows + j
+			b, c, d := a+rows, a+rows+1, a+1
+
+			// no slivers at the axis
+			if points[j][0] != 0 {
+				geo.AddIndexedFace(a, b, c)
+			}
+			if points[j+1][0] != 0 {
+				geo.AddIndexedFace(a, c, d)
+			}
+		}
+	}
+
+	if phiLength < 0 {
+		for i, f := range geo.faces {
+			geo.faces[i].B, geo.faces[i].C = f.C, f.B
+		}
+		geo.lines = geo.lines[:0]
+		for _, f := range geo.faces {
+			geo.lines = append(geo.lines, f.ToLines()...)
+		}
+	}
+
+	// hard edges split vertices
+	geo.ComputeVertexNormals(shapeCreaseAngle)
+	if err := geo.Validate(); err != nil {
+		return nil, err
+	}
+	geo.ComputeBoundary()
+
+	return geo, nil
+}
+
+// tube of constant radius swept along a curve, u runs along the curve and v around it.
+// Fails if the vertices exceed the limit of the 16 bit indices
+func NewTubeGeometry(path Curve, tubularSegments int, radius float64, radialSegments int, closed bool) (*Geometry, error) {
+	geo := NewGeometry()
+	if tubularSegments < 1 {
+		tubularSegments = 1
+	}
+	if radialSegments < 3 {
+		radialSegments = 3
+	}
+	if tubularSegments+1 > (m.MaxUint16+1)/(radialSegments+1) {
+		return nil, fmt.Errorf("too many vertices: %v tubular and %v radial segments", tubularSegments, radialSegments)
+	}
+
+	points, _, normals, binormals := curveFrames(path, tubularSegments, closed)
+	color := math.Color{1, 1, 1}
+
+	for i := 0; i <= tubularSegments; i++ {
+		for j := 0; j <= radialSegments; j++ {
+			switch {
+			case closed && i == tubularSegments:
+				// identical seam positions
+				v := geo.vertices[j]
+				v.uv = math.Vector{1, v.uv[1]}
+				geo.AddVertex(v)
+				continue
+			case j == radialSegments:
+				v := geo.vertices[len(geo.vertices)-radialSegments]
+				v.uv = math.Vector{v.uv[0], 1}
+				geo.AddVertex(v)
+				continue
+			}
+
+			angle := float64(j) / float64(radialSegments) * 2 * math.Pi
+			n := normals[i].MulScalar(m.Cos(angle)).Add(binormals[i].MulScalar(m.Sin(angle))).Normalize()
+			uv := math.Vector{float64(i) / float64(tubularSegments), float64(j) / float64(radialSegments)}
+
+			geo.AddVertex(NewVertex(points[i].Add(n.MulScalar(radius)), n, uv, color))
+		}
+	}
+
+	row := radialSegments + 1
+	for i := 0; i < tubularSegments; i++ {
+		for j := 0; j < radialSegments; j++ {
+			a := i*row + j
+			b, c, d := a+row, a+row+1, a+1
+			geo.AddIndexedFace(a, d, b)
+			geo.AddIndexedFace(b, d, c)
+		}
+	}
+
+	geo.ComputeBoundary()
+
+	return geo, nil
+}
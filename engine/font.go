@@ -3,289 +3,410 @@ package engine
 import (
 	"fmt"
 	"image"
-	"image/color"
+	"image/draw"
 	"io/ioutil"
+	"log"
 	m "math"
+	"runtime"
+	"sort"
+	"sync"
+	"unicode"
 
 	"github.com/der-antikeks/gisp/math"
 
 	"code.google.com/p/freetype-go/freetype"
-	"github.com/go-gl/gl"
+	"code.google.com/p/freetype-go/freetype/truetype"
 )
 
 type Glyph struct {
-	x, y    int
-	w, h    int
-	advance int
+	page      int
+	x, y      int     // position in the atlas page
+	w, h      int     // size including the padding
+	left, top int     // offset of the upper left corner to the pen position, y up
+	advance   float64 // in pixels
+
+	index truetype.Index
+}
+
+type FontOptions struct {
+	Size     float64 // in points, 32 if zero
+	DPI      float64 // screen resolution in dots per inch, 72 if zero
+	Spread   int     // signed distance radius in pixels, 4 if zero
+	PageSize int     // maximum edge length of an atlas page, 1024 if zero
+	Cache    string  // file of the baked atlas, reused as long as font and options match
+
+	// included characters, printable ascii if both are empty
+	Runes  []rune
+	Ranges []*unicode.RangeTable // e.g. unicode.Latin or unicode.Cyrillic
+}
+
+func (o FontOptions) withDefaults() FontOptions {
+	if o.Size <= 0 {
+		o.Size = 32
+	}
+	if o.DPI <= 0 {
+		o.DPI = 72
+	}
+	if o.Spread <= 0 {
+		o.Spread = 4
+	}
+	if o.PageSize <= 0 {
+		o.PageSize = 1024
+	}
+	if len(o.Runes) == 0 && len(o.Ranges) == 0 {
+		o.Runes = RuneRange(32, 126)
+	}
+	return o
+}
+
+// all runes from low to high
+func RuneRange(low, high rune) []rune {
+	var runes []rune
+	for r := low; r <= high; r++ {
+		runes = append(runes, r)
+	}
+	return runes
+}
+
+type runeSlice []rune
+
+func (r runeSlice) Len() int           { return len(r) }
+func (r runeSlice) Less(i, j int) bool { return r[i] < r[j] }
+func (r runeSlice) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+
+// sorted graphic runes of the options
+func (o FontOptions) runes() []rune {
+	set := make(map[rune]bool)
+	for _, r := range o.Runes {
+		set[r] = true
+	}
+	for _, t := range o.Ranges {
+		for _, r := range t.R16 {
+			for c := rune(r.Lo); c <= rune(r.Hi); c += rune(r.Stride) {
+				set[c] = true
+			}
+		}
+		for _, r := range t.R32 {
+			for c := rune(r.Lo); c <= rune(r.Hi); c += rune(r.Stride) {
+				set[c] = true
+			}
+		}
+	}
+
+	runes := make([]rune, 0, len(set))
+	for r := range set {
+		if unicode.IsGraphic(r) {
+			runes = append(runes, r)
+		}
+	}
+	sort.Stable(runeSlice(runes))
+
+	return runes
+}
+
+// distance field effects of the font material, lengths are in pixels of the atlas
+// and should not exceed the spread of the font
+type TextStyle struct {
+	Color     math.Color // multiplied with the character colors
+	Smoothing float64    // softness of the edges in distance field units
+
+	OutlineColor math.Color
+	OutlineWidth float64
+
+	ShadowColor    math.Color
+	ShadowOpacity  float64     // no shadow if zero
+	ShadowOffset   math.Vector // y down
+	ShadowSoftness float64
+
+	GlowColor   math.Color
+	GlowOpacity float64 // no glow if zero
+	GlowWidth   float64
+}
+
+func (s TextStyle) apply(mat *Material) {
+	mat.SetUniform("diffuse", s.Color)
+	mat.SetUniform("smoothing", s.Smoothing)
+
+	mat.SetUniform("outlineColor", s.OutlineColor)
+	mat.SetUniform("outlineWidth", s.OutlineWidth)
+
+	mat.SetUniform("shadowColor", s.ShadowColor)
+	mat.SetUniform("shadowOpacity", s.ShadowOpacity)
+	mat.SetUniform("shadowOffset", s.ShadowOffset)
+	mat.SetUniform("shadowSoftness", s.ShadowSoftness)
+
+	mat.SetUniform("glowColor", s.GlowColor)
+	mat.SetUniform("glowOpacity", s.GlowOpacity)
+	mat.SetUniform("glowWidth", s.GlowWidth)
 }
 
 // http://www.valvesoftware.com/publications/2007/SIGGRAPH2007_AlphaTestedMagnification.pdf
 type Font struct {
-	material      *Material
-	width, height int
-	charset       map[rune]Glyph
+	pages     []*Material // one per atlas page
+	pageSizes []image.Point
+	charset   map[rune]Glyph
+
+	font  *truetype.Font
+	scale int32   // 26.6 fixed point pixels per em
+	size  float64 // pixels per em
+
+	ascent, descent float64 // in pixels, descent is negative
+
+	spread int
+	style  TextStyle
 }
 
-func LoadFont(fontfile string /*, size, low, high int*/) (*Font, error) {
-	dpi := 72.0           // screen resolution in dots per inch
-	size := 32.0          // font size in points
-	low, high := 32, 127  // lower, upper rune limits
-	spread := 4           // signed distance radius
-	padding := spread + 1 // padding between glyphs
+func LoadFont(fontfile string, opts FontOptions) (*Font, error) {
+	opts = opts.withDefaults()
+
+	// read font data
+	data, err := ioutil.ReadFile(fontfile)
+	if err != nil {
+		return nil, err
+	}
 
-	// load truetype into image
-	glyphs, img, err := loadTruetype(fontfile, dpi, size, low, high, padding)
+	font, err := freetype.ParseFont(data)
 	if err != nil {
 		return nil, err
 	}
 
-	// generate distance field
-	img = generateDistanceField(img, glyphs, spread)
-	bounds := img.Bounds().Size()
+	// baked atlas of a previous run
+	var pages []*image.RGBA
+	var glyphs map[rune]Glyph
+	key := fontAtlasKey(data, opts)
 
-	// generate texture
-	tex := &ImageTexture{
-		image: []*image.RGBA{img},
+	if opts.Cache != "" {
+		pages, glyphs, _ = loadFontAtlas(opts.Cache, key)
+	}
+	if pages == nil {
+		if pages, glyphs, err = bakeFont(font, opts); err != nil {
+			return nil, err
+		}
 
-		wrapS:     gl.REPEAT,
-		wrapT:     gl.REPEAT,
-		magFilter: gl.LINEAR,
-		minFilter: gl.LINEAR_MIPMAP_LINEAR,
+		if opts.Cache != "" {
+			if err := saveFontAtlas(opts.Cache, key, pages, glyphs); err != nil {
+				log.Printf("could not save font atlas: %v\n", err)
+			}
+		}
+	}
 
-		needsUpdate: true,
+	f := &Font{
+		charset: glyphs,
+		font:    font,
+		scale:   int32(opts.Size * opts.DPI / 72 * 64),
+		size:    opts.Size * opts.DPI / 72,
+		spread:  opts.Spread,
+		style: TextStyle{
+			Color:     math.Color{1, 0, 1},
+			Smoothing: 0.25,
+		},
 	}
 
-	// load material
-	mat, err := NewMaterial("font")
-	if err != nil {
-		return nil, err
+	bounds := font.Bounds(f.scale)
+	f.ascent, f.descent = float64(bounds.YMax)/64, float64(bounds.YMin)/64
+
+	for _, p := range pages {
+		f.pageSizes = append(f.pageSizes, p.Bounds().Size())
+
+		// generate texture
+		tex := NewTextureFromImage(p)
+		tex.SetColorSpace(Linear) // distances
+
+		// load material
+		mat, err := NewMaterial("font")
+		if err != nil {
+			tex.Dispose()
+			f.Dispose()
+			return nil, err
+		}
+		mat.SetUniform("distanceFieldMap", tex)
+		mat.SetUniform("spread", float64(f.spread))
+		f.style.apply(mat)
+
+		f.pages = append(f.pages, mat)
 	}
-	mat.SetUniform("distanceFieldMap", tex)
-	mat.SetUniform("diffuse", math.Color{1, 0, 1})
-	mat.SetUniform("smoothing", 0.25 /* / (float64(spread) * scale)*/)
-
-	return &Font{
-		material: mat,
-		charset:  glyphs,
-		width:    bounds.X,
-		height:   bounds.Y,
-	}, nil
+
+	return f, nil
 }
 
+// releases the page textures, the materials share the cached font program
+// which has to outlive the font
 func (f *Font) Dispose() {
-	if f.material != nil {
-		f.material.Dispose()
+	for _, p := range f.pages {
+		if t, ok := p.Uniform("distanceFieldMap").(Texture); ok {
+			t.Dispose()
+		}
 	}
 }
 
-func (f *Font) Printf(format string, a ...interface{}) *Mesh {
-	geo := &Geometry{
-		initialized: false,
-		needsUpdate: true,
-		hint:        gl.STATIC_DRAW,
+func (f *Font) Style() TextStyle {
+	return f.style
+}
+
+// style of all texts without their own
+func (f *Font) SetStyle(s TextStyle) {
+	f.style = s
+	for _, p := range f.pages {
+		s.apply(p)
 	}
+}
 
-	normal := math.Vector{0, 0, 1}
-	color := math.Color{1, 1, 1}
-	str := fmt.Sprintf(format, a...)
-	size := 1.0
-	sx := 0.0
-
-	for _, c := range str {
-		glyph, found := f.charset[c]
-		if !found {
-			continue
-		}
+// pixels per em
+func (f *Font) Size() float64 {
+	return f.size
+}
 
-		x := float64(glyph.x) / float64(f.width)
-		y := float64(glyph.y) / float64(f.height)
-		w := float64(glyph.advance) / float64(f.width)
-		h := float64(glyph.h) / float64(f.height)
-
-		a := math.Vector{sx + size*w, size * h, 0}
-		b := math.Vector{sx, size * h, 0}
-		c := math.Vector{sx, 0, 0}
-		d := math.Vector{sx + size*w, 0, 0}
-		sx += size * w
-
-		tl := math.Vector{x, y}
-		tr := math.Vector{x + w, y}
-		bl := math.Vector{x, y + h}
-		br := math.Vector{x + w, y + h}
-
-		geo.AddFace(
-			Vertex{
-				position: a,
-				normal:   normal,
-				uv:       tr,
-				color:    color,
-			}, Vertex{
-				position: b,
-				normal:   normal,
-				uv:       tl,
-				color:    color,
-			}, Vertex{
-				position: c,
-				normal:   normal,
-				uv:       bl,
-				color:    color,
-			})
-		geo.AddFace(
-			Vertex{
-				position: c,
-				normal:   normal,
-				uv:       bl,
-				color:    color,
-			}, Vertex{
-				position: d,
-				normal:   normal,
-				uv:       br,
-				color:    color,
-			}, Vertex{
-				position: a,
-				normal:   normal,
-				uv:       tr,
-				color:    color,
-			})
+func (f *Font) HasGlyph(r rune) bool {
+	_, found := f.charset[r]
+	return found
+}
 
+// append a glyph quad, positions in pixels
+func (f *Font) addGlyph(geo *Geometry, g Glyph, x, y float64, color math.Color) {
+	if g.w == 0 || g.h == 0 {
+		return
 	}
 
-	geo.MergeVertices()
-	geo.ComputeBoundary()
+	positions, uvs := f.glyphQuad(g, x, y)
+	normal := math.Vector{0, 0, 1}
+
+	a := geo.AddVertex(NewVertex(positions[0], normal, uvs[0], color))
+	b := geo.AddVertex(NewVertex(positions[1], normal, uvs[1], color))
+	c := geo.AddVertex(NewVertex(positions[2], normal, uvs[2], color))
+	d := geo.AddVertex(NewVertex(positions[3], normal, uvs[3], color))
 
-	return &Mesh{
-		geometry: geo,
-		material: f.material,
+	geo.AddIndexedFace(a, b, c)
+	geo.AddIndexedFace(c, d, a)
+}
 
-		up:    math.Vector{0, 1, 0},
-		scale: math.Vector{1, 1, 1},
+// corners of a glyph quad in units of the font size, counter clockwise from the top right
+func (f *Font) glyphQuad(g Glyph, x, y float64) (positions, uvs [4]math.Vector) {
+	x0, y1 := (x+float64(g.left))/f.size, (y+float64(g.top))/f.size
+	x1, y0 := x0+float64(g.w)/f.size, y1-float64(g.h)/f.size
 
-		matrixNeedsUpdate:      true,
-		matrixWorldNeedsUpdate: true,
-	}
+	ps := f.pageSizes[g.page]
+	u0, v0 := float64(g.x)/float64(ps.X), float64(g.y)/float64(ps.Y)
+	u1, v1 := float64(g.x+g.w)/float64(ps.X), float64(g.y+g.h)/float64(ps.Y)
+
+	positions = [4]math.Vector{{x1, y1, 0}, {x0, y1, 0}, {x0, y0, 0}, {x1, y0, 0}}
+	uvs = [4]math.Vector{{u1, v0}, {u0, v0}, {u0, v1}, {u1, v1}}
+	return
 }
 
-func loadTruetype(fontfile string, dpi, size float64, low, high, padding int) (map[rune]Glyph, *image.RGBA, error) {
-	// read font data
-	fontBytes, err := ioutil.ReadFile(fontfile)
+// single line text mesh, see Print
+func (f *Font) Printf(format string, a ...interface{}) *Mesh {
+	return f.Print(fmt.Sprintf(format, a...), TextLayout{})
+}
+
+// rendered glyphs with signed distance fields distributed over atlas pages
+func bakeFont(font *truetype.Font, opts FontOptions) ([]*image.RGBA, map[rune]Glyph, error) {
+	padding := opts.Spread + 1 // padding between glyphs
+
+	// render glyphs
+	glyphs, images, err := renderGlyphs(font, opts.DPI, opts.Size, opts.runes(), padding)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	font, err := freetype.ParseFont(fontBytes)
-	if err != nil {
-		return nil, nil, err
+	// distribute over pages
+	runes := make([]rune, 0, len(glyphs))
+	for r := range glyphs {
+		runes = append(runes, r)
+	}
+	sort.Stable(runeSlice(runes))
+
+	sizes := make([]image.Point, len(runes))
+	for i, r := range runes {
+		sizes[i] = image.Point{glyphs[r].w, glyphs[r].h}
+	}
+	slots, pageSizes := packAtlas(sizes, opts.PageSize)
+
+	pages := make([]*image.RGBA, len(pageSizes))
+	for i, s := range pageSizes {
+		pages[i] = image.NewRGBA(image.Rect(0, 0, s.X, s.Y))
 	}
 
-	// create image
-	glyphCounts := high - low + 1
-	glyphsPerRow := int(m.Ceil(m.Sqrt(float64(glyphCounts))))
-	glyphsPerCol := (glyphCounts / glyphsPerRow) + 1
+	for i, r := range runes {
+		g := glyphs[r]
+		g.page, g.x, g.y = slots[i].page, slots[i].x, slots[i].y
+		glyphs[r] = g
+	}
+
+	// generate distance fields in parallel, glyphs cover disjoint parts of the pages
+	jobs := make(chan rune)
+	var wg sync.WaitGroup
+
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				g := glyphs[r]
+				df := generateDistanceField(images[r], opts.Spread)
+				draw.Draw(pages[g.page], image.Rect(g.x, g.y, g.x+g.w, g.y+g.h), df, image.ZP, draw.Src)
+			}
+		}()
+	}
 
-	glyphBounds := font.Bounds(int32(size))
-	glyphWidth := int(glyphBounds.XMax-glyphBounds.XMin) + padding*2
-	glyphHeight := int(glyphBounds.YMax-glyphBounds.YMin) + padding*2
+	for r := range images {
+		jobs <- r
+	}
+	close(jobs)
+	wg.Wait()
+
+	return pages, glyphs, nil
+}
 
-	rgba := image.NewRGBA(image.Rect(0, 0,
-		math.NextHighestPowerOfTwo(glyphWidth*glyphsPerRow),
-		math.NextHighestPowerOfTwo(glyphHeight*glyphsPerCol)))
-	//draw.Draw(rgba, rgba.Bounds(), image.Black, image.ZP, draw.Src)
+func renderGlyphs(font *truetype.Font, dpi, size float64, runes []rune, padding int) (map[rune]Glyph, map[rune]*image.RGBA, error) {
+	scale := int32(size * dpi / 72 * 64)
 
 	// initialize context
 	c := freetype.NewContext()
 	c.SetDPI(dpi)
 	c.SetFont(font)
 	c.SetFontSize(size)
-	c.SetClip(rgba.Bounds())
-	c.SetDst(rgba)
 	c.SetSrc(image.White)
 
-	// draw runes
+	// draw runes, each into a tight image
 	glyphs := make(map[rune]Glyph)
-	var glyphNum, glyphX, glyphY int
+	images := make(map[rune]*image.RGBA)
+	buf := truetype.NewGlyphBuf()
 
-	for ch := low; ch <= high; ch++ {
-		glyphX = (glyphNum % glyphsPerRow) * glyphWidth
-		glyphY = (glyphNum / glyphsPerRow) * glyphHeight
-
-		metric := font.HMetric(int32(size), font.Index(rune(ch)))
-		advance := int(metric.AdvanceWidth) + padding
-
-		glyphs[rune(ch)] = Glyph{glyphX, glyphY, glyphWidth, glyphHeight, advance}
-
-		pt := freetype.Pt(glyphX+padding, glyphY+padding+int(c.PointToFix32(size)>>8))
-		if _, err = c.DrawString(string(ch), pt); err != nil {
-			return nil, nil, err
+	for _, r := range runes {
+		index := font.Index(r)
+		if index == 0 {
+			continue // not in the font
 		}
 
-		glyphNum++
-	}
-
-	return glyphs, rgba, nil
-}
-
-func generateDistanceField(in *image.RGBA, glyphs map[rune]Glyph, spread int) *image.RGBA {
-	out := image.NewRGBA(in.Bounds())
-
-	for _, glyph := range glyphs {
-		// create mask
-		mask := make([][]bool, glyph.h)
-
-		for y := 0; y < glyph.h; y++ {
-			if mask[y] == nil {
-				mask[y] = make([]bool, glyph.w)
-			}
-
-			for x := 0; x < glyph.w; x++ {
-				r, g, b, a := in.At(glyph.x+x, glyph.y+y).RGBA()
-				mask[y][x] = (r >= 0x7fff || g >= 0x7fff || b >= 0x7fff) && (a >= 0x7fff)
-			}
+		metric := font.HMetric(scale, index)
+		glyph := Glyph{
+			advance: float64(metric.AdvanceWidth) / 64,
+			index:   index,
 		}
 
-		// find signed distance
-		for y := 0; y < glyph.h; y++ {
-			for x := 0; x < glyph.w; x++ {
-				out.Set(
-					glyph.x+x, glyph.y+y,
-					distanceToColor(
-						findSignedDistance(x, y, spread, mask),
-						spread))
-			}
+		if err := buf.Load(font, scale, index, truetype.NoHinting); err != nil {
+			return nil, nil, err
 		}
-	}
-
-	return out
-}
+		xmin, ymin := int(m.Floor(float64(buf.B.XMin)/64)), int(m.Floor(float64(buf.B.YMin)/64))
+		xmax, ymax := int(m.Ceil(float64(buf.B.XMax)/64)), int(m.Ceil(float64(buf.B.YMax)/64))
 
-func findSignedDistance(centerX, centerY, delta int, mask [][]bool) float64 {
-	width := len(mask[0])
-	height := len(mask)
-	base := mask[centerY][centerX]
+		if xmax > xmin && ymax > ymin {
+			glyph.w, glyph.h = xmax-xmin+padding*2, ymax-ymin+padding*2
+			glyph.left, glyph.top = xmin-padding, ymax+padding
 
-	startX, endX := int(m.Max(0, float64(centerX-delta))), int(m.Min(float64(centerX+delta), float64(width-1)))
-	startY, endY := int(m.Max(0, float64(centerY-delta))), int(m.Min(float64(centerY+delta), float64(height-1)))
+			img := image.NewRGBA(image.Rect(0, 0, glyph.w, glyph.h))
+			c.SetClip(img.Bounds())
+			c.SetDst(img)
 
-	closestSquareDist := delta * delta
-
-	for y := startY; y <= endY; y++ {
-		for x := startX; x <= endX; x++ {
-			if base != mask[y][x] {
-				squareDist := (centerX-x)*(centerX-x) + (centerY-y)*(centerY-y)
-				if squareDist < closestSquareDist {
-					closestSquareDist = squareDist
-				}
+			pt := freetype.Pt(padding-xmin, padding+ymax)
+			if _, err := c.DrawString(string(r), pt); err != nil {
+				return nil, nil, err
 			}
+			images[r] = img
 		}
-	}
 
-	closestDist := m.Sqrt(float64(closestSquareDist))
-	if base {
-		return m.Min(closestDist, float64(delta))
+		glyphs[r] = glyph
 	}
-	return -m.Min(closestDist, float64(delta))
-}
 
-func distanceToColor(distance float64, spread int) color.Color {
-	c := uint8(m.Min(1, m.Max(0, 0.5+0.5*(distance/float64(spread)))) * 0xff)
-	return color.RGBA{c, c, c, c} // premultiplied alpha white
+	return glyphs, images, nil
 }
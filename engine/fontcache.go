@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/png"
+	"os"
+
+	"code.google.com/p/freetype-go/freetype/truetype"
+)
+
+// increased with every change of the baking or the file layout
+const fontAtlasVersion = 1
+
+type fontAtlasGlyph struct {
+	Rune            rune
+	Page, X, Y      int
+	W, H, Left, Top int
+	Advance         float64
+	Index           uint16
+}
+
+type fontAtlasFile struct {
+	Version int
+	Key     uint64
+	Glyphs  []fontAtlasGlyph
+	Pages   [][]byte // png, the distances are in the gray channel
+}
+
+// identifies the font data and the options a cached atlas was baked with
+func fontAtlasKey(data []byte, opts FontOptions) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	fmt.Fprint(h, opts.Size, opts.DPI, opts.Spread, opts.PageSize, opts.runes())
+	return h.Sum64()
+}
+
+func saveFontAtlas(path string, key uint64, pages []*image.RGBA, glyphs map[rune]Glyph) error {
+	file := fontAtlasFile{
+		Version: fontAtlasVersion,
+		Key:     key,
+	}
+
+	for r, g := range glyphs {
+		file.Glyphs = append(file.Glyphs, fontAtlasGlyph{
+			Rune: r,
+			Page: g.page, X: g.x, Y: g.y,
+			W: g.w, H: g.h, Left: g.left, Top: g.top,
+			Advance: g.advance,
+			Index:   uint16(g.index),
+		})
+	}
+
+	for _, p := range pages {
+		// premultiplied white, alpha equals the other channels
+		gray := image.NewGray(p.Bounds())
+		for i := range gray.Pix {
+			gray.Pix[i] = p.Pix[i*4+3]
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, gray); err != nil {
+			return err
+		}
+		file.Pages = append(file.Pages, buf.Bytes())
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := gob.NewEncoder(out).Encode(file); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func loadFontAtlas(path string, key uint64) ([]*image.RGBA, map[rune]Glyph, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer in.Close()
+
+	var file fontAtlasFile
+	if err := gob.NewDecoder(in).Decode(&file); err != nil {
+		return nil, nil, err
+	}
+	if file.Version != fontAtlasVersion || file.Key != key {
+		return nil, nil, errors.New("font atlas is outdated")
+	}
+
+	pages := make([]*image.RGBA, len(file.Pages))
+	for i, data := range file.Pages {
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		gray, ok := img.(*image.Gray)
+		if !ok {
+			return nil, nil, fmt.Errorf("unexpected font atlas page format %T", img)
+		}
+
+		pages[i] = image.NewRGBA(gray.Bounds())
+		for j, c := range gray.Pix {
+			pages[i].Pix[j*4], pages[i].Pix[j*4+1], pages[i].Pix[j*4+2], pages[i].Pix[j*4+3] = c, c, c, c
+		}
+	}
+
+	glyphs := make(map[rune]Glyph, len(file.Glyphs))
+	for _, g := range file.Glyphs {
+		if g.Page < 0 || g.Page >= len(pages) {
+			return nil, nil, fmt.Errorf("glyph %q on missing page %v", g.Rune, g.Page)
+		}
+		glyphs[g.Rune] = Glyph{
+			page: g.Page, x: g.X, y: g.Y,
+			w: g.W, h: g.H, left: g.Left, top: g.Top,
+			advance: g.Advance,
+			index:   truetype.Index(g.Index),
+		}
+	}
+
+	return pages, glyphs, nil
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFontAtlas_SaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fontcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "atlas.cache")
+
+	pages := []*image.RGBA{image.NewRGBA(image.Rect(0, 0, 64, 32)), image.NewRGBA(image.Rect(0, 0, 16, 16))}
+	for i := range pages[0].Pix {
+		pages[0].Pix[i] = uint8(i / 4)
+	}
+	glyphs := map[rune]Glyph{
+		'a': {page: 0, x: 1, y: 2, w: 12, h: 14, left: -1, top: 9, advance: 10.5, index: 68},
+		'b': {page: 1, w: 16, h: 16, advance: 11},
+		' ': {advance: 5, index: 3},
+	}
+
+	opts := FontOptions{}.withDefaults()
+	key := fontAtlasKey([]byte("font"), opts)
+
+	if err := saveFontAtlas(path, key, pages, glyphs); err != nil {
+		t.Fatal(err)
+	}
+
+	p, g, err := loadFontAtlas(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, pages) {
+		t.Errorf("loadFontAtlas() pages differ")
+	}
+	if !reflect.DeepEqual(g, glyphs) {
+		t.Errorf("loadFontAtlas() glyphs != %v (got %v)", glyphs, g)
+	}
+
+	// stale caches are rejected
+	tests := []struct {
+		data []byte
+		opts FontOptions
+	}{
+		{[]byte("other font"), opts},
+		{[]byte("font"), FontOptions{Size: 48}.withDefaults()},
+		{[]byte("font"), FontOptions{Runes: []rune("abc")}.withDefaults()},
+	}
+
+	for _, c := range tests {
+		if _, _, err := loadFontAtlas(path, fontAtlasKey(c.data, c.opts)); err == nil {
+			t.Errorf("loadFontAtlas(%q, %+v) accepted a stale atlas", c.data, c.opts)
+		}
+	}
+}
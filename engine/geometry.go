@@ -2,6 +2,7 @@ package engine
 
 import (
 	"fmt"
+	"log"
 	m "math"
 
 	"github.com/der-antikeks/gisp/math"
@@ -15,8 +16,24 @@ type Vertex struct {
 	normal   math.Vector
 	uv       math.Vector
 	color    math.Color
+	tangent  math.Vector // w is the bitangent handedness
 }
 
+func NewVertex(position, normal, uv math.Vector, color math.Color) Vertex {
+	return Vertex{
+		position: position,
+		normal:   normal,
+		uv:       uv,
+		color:    color,
+	}
+}
+
+func (v Vertex) Position() math.Vector { return v.position }
+func (v Vertex) Normal() math.Vector   { return v.normal }
+func (v Vertex) UV() math.Vector       { return v.uv }
+func (v Vertex) Color() math.Color     { return v.color }
+func (v Vertex) Tangent() math.Vector  { return v.tangent }
+
 func (v Vertex) Key(precision int) string {
 	return fmt.Sprintf("%v_%v_%v_%v_%v_%v_%v_%v_%v_%v_%v",
 		math.Round(v.position[0], precision),
@@ -52,6 +69,38 @@ type Line struct {
 	A, B int
 }
 
+// expected update frequency of the vertex data
+type GeometryUsage int
+
+const (
+	StaticDrawUsage  GeometryUsage = gl.STATIC_DRAW  // set once
+	DynamicDrawUsage GeometryUsage = gl.DYNAMIC_DRAW // modified repeatedly
+	StreamDrawUsage  GeometryUsage = gl.STREAM_DRAW  // modified every frame
+)
+
+// vertices changed since the last upload, empty if min >= max
+type vertexRange struct {
+	min, max int
+}
+
+func (r *vertexRange) add(from, to int) {
+	if r.empty() {
+		r.min, r.max = from, to
+		return
+	}
+
+	if from < r.min {
+		r.min = from
+	}
+	if to > r.max {
+		r.max = to
+	}
+}
+
+func (r vertexRange) empty() bool {
+	return r.min >= r.max
+}
+
 type Geometry struct {
 	// data slices
 	vertices []Vertex
@@ -66,6 +115,7 @@ type Geometry struct {
 	normalBuffer      gl.Buffer
 	uvBuffer          gl.Buffer
 	colorBuffer       gl.Buffer
+	tangentBuffer     gl.Buffer
 	initialized       bool
 
 	faceArray     []uint16 // uint32 (4 byte) if points > 65535
@@ -74,12 +124,24 @@ type Geometry struct {
 	normalArray   []float32
 	uvArray       []float32
 	colorArray    []float32
+	tangentArray  []float32
+	hasTangents   bool
 	needsUpdate   bool
 
+	// partial updates
+	positionRange vertexRange
+	normalRange   vertexRange
+	uvRange       vertexRange
+	colorRange    vertexRange
+
 	hint gl.GLenum //  gl.STATIC_DRAW, gl.DYNAMIC_DRAW
 
+	linearColors bool // uploaded vertex colors are converted to linear, see setLinearColors
+
 	// boundings
-	bounding math.Boundary
+	bounding      math.Boundary
+	boundsDirty   bool // recomputed on the next call of Boundary
+	boundsVersion int  // incremented on every change, to refit scene proxies
 }
 
 func NewGeometry() *Geometry {
@@ -586,31 +648,183 @@ func (g *Geometry) AddFace(a, b, c Vertex) {
 	g.vertices = append(g.vertices, a, b, c)
 	g.faces = append(g.faces, Face{offset, offset + 1, offset + 2})
 	g.lines = append(g.lines, Line{offset, offset + 1}, Line{offset + 1, offset + 2}, Line{offset + 2, offset})
+	g.needsUpdate = true
+}
+
+// append a vertex without faces, returns its index. The vertex limit of the
+// 16 bit indices is checked by Validate
+func (g *Geometry) AddVertex(v Vertex) int {
+	g.vertices = append(g.vertices, v)
+	g.needsUpdate = true
+
+	return len(g.vertices) - 1
+}
+
+// add a face of already added vertices, counter clockwise. The indices are checked by Validate
+func (g *Geometry) AddIndexedFace(a, b, c int) {
+	f := Face{a, b, c}
+	g.faces = append(g.faces, f)
+	g.lines = append(g.lines, f.ToLines()...)
+	g.needsUpdate = true
+}
+
+// vertex limit of the 16 bit indices and references to missing vertices,
+// invalid geometries are not drawn
+func (g *Geometry) Validate() error {
+	n := len(g.vertices)
+	if n > m.MaxUint16+1 {
+		return fmt.Errorf("too many vertices: %v", n)
+	}
+
+	for i, f := range g.faces {
+		if f.A < 0 || f.A >= n || f.B < 0 || f.B >= n || f.C < 0 || f.C >= n {
+			return fmt.Errorf("face %v references missing vertex", i)
+		}
+	}
+	for i, l := range g.lines {
+		if l.A < 0 || l.A >= n || l.B < 0 || l.B >= n {
+			return fmt.Errorf("line %v references missing vertex", i)
+		}
+	}
+
+	return nil
+}
+
+// geometry of vertex attribute lists and triangle indices, missing normals and uvs are zero,
+// missing colors white
+func NewIndexedGeometry(positions, normals, uvs []math.Vector, colors []math.Color, indices []int) (*Geometry, error) {
+	n := len(positions)
+	switch {
+	case n > m.MaxUint16+1:
+		return nil, fmt.Errorf("too many vertices: %v", n)
+	case normals != nil && len(normals) != n:
+		return nil, fmt.Errorf("normal count %v does not match vertex count %v", len(normals), n)
+	case uvs != nil && len(uvs) != n:
+		return nil, fmt.Errorf("uv count %v does not match vertex count %v", len(uvs), n)
+	case colors != nil && len(colors) != n:
+		return nil, fmt.Errorf("color count %v does not match vertex count %v", len(colors), n)
+	case len(indices)%3 != 0:
+		return nil, fmt.Errorf("index count %v is not a multiple of 3", len(indices))
+	}
+
+	geo := NewGeometry()
+	geo.vertices = make([]Vertex, n)
+
+	for i, p := range positions {
+		v := Vertex{
+			position: p,
+			color:    math.Color{1, 1, 1},
+		}
+		if normals != nil {
+			v.normal = normals[i]
+		}
+		if uvs != nil {
+			v.uv = uvs[i]
+		}
+		if colors != nil {
+			v.color = colors[i]
+		}
+		geo.vertices[i] = v
+	}
+
+	for i := 0; i < len(indices); i += 3 {
+		a, b, c := indices[i], indices[i+1], indices[i+2]
+		if a < 0 || a >= n || b < 0 || b >= n || c < 0 || c >= n {
+			return nil, fmt.Errorf("face %v references missing vertex", i/3)
+		}
+		geo.AddIndexedFace(a, b, c)
+	}
+
+	geo.ComputeBoundary()
+	return geo, nil
+}
+
+// copy of the vertices
+func (g *Geometry) Vertices() []Vertex {
+	return append([]Vertex(nil), g.vertices...)
+}
+
+func (g *Geometry) Vertex(i int) Vertex {
+	return g.vertices[i]
 }
 
+// copy of the faces
+func (g *Geometry) Faces() []Face {
+	return append([]Face(nil), g.faces...)
+}
+
+// copy of the wireframe lines
+func (g *Geometry) Lines() []Line {
+	return append([]Line(nil), g.lines...)
+}
+
+// decimal places of vertex attributes compared when merging
+type MergePrecision struct {
+	Position, Normal, UV, Color int
+}
+
+var DefaultMergePrecision = MergePrecision{4, 4, 4, 4}
+
+// weld vertices with equal attributes at DefaultMergePrecision and remove degenerated faces
 func (g *Geometry) MergeVertices() {
+	g.MergeVerticesPrecision(DefaultMergePrecision)
+}
+
+// quantize like math.Round, keeps the sign of zero so the result matches Vertex.Key
+func quantize(v, scale float64) uint64 {
+	var r float64
+	if tmp := v * scale; tmp > 0 {
+		r = m.Floor(tmp + 0.5)
+	} else {
+		r = m.Ceil(tmp - 0.5)
+	}
+	return m.Float64bits(r)
+}
+
+func (g *Geometry) MergeVerticesPrecision(p MergePrecision) {
+	ps := m.Pow(10, float64(p.Position))
+	ns := m.Pow(10, float64(p.Normal))
+	us := m.Pow(10, float64(p.UV))
+	cs := m.Pow(10, float64(p.Color))
+
 	// search and mark duplicate vertices
-	lookup := map[string]int{}
-	unique := []Vertex{}
-	changed := map[int]int{}
+	lookup := make(map[[11]uint64]int, len(g.vertices))
+	unique := make([]Vertex, 0, len(g.vertices))
+	changed := make([]int, len(g.vertices))
 
 	for i, v := range g.vertices {
-		key := v.Key(4)
-
-		if j, found := lookup[key]; !found {
-			// new vertex
-			lookup[key] = i
-			unique = append(unique, v)
-			changed[i] = len(unique) - 1
-		} else {
+		key := [11]uint64{
+			quantize(v.position[0], ps),
+			quantize(v.position[1], ps),
+			quantize(v.position[2], ps),
+
+			quantize(v.normal[0], ns),
+			quantize(v.normal[1], ns),
+			quantize(v.normal[2], ns),
+
+			quantize(v.uv[0], us),
+			quantize(v.uv[1], us),
+
+			quantize(v.color.R, cs),
+			quantize(v.color.G, cs),
+			quantize(v.color.B, cs),
+		}
+
+		if j, found := lookup[key]; found {
 			// duplicate vertex
-			changed[i] = changed[j]
+			changed[i] = j
+			continue
 		}
+
+		// new vertex
+		lookup[key] = len(unique)
+		changed[i] = len(unique)
+		unique = append(unique, v)
 	}
 
 	// change faces
-	cleaned := []Face{}
-	lines := []Line{}
+	cleaned := make([]Face, 0, len(g.faces))
+	lines := make([]Line, 0, len(g.faces)*3)
 
 	for _, f := range g.faces {
 		a, b, c := changed[f.A], changed[f.B], changed[f.C]
@@ -628,19 +842,219 @@ func (g *Geometry) MergeVertices() {
 	g.vertices = unique
 	g.faces = cleaned
 	g.lines = lines
+	g.needsUpdate = true
+}
+
+// reallocates the buffers with the new usage hint
+func (g *Geometry) SetUsage(u GeometryUsage) {
+	g.hint = gl.GLenum(u)
+	g.needsUpdate = true
+}
+
+func (g *Geometry) Usage() GeometryUsage {
+	return GeometryUsage(g.hint)
+}
+
+// clip the range of vertices starting at offset
+func (g *Geometry) vertexSpan(offset, n int) (int, int) {
+	from, to := offset, offset+n
+	if from < 0 {
+		from = 0
+	}
+	if to > len(g.vertices) {
+		to = len(g.vertices)
+	}
+	if to < from {
+		to = from
+	}
+	return from, to
+}
+
+// replace positions starting with vertex offset, the boundary is recomputed lazily
+// and meshes using the geometry are refitted in the scene on the next matrix update
+func (g *Geometry) SetPositions(offset int, positions ...math.Vector) {
+	from, to := g.vertexSpan(offset, len(positions))
+	for i := from; i < to; i++ {
+		g.vertices[i].position = positions[i-offset]
+	}
+	g.positionRange.add(from, to)
+
+	if to > from {
+		g.boundsDirty = true
+		g.boundsVersion++
+	}
+}
+
+func (g *Geometry) SetNormals(offset int, normals ...math.Vector) {
+	from, to := g.vertexSpan(offset, len(normals))
+	for i := from; i < to; i++ {
+		g.vertices[i].normal = normals[i-offset]
+	}
+	g.normalRange.add(from, to)
+}
+
+func (g *Geometry) SetUVs(offset int, uvs ...math.Vector) {
+	from, to := g.vertexSpan(offset, len(uvs))
+	for i := from; i < to; i++ {
+		g.vertices[i].uv = uvs[i-offset]
+	}
+	g.uvRange.add(from, to)
+}
+
+func (g *Geometry) SetColors(offset int, colors ...math.Color) {
+	from, to := g.vertexSpan(offset, len(colors))
+	for i := from; i < to; i++ {
+		g.vertices[i].color = colors[i-offset]
+	}
+	g.colorRange.add(from, to)
 }
 
 func (g *Geometry) ComputeBoundary() {
+	g.computeBoundary()
+	g.boundsVersion++
+}
+
+func (g *Geometry) computeBoundary() {
 	g.bounding = math.NewBoundary()
 	for _, v := range g.vertices {
 		g.bounding.AddPoint(v.position)
 	}
+	g.boundsDirty = false
 }
 
 func (g *Geometry) Boundary() math.Boundary {
+	if g.boundsDirty {
+		g.computeBoundary()
+	}
 	return g.bounding
 }
 
+// transform a vertex, normals with the normal matrix
+func transformVertex(v Vertex, mat, normal math.Matrix) Vertex {
+	p := v.position
+	p[3] = 1
+	p = mat.Transform(p)
+	v.position = math.Vector{p[0], p[1], p[2], v.position[3]}
+
+	n := v.normal
+	n[3] = 0
+	n = normal.Transform(n)
+	n[3] = 0 // translation of the inverse transpose
+	v.normal = n.Normalize()
+
+	t := v.tangent
+	t[3] = 0
+	t = mat.Transform(t).Normalize()
+	t[3] = v.tangent[3]
+	v.tangent = t
+
+	return v
+}
+
+// bake a transformation into the vertices, mirroring flips the face winding
+func (g *Geometry) ApplyMatrix(mat math.Matrix) {
+	normal := mat.Normal()
+	for i, v := range g.vertices {
+		g.vertices[i] = transformVertex(v, mat, normal)
+	}
+
+	if mat.Determinant() < 0 {
+		for i, f := range g.faces {
+			g.faces[i] = Face{f.A, f.C, f.B}
+		}
+	}
+
+	g.ComputeBoundary()
+	g.needsUpdate = true
+}
+
+func (g *Geometry) Translate(v math.Vector) {
+	g.ApplyMatrix(math.Identity().Translate(v))
+}
+
+// rotate by angle radians around axis
+func (g *Geometry) Rotate(angle float64, axis math.Vector) {
+	g.ApplyMatrix(math.Identity().Rotate(angle, axis))
+}
+
+func (g *Geometry) Scale(v math.Vector) {
+	g.ApplyMatrix(math.Identity().Scale(v))
+}
+
+// move the center of the boundary to the origin, returns the applied offset
+func (g *Geometry) Center() math.Vector {
+	g.ComputeBoundary()
+
+	offset := g.bounding.Center().Negate()
+	offset[3] = 0
+	g.Translate(offset)
+
+	return offset
+}
+
+// center and scale to a bounding sphere with radius 1
+func (g *Geometry) NormalizeSize() {
+	g.Center()
+
+	if _, r := g.bounding.Sphere(); r > 0 {
+		g.Scale(math.Vector{1 / r, 1 / r, 1 / r})
+	}
+}
+
+// copy of the vertex data with its own gl buffers
+func (g *Geometry) Clone() *Geometry {
+	return &Geometry{
+		vertices: append([]Vertex(nil), g.vertices...),
+		faces:    append([]Face(nil), g.faces...),
+		lines:    append([]Line(nil), g.lines...),
+
+		hasTangents: g.hasTangents,
+		needsUpdate: true,
+		hint:        g.hint,
+
+		bounding:    g.bounding,
+		boundsDirty: g.boundsDirty,
+	}
+}
+
+// append the transformed vertices and faces of other, e.g. to batch static meshes into one draw call,
+// tangents are kept only if both geometries have them. Fails without changes if the result
+// exceeds the vertex limit of the 16 bit indices
+func (g *Geometry) Merge(other *Geometry, mat math.Matrix) error {
+	if n := len(g.vertices) + len(other.vertices); n > m.MaxUint16+1 {
+		return fmt.Errorf("too many vertices: %v", n)
+	}
+
+	if len(g.vertices) == 0 {
+		g.hasTangents = other.hasTangents
+	} else {
+		g.hasTangents = g.hasTangents && other.hasTangents
+	}
+
+	offset := len(g.vertices)
+	normal := mat.Normal()
+	mirror := mat.Determinant() < 0
+
+	for _, v := range other.vertices {
+		g.vertices = append(g.vertices, transformVertex(v, mat, normal))
+	}
+
+	for _, f := range other.faces {
+		if mirror {
+			f.B, f.C = f.C, f.B
+		}
+		g.faces = append(g.faces, Face{f.A + offset, f.B + offset, f.C + offset})
+	}
+
+	for _, l := range other.lines {
+		g.lines = append(g.lines, Line{l.A + offset, l.B + offset})
+	}
+
+	g.ComputeBoundary()
+	g.needsUpdate = true
+	return nil
+}
+
 // init vertex buffers
 func (g *Geometry) init() {
 	g.vertexArrayObject = gl.GenVertexArray()
@@ -650,6 +1064,7 @@ func (g *Geometry) init() {
 	g.normalBuffer = gl.GenBuffer()
 	g.uvBuffer = gl.GenBuffer()
 	g.colorBuffer = gl.GenBuffer()
+	g.tangentBuffer = gl.GenBuffer()
 
 	g.initialized = true
 }
@@ -662,15 +1077,25 @@ func (g *Geometry) update() {
 
 	g.vertexArrayObject.Bind()
 
+	// truncated indices would draw garbage, draw nothing instead
+	faces, lines := g.faces, g.lines
+	if err := g.Validate(); err != nil {
+		log.Printf("geometry not drawn: %v\n", err)
+		faces, lines = nil, nil
+	}
+
 	// init mesh buffers
-	g.faceArray = make([]uint16, len(g.faces)*3)
-	g.lineArray = make([]uint16, len(g.lines)*2)
+	g.faceArray = make([]uint16, len(faces)*3)
+	g.lineArray = make([]uint16, len(lines)*2)
 
 	nvertices := len(g.vertices)
 	g.positionArray = make([]float32, nvertices*3)
 	g.normalArray = make([]float32, nvertices*3)
 	g.uvArray = make([]float32, nvertices*2)
 	g.colorArray = make([]float32, nvertices*3)
+	if g.hasTangents {
+		g.tangentArray = make([]float32, nvertices*4)
+	}
 
 	// copy values to buffers
 	for i, v := range g.vertices {
@@ -689,18 +1114,30 @@ func (g *Geometry) update() {
 		g.uvArray[i*2+1] = float32(v.uv[1])
 
 		// color
-		g.colorArray[i*3] = float32(v.color.R)
-		g.colorArray[i*3+1] = float32(v.color.G)
-		g.colorArray[i*3+2] = float32(v.color.B)
+		c := v.color
+		if g.linearColors {
+			c = c.Linear()
+		}
+		g.colorArray[i*3] = float32(c.R)
+		g.colorArray[i*3+1] = float32(c.G)
+		g.colorArray[i*3+2] = float32(c.B)
+
+		// tangent
+		if g.hasTangents {
+			g.tangentArray[i*4] = float32(v.tangent[0])
+			g.tangentArray[i*4+1] = float32(v.tangent[1])
+			g.tangentArray[i*4+2] = float32(v.tangent[2])
+			g.tangentArray[i*4+3] = float32(v.tangent[3])
+		}
 	}
 
-	for i, f := range g.faces {
+	for i, f := range faces {
 		g.faceArray[i*3] = uint16(f.A)
 		g.faceArray[i*3+1] = uint16(f.B)
 		g.faceArray[i*3+2] = uint16(f.C)
 	}
 
-	for i, l := range g.lines {
+	for i, l := range lines {
 		g.lineArray[i*2] = uint16(l.A)
 		g.lineArray[i*2+1] = uint16(l.B)
 	}
@@ -715,38 +1152,126 @@ func (g *Geometry) update() {
 	// normal
 	g.normalBuffer.Bind(gl.ARRAY_BUFFER)
 	size = len(g.normalArray) * int(glh.Sizeof(gl.FLOAT))
-	gl.BufferData(gl.ARRAY_BUFFER, size, g.normalArray, gl.STATIC_DRAW)
+	gl.BufferData(gl.ARRAY_BUFFER, size, g.normalArray, g.hint)
 
 	// uv
 	g.uvBuffer.Bind(gl.ARRAY_BUFFER)
 	size = len(g.uvArray) * int(glh.Sizeof(gl.FLOAT))
-	gl.BufferData(gl.ARRAY_BUFFER, size, g.uvArray, gl.STATIC_DRAW)
+	gl.BufferData(gl.ARRAY_BUFFER, size, g.uvArray, g.hint)
 
 	// color
 	g.colorBuffer.Bind(gl.ARRAY_BUFFER)
 	size = len(g.colorArray) * int(glh.Sizeof(gl.FLOAT))
-	gl.BufferData(gl.ARRAY_BUFFER, size, g.colorArray, gl.STATIC_DRAW)
+	gl.BufferData(gl.ARRAY_BUFFER, size, g.colorArray, g.hint)
+
+	// tangent
+	if g.hasTangents {
+		g.tangentBuffer.Bind(gl.ARRAY_BUFFER)
+		size = len(g.tangentArray) * int(glh.Sizeof(gl.FLOAT))
+		gl.BufferData(gl.ARRAY_BUFFER, size, g.tangentArray, g.hint)
+	}
 
 	// face
 	g.faceBuffer.Bind(gl.ELEMENT_ARRAY_BUFFER)
 	size = len(g.faceArray) * int(glh.Sizeof(gl.UNSIGNED_SHORT)) // gl.UNSIGNED_SHORT 2, gl.UNSIGNED_INT 4
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, size, g.faceArray, gl.STATIC_DRAW)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, size, g.faceArray, g.hint)
 
 	// line
 	g.lineBuffer.Bind(gl.ELEMENT_ARRAY_BUFFER)
 	size = len(g.lineArray) * int(glh.Sizeof(gl.UNSIGNED_SHORT))
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, size, g.lineArray, gl.STATIC_DRAW)
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, size, g.lineArray, g.hint)
 
+	g.positionRange = vertexRange{}
+	g.normalRange = vertexRange{}
+	g.uvRange = vertexRange{}
+	g.colorRange = vertexRange{}
 	g.needsUpdate = false
 }
 
+// vertex colors are given gamma encoded and converted to linear for a renderer with
+// srgb output, the colors are uploaded again if the conversion changes
+func (g *Geometry) setLinearColors(b bool) {
+	if g.linearColors != b {
+		g.linearColors = b
+		g.colorRange.add(0, len(g.vertices))
+	}
+}
+
+// upload complete buffers or the changed ranges
+func (g *Geometry) sync() {
+	if g.needsUpdate {
+		g.update()
+		return
+	}
+
+	if !g.positionRange.empty() {
+		r := g.positionRange
+		for i := r.min; i < r.max; i++ {
+			p := g.vertices[i].position
+			g.positionArray[i*3] = float32(p[0])
+			g.positionArray[i*3+1] = float32(p[1])
+			g.positionArray[i*3+2] = float32(p[2])
+		}
+		updateBufferRange(g.positionBuffer, g.positionArray, r, 3)
+		g.positionRange = vertexRange{}
+	}
+
+	if !g.normalRange.empty() {
+		r := g.normalRange
+		for i := r.min; i < r.max; i++ {
+			n := g.vertices[i].normal
+			g.normalArray[i*3] = float32(n[0])
+			g.normalArray[i*3+1] = float32(n[1])
+			g.normalArray[i*3+2] = float32(n[2])
+		}
+		updateBufferRange(g.normalBuffer, g.normalArray, r, 3)
+		g.normalRange = vertexRange{}
+	}
+
+	if !g.uvRange.empty() {
+		r := g.uvRange
+		for i := r.min; i < r.max; i++ {
+			uv := g.vertices[i].uv
+			g.uvArray[i*2] = float32(uv[0])
+			g.uvArray[i*2+1] = float32(uv[1])
+		}
+		updateBufferRange(g.uvBuffer, g.uvArray, r, 2)
+		g.uvRange = vertexRange{}
+	}
+
+	if !g.colorRange.empty() {
+		r := g.colorRange
+		for i := r.min; i < r.max; i++ {
+			c := g.vertices[i].color
+			if g.linearColors {
+				c = c.Linear()
+			}
+			g.colorArray[i*3] = float32(c.R)
+			g.colorArray[i*3+1] = float32(c.G)
+			g.colorArray[i*3+2] = float32(c.B)
+		}
+		updateBufferRange(g.colorBuffer, g.colorArray, r, 3)
+		g.colorRange = vertexRange{}
+	}
+}
+
+// upload the vertices r of an array with size components per vertex
+func updateBufferRange(buffer gl.Buffer, array []float32, r vertexRange, size int) {
+	stride := size * int(glh.Sizeof(gl.FLOAT))
+
+	buffer.Bind(gl.ARRAY_BUFFER)
+	gl.BufferSubData(gl.ARRAY_BUFFER, r.min*stride, (r.max-r.min)*stride, array[r.min*size:r.max*size])
+}
+
 func (g *Geometry) Dispose() {
 	if g.positionBuffer != 0 {
 		g.positionBuffer.Delete()
-	}
-
-	if g.uvBuffer != 0 {
+		g.normalBuffer.Delete()
 		g.uvBuffer.Delete()
+		g.colorBuffer.Delete()
+		g.tangentBuffer.Delete()
+		g.faceBuffer.Delete()
+		g.lineBuffer.Delete()
 	}
 
 	if g.vertexArrayObject != 0 {
@@ -755,73 +1280,65 @@ func (g *Geometry) Dispose() {
 }
 
 func (g *Geometry) BindVertexArray() {
-	if g.needsUpdate {
-		g.update()
-	}
+	g.sync()
 
 	g.vertexArrayObject.Bind()
 }
 
 func (g *Geometry) BindPositionBuffer() {
-	if g.needsUpdate {
-		g.update()
-	}
+	g.sync()
 
 	g.positionBuffer.Bind(gl.ARRAY_BUFFER)
 }
 
 func (g *Geometry) BindNormalBuffer() {
-	if g.needsUpdate {
-		g.update()
-	}
+	g.sync()
 
 	g.normalBuffer.Bind(gl.ARRAY_BUFFER)
 }
 
 func (g *Geometry) BindUvBuffer() {
-	if g.needsUpdate {
-		g.update()
-	}
+	g.sync()
 
 	g.uvBuffer.Bind(gl.ARRAY_BUFFER)
 }
 
 func (g *Geometry) BindColorBuffer() {
-	if g.needsUpdate {
-		g.update()
-	}
+	g.sync()
 
 	g.colorBuffer.Bind(gl.ARRAY_BUFFER)
 }
 
+func (g *Geometry) HasTangents() bool {
+	return g.hasTangents
+}
+
+func (g *Geometry) BindTangentBuffer() {
+	g.sync()
+
+	g.tangentBuffer.Bind(gl.ARRAY_BUFFER)
+}
+
 func (g *Geometry) BindLineBuffer() {
-	if g.needsUpdate {
-		g.update()
-	}
+	g.sync()
 
 	g.lineBuffer.Bind(gl.ELEMENT_ARRAY_BUFFER)
 }
 
 func (g *Geometry) LineCount() int {
-	if g.needsUpdate {
-		g.update()
-	}
+	g.sync()
 
 	return len(g.lineArray)
 }
 
 func (g *Geometry) BindFaceBuffer() {
-	if g.needsUpdate {
-		g.update()
-	}
+	g.sync()
 
 	g.faceBuffer.Bind(gl.ELEMENT_ARRAY_BUFFER)
 }
 
 func (g *Geometry) FaceCount() int {
-	if g.needsUpdate {
-		g.update()
-	}
+	g.sync()
 
 	return len(g.faceArray)
 }
@@ -0,0 +1,379 @@
+package engine
+
+import (
+	m "math"
+	"math/rand"
+	"testing"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+func TestGeometry_SetPositions(t *testing.T) {
+	geo := NewPlaneGeometry(2, 2)
+	geo.SetUsage(StreamDrawUsage)
+	geo.sync()
+
+	tests := []struct {
+		offset    int
+		positions []math.Vector
+		changed   vertexRange
+	}{
+		{1, []math.Vector{{1, 1, 1}}, vertexRange{1, 2}},
+		{2, []math.Vector{{2, 2, 2}, {3, 3, 3}, {4, 4, 4}, {5, 5, 5}}, vertexRange{2, 4}}, // clipped
+		{-1, []math.Vector{{6, 6, 6}, {7, 7, 7}}, vertexRange{0, 1}},
+		{10, []math.Vector{{8, 8, 8}}, vertexRange{}},
+	}
+
+	for _, c := range tests {
+		geo.SetPositions(c.offset, c.positions...)
+		if geo.positionRange != c.changed && !(c.changed.empty() && geo.positionRange.empty()) {
+			t.Errorf("SetPositions(%v, %v) range != %v (got %v)", c.offset, len(c.positions), c.changed, geo.positionRange)
+		}
+
+		geo.sync()
+		if !geo.positionRange.empty() || geo.needsUpdate {
+			t.Errorf("sync() after SetPositions(%v, %v) left pending updates", c.offset, len(c.positions))
+		}
+
+		for i := c.changed.min; i < c.changed.max; i++ {
+			p := c.positions[i-c.offset]
+			if a := geo.positionArray[i*3 : i*3+3]; a[0] != float32(p[0]) || a[1] != float32(p[1]) || a[2] != float32(p[2]) {
+				t.Errorf("SetPositions(%v, %v) vertex %v != %v (got %v)", c.offset, len(c.positions), i, p, a)
+			}
+		}
+	}
+
+	if !geo.Boundary().ContainsPoint(math.Vector{3, 3, 3, 1}) {
+		t.Errorf("SetPositions() did not expand the boundary")
+	}
+
+	// boundary shrinks with the positions
+	geo.SetPositions(0, math.Vector{0, 0, 0}, math.Vector{0, 0, 0}, math.Vector{0, 0, 0}, math.Vector{0, 0, 0})
+	if geo.Boundary().ContainsPoint(math.Vector{1, 1, 1, 1}) {
+		t.Errorf("SetPositions() did not shrink the boundary")
+	}
+
+	// ranges are merged
+	geo.SetColors(3, math.Color{1, 0, 0})
+	geo.SetColors(0, math.Color{0, 1, 0})
+	if geo.colorRange != (vertexRange{0, 4}) {
+		t.Errorf("SetColors() range != {0 4} (got %v)", geo.colorRange)
+	}
+}
+
+func TestNewIndexedGeometry(t *testing.T) {
+	quad := []math.Vector{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}
+
+	tests := []struct {
+		name    string
+		normals []math.Vector
+		colors  []math.Color
+		indices []int
+		faces   int
+		err     bool
+	}{
+		{"quad", nil, nil, []int{0, 1, 2, 2, 3, 0}, 2, false},
+		{"colored", nil, []math.Color{{1, 0, 0}, {1, 0, 0}, {1, 0, 0}, {1, 0, 0}}, []int{0, 1, 2}, 1, false},
+		{"normal count", []math.Vector{{0, 0, 1}}, nil, []int{0, 1, 2}, 0, true},
+		{"index count", nil, nil, []int{0, 1}, 0, true},
+		{"index range", nil, nil, []int{0, 1, 4}, 0, true},
+	}
+
+	for _, c := range tests {
+		geo, err := NewIndexedGeometry(quad, c.normals, nil, c.colors, c.indices)
+		if (err != nil) != c.err {
+			t.Errorf("NewIndexedGeometry(%v) error %v (got %v)", c.name, c.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if f := geo.Faces(); len(f) != c.faces || len(geo.Lines()) != 3*c.faces {
+			t.Errorf("NewIndexedGeometry(%v) faces != %v (got %v)", c.name, c.faces, len(f))
+		}
+		if v := geo.Vertex(3); !v.Position().Equals(quad[3], 6) {
+			t.Errorf("NewIndexedGeometry(%v) vertex 3 != %v (got %v)", c.name, quad[3], v.Position())
+		}
+		if col := geo.Vertex(0).Color(); c.colors == nil && col != (math.Color{1, 1, 1}) {
+			t.Errorf("NewIndexedGeometry(%v) default color != white (got %v)", c.name, col)
+		}
+	}
+}
+
+// string key welding, reference for MergeVertices
+func keyMergeVertices(g *Geometry) {
+	lookup := map[string]int{}
+	unique := []Vertex{}
+	changed := map[int]int{}
+
+	for i, v := range g.vertices {
+		key := v.Key(4)
+
+		if j, found := lookup[key]; !found {
+			lookup[key] = i
+			unique = append(unique, v)
+			changed[i] = len(unique) - 1
+		} else {
+			changed[i] = changed[j]
+		}
+	}
+
+	cleaned := []Face{}
+	for _, f := range g.faces {
+		a, b, c := changed[f.A], changed[f.B], changed[f.C]
+		if a == b || b == c || c == a {
+			continue
+		}
+		cleaned = append(cleaned, Face{a, b, c})
+	}
+
+	g.vertices = unique
+	g.faces = cleaned
+}
+
+// grid of unwelded quads with n triangles, coordinates slightly jittered
+func testGrid(n int, jitter float64) *Geometry {
+	rnd := rand.New(rand.NewSource(3))
+	geo := NewGeometry()
+	size := int(m.Sqrt(float64(n / 2)))
+
+	vertex := func(x, y int) Vertex {
+		j := func() float64 { return (rnd.Float64() - 0.5) * jitter }
+		return Vertex{
+			position: math.Vector{float64(x)*0.01 + j(), float64(y)*0.01 + j(), 0},
+			normal:   math.Vector{0, 0, 1 + j()},
+			uv:       math.Vector{float64(x) / float64(size), float64(y) / float64(size)},
+			color:    math.Color{1, 1, 1},
+		}
+	}
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			geo.AddFace(vertex(x, y), vertex(x+1, y), vertex(x+1, y+1))
+			geo.AddFace(vertex(x+1, y+1), vertex(x, y+1), vertex(x, y))
+		}
+	}
+
+	return geo
+}
+
+func TestGeometry_MergeVertices(t *testing.T) {
+	tests := []struct {
+		name string
+		geo  *Geometry
+	}{
+		{"grid", testGrid(2000, 0)},
+		{"jittered grid", testGrid(2000, 0.0002)},
+		{"cube", NewCubeGeometry(1)},
+		{"sphere", NewSphereGeometry(1, 16, 12)},
+	}
+
+	// signed zero and rounding borders
+	zeros := NewGeometry()
+	for _, x := range []float64{0, m.Copysign(0, -1), 0.00004, -0.00004, 0.00005, -0.00005, 0.00006} {
+		zeros.AddFace(
+			Vertex{position: math.Vector{x, 0, 0}},
+			Vertex{position: math.Vector{1, x, 0}},
+			Vertex{position: math.Vector{1, 1, x}},
+		)
+	}
+	tests = append(tests, struct {
+		name string
+		geo  *Geometry
+	}{"zeros", zeros})
+
+	for _, c := range tests {
+		expected := &Geometry{
+			vertices: append([]Vertex(nil), c.geo.vertices...),
+			faces:    append([]Face(nil), c.geo.faces...),
+		}
+		keyMergeVertices(expected)
+		c.geo.MergeVertices()
+
+		if len(c.geo.vertices) != len(expected.vertices) || len(c.geo.faces) != len(expected.faces) {
+			t.Errorf("MergeVertices(%v) != %v vertices, %v faces (got %v, %v)", c.name,
+				len(expected.vertices), len(expected.faces), len(c.geo.vertices), len(c.geo.faces))
+			continue
+		}
+		for i := range expected.faces {
+			if c.geo.faces[i] != expected.faces[i] {
+				t.Errorf("MergeVertices(%v) face %v != %v (got %v)", c.name, i, expected.faces[i], c.geo.faces[i])
+				break
+			}
+		}
+	}
+}
+
+func benchmarkMergeVertices(b *testing.B, merge func(g *Geometry)) {
+	b.StopTimer()
+	geo := testGrid(1000000, 0)
+	vertices, faces := geo.vertices, geo.faces
+
+	for i := 0; i < b.N; i++ {
+		geo.vertices = append([]Vertex(nil), vertices...)
+		geo.faces = append([]Face(nil), faces...)
+
+		b.StartTimer()
+		merge(geo)
+		b.StopTimer()
+	}
+}
+
+func BenchmarkGeometry_MergeVertices_1M(b *testing.B) {
+	benchmarkMergeVertices(b, func(g *Geometry) { g.MergeVertices() })
+}
+
+func BenchmarkGeometry_MergeVertices_Key1M(b *testing.B) {
+	benchmarkMergeVertices(b, keyMergeVertices)
+}
+
+func TestGeometry_ApplyMatrix(t *testing.T) {
+	tri := func() *Geometry {
+		geo, _ := NewIndexedGeometry(
+			[]math.Vector{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}},
+			[]math.Vector{{0, 0, 1}, {0, 0, 1}, {0, 0, 1}},
+			nil, nil, []int{0, 1, 2})
+		return geo
+	}
+
+	tests := []struct {
+		name     string
+		apply    func(g *Geometry)
+		position math.Vector // of vertex 1
+		normal   math.Vector
+		face     Face
+	}{
+		{"translate", func(g *Geometry) { g.Translate(math.Vector{1, 2, 3}) }, math.Vector{2, 2, 3}, math.Vector{0, 0, 1}, Face{0, 1, 2}},
+		{"rotate", func(g *Geometry) { g.Rotate(m.Pi/2, math.Vector{0, 1, 0}) }, math.Vector{0, 0, -1}, math.Vector{1, 0, 0}, Face{0, 1, 2}},
+		{"scale", func(g *Geometry) { g.Scale(math.Vector{2, 1, 4}) }, math.Vector{2, 0, 0}, math.Vector{0, 0, 1}, Face{0, 1, 2}},
+		{"mirror", func(g *Geometry) { g.Scale(math.Vector{1, 1, -1}) }, math.Vector{1, 0, 0}, math.Vector{0, 0, -1}, Face{0, 2, 1}},
+	}
+
+	for _, c := range tests {
+		geo := tri()
+		c.apply(geo)
+
+		v := geo.Vertex(1)
+		if !v.Position().Equals(c.position, 6) {
+			t.Errorf("%v position != %v (got %v)", c.name, c.position, v.Position())
+		}
+		if !v.Normal().Equals(c.normal, 6) {
+			t.Errorf("%v normal != %v (got %v)", c.name, c.normal, v.Normal())
+		}
+		if f := geo.Faces()[0]; f != c.face {
+			t.Errorf("%v face != %v (got %v)", c.name, c.face, f)
+		}
+		if !geo.Boundary().ContainsPoint(math.Vector{c.position[0], c.position[1], c.position[2], 1}) {
+			t.Errorf("%v boundary does not contain %v (got %v)", c.name, c.position, geo.Boundary())
+		}
+	}
+}
+
+func TestGeometry_Merge(t *testing.T) {
+	cube := NewCubeGeometry(1)
+	batch := NewGeometry()
+
+	for i := 0; i < 3; i++ {
+		if err := batch.Merge(cube, math.Identity().Translate(math.Vector{float64(i) * 2, 0, 0})); err != nil {
+			t.Fatalf("Merge() failed: %v", err)
+		}
+	}
+
+	if n := batch.VerticesCount(); n != 3*cube.VerticesCount() {
+		t.Errorf("Merge() vertices != %v (got %v)", 3*cube.VerticesCount(), n)
+	}
+	if n := len(batch.faces); n != 3*len(cube.faces) {
+		t.Errorf("Merge() faces != %v (got %v)", 3*len(cube.faces), n)
+	}
+	if f, e := batch.faces[len(cube.faces)], cube.faces[0]; f.A != e.A+cube.VerticesCount() {
+		t.Errorf("Merge() face index %v != %v", f.A, e.A+cube.VerticesCount())
+	}
+
+	clone := batch.Clone()
+	clone.Translate(math.Vector{0, 10, 0})
+	if batch.vertices[0].position.Equals(clone.vertices[0].position, 6) {
+		t.Errorf("Clone() shares vertex data")
+	}
+
+	offset := batch.Center()
+	if !offset.Equals(math.Vector{-2, 0, 0}, 6) || !batch.Boundary().Center().Equals(math.Vector{0, 0, 0, 1}, 6) {
+		t.Errorf("Center() offset != -2,0,0 (got %v, boundary %v)", offset, batch.Boundary())
+	}
+
+	batch.NormalizeSize()
+	if _, r := batch.Boundary().Sphere(); m.Abs(r-1) > 1e-9 {
+		t.Errorf("NormalizeSize() radius != 1 (got %v)", r)
+	}
+}
+
+func TestGeometry_MergeLimit(t *testing.T) {
+	big := NewGeometry()
+	big.vertices = make([]Vertex, 65000)
+	cube := NewCubeGeometry(1)
+
+	if err := big.Merge(cube, math.Identity()); err != nil {
+		t.Errorf("Merge() below the limit failed: %v", err)
+	}
+	for i := 0; i < 40; i++ {
+		big.Merge(cube, math.Identity())
+	}
+	if n := big.VerticesCount(); n > 65536 {
+		t.Errorf("Merge() exceeded the vertex limit with %v vertices", n)
+	}
+	if err := big.Merge(cube, math.Identity()); err == nil {
+		t.Errorf("Merge() beyond the limit did not fail")
+	}
+}
+
+func TestGeometry_Validate(t *testing.T) {
+	tooMany := NewGeometry()
+	for i := 0; i < 65537; i++ {
+		tooMany.AddVertex(Vertex{})
+	}
+
+	missing := NewCubeGeometry(1)
+	missing.AddIndexedFace(0, 1, missing.VerticesCount())
+
+	tests := []struct {
+		name  string
+		geo   *Geometry
+		valid bool
+	}{
+		{"cube", NewCubeGeometry(1), true},
+		{"too many vertices", tooMany, false},
+		{"missing vertex", missing, false},
+	}
+
+	for _, c := range tests {
+		if err := c.geo.Validate(); (err == nil) != c.valid {
+			t.Errorf("Validate(%v) error %v", c.name, err)
+		}
+	}
+}
+
+func TestGeometry_LinearColors(t *testing.T) {
+	geo := NewPlaneGeometry(1, 1)
+	color := math.Color{0.5, 0.5, 0.5}
+	geo.SetColors(0, color, color, color, color)
+	geo.sync()
+
+	tests := []struct {
+		linear   bool
+		expected math.Color
+	}{
+		{false, color},
+		{true, color.Linear()},
+		{true, color.Linear()},
+		{false, color},
+	}
+
+	for _, c := range tests {
+		geo.setLinearColors(c.linear)
+		geo.sync()
+
+		if r := geo.colorArray[0]; r != float32(c.expected.R) {
+			t.Errorf("setLinearColors(%v) color != %v (got %v)", c.linear, c.expected.R, r)
+		}
+	}
+}
@@ -0,0 +1,1222 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	m "math"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// far plane of cameras with an infinite projection
+const gltfDefaultFar = 1000
+
+// element limit of accessors without buffer view, their count is not bounded by any data
+const gltfMaxElements = 1 << 22
+
+// https://github.com/KhronosGroup/glTF/tree/master/specification/2.0
+type gltfDocument struct {
+	Asset struct {
+		Version    string
+		MinVersion string
+	}
+	ExtensionsRequired []string
+
+	Scene  *int
+	Scenes []struct {
+		Name  string
+		Nodes []int
+	}
+	Nodes       []gltfNode
+	Meshes      []gltfMesh
+	Accessors   []gltfAccessor
+	BufferViews []gltfBufferView
+	Buffers     []gltfBuffer
+	Materials   []gltfMaterial
+	Textures    []gltfTexture
+	Images      []gltfImage
+	Samplers    []gltfSampler
+	Cameras     []gltfCamera
+	Skins       []gltfSkin
+	Animations  []gltfAnimation
+
+	Extensions struct {
+		Lights *struct {
+			Lights []gltfLight
+		} `json:"KHR_lights_punctual"`
+	}
+}
+
+type gltfNode struct {
+	Name        string
+	Children    []int
+	Matrix      []float64
+	Translation []float64
+	Rotation    []float64
+	Scale       []float64
+
+	Mesh   *int
+	Camera *int
+	Skin   *int
+
+	Extensions struct {
+		Light *struct {
+			Light int
+		} `json:"KHR_lights_punctual"`
+	}
+}
+
+type gltfMesh struct {
+	Name       string
+	Primitives []struct {
+		Attributes map[string]int
+		Indices    *int
+		Material   *int
+		Mode       *int
+	}
+}
+
+type gltfAccessor struct {
+	BufferView    *int
+	ByteOffset    int
+	ComponentType int
+	Normalized    bool
+	Count         int
+	Type          string
+
+	Sparse *struct {
+		Count   int
+		Indices struct {
+			BufferView    int
+			ByteOffset    int
+			ComponentType int
+		}
+		Values struct {
+			BufferView int
+			ByteOffset int
+		}
+	}
+}
+
+type gltfBufferView struct {
+	Buffer     int
+	ByteOffset int
+	ByteLength int
+	ByteStride int
+}
+
+type gltfBuffer struct {
+	URI        string
+	ByteLength int
+}
+
+type gltfTextureInfo struct {
+	Index    int
+	TexCoord int
+}
+
+type gltfMaterial struct {
+	Name                 string
+	PBRMetallicRoughness *struct {
+		BaseColorFactor  []float64
+		BaseColorTexture *gltfTextureInfo
+	}
+	EmissiveFactor []float64
+	AlphaMode      string
+}
+
+type gltfTexture struct {
+	Sampler *int
+	Source  *int
+}
+
+type gltfImage struct {
+	URI        string
+	MimeType   string
+	BufferView *int
+}
+
+type gltfSampler struct {
+	MagFilter, MinFilter int
+	WrapS, WrapT         int
+}
+
+type gltfCamera struct {
+	Type        string
+	Perspective *struct {
+		AspectRatio float64
+		YFov        float64
+		ZNear, ZFar float64
+	}
+	Orthographic *struct {
+		XMag, YMag  float64
+		ZNear, ZFar float64
+	}
+}
+
+type gltfSkin struct {
+	Name                string
+	InverseBindMatrices *int
+	Skeleton            *int
+	Joints              []int
+}
+
+type gltfAnimation struct {
+	Name     string
+	Channels []struct {
+		Sampler int
+		Target  struct {
+			Node *int
+			Path string
+		}
+	}
+	Samplers []struct {
+		Input, Output int
+		Interpolation string
+	}
+}
+
+type gltfLight struct {
+	Name      string
+	Type      string
+	Color     []float64
+	Intensity *float64
+	Range     float64
+	Spot      *struct {
+		InnerConeAngle float64
+		OuterConeAngle *float64
+	}
+}
+
+// punctual light of a node, shining along its -z axis
+type GLTFLight struct {
+	Name      string
+	Type      string // directional, point or spot
+	Color     math.Color
+	Intensity float64 // candela for point and spot, lux for directional lights
+	Range     float64 // infinite if zero
+
+	InnerConeAngle, OuterConeAngle float64 // spot lights, in radians
+
+	Node *Group
+}
+
+type GLTFSkin struct {
+	Name                string
+	Joints              []*Group
+	InverseBindMatrices []math.Matrix // by joint
+	Skeleton            *Group        // common root of the joints, may be nil
+}
+
+// vertex influences of a skinned mesh by vertex of its geometry
+type GLTFSkinnedMesh struct {
+	Mesh    *Mesh
+	Skin    *GLTFSkin
+	Joints  [][4]int      // indices into the joints of the skin
+	Weights []math.Vector // of the joints
+}
+
+// keyframes of one property of a node
+type GLTFChannel struct {
+	Node          *Group
+	Path          string // translation, rotation or scale
+	Interpolation string // STEP, LINEAR or CUBICSPLINE
+
+	Times  []float64     // in seconds
+	Values []math.Vector // in-tangent, value and out-tangent per key for cubic splines
+}
+
+type GLTFAnimation struct {
+	Name     string
+	Channels []GLTFChannel
+	Duration float64 // in seconds
+}
+
+// model of a gltf 2.0 file, morph targets are not supported
+type GLTF struct {
+	Scene  *Group   // default scene
+	Scenes []*Group // root nodes are children of one scene only
+	Nodes  []*Group // by node index
+
+	Cameras       []Camera // children of their nodes, in node order
+	Lights        []GLTFLight
+	Skins         []*GLTFSkin
+	SkinnedMeshes []GLTFSkinnedMesh
+	Animations    []*GLTFAnimation
+
+	names map[string]*Group
+}
+
+// node by name, nil if not found
+func (g *GLTF) Node(name string) *Group {
+	return g.names[name]
+}
+
+// loads .gltf files with embedded or external buffers and binary .glb files
+func LoadGLTF(path string) (*GLTF, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseGLTF(data, filepath.Dir(path))
+}
+
+type gltfLoader struct {
+	doc gltfDocument
+	dir string
+	bin []byte // glb chunk
+
+	buffers   [][]byte
+	textures  map[int]*ImageTexture
+	materials map[int]*Material
+	fallback  *Material // of primitives without material
+}
+
+func parseGLTF(data []byte, dir string) (*GLTF, error) {
+	l := &gltfLoader{
+		dir:       dir,
+		textures:  make(map[int]*ImageTexture),
+		materials: make(map[int]*Material),
+	}
+
+	doc := data
+	if bytes.HasPrefix(data, []byte("glTF")) {
+		var err error
+		if doc, l.bin, err = parseGLB(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal(doc, &l.doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(l.doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("unsupported gltf version %q", l.doc.Asset.Version)
+	}
+	for _, e := range l.doc.ExtensionsRequired {
+		if e != "KHR_lights_punctual" {
+			return nil, fmt.Errorf("unsupported gltf extension %v", e)
+		}
+	}
+
+	if err := l.loadBuffers(); err != nil {
+		return nil, err
+	}
+
+	return l.load()
+}
+
+// json and binary chunk of a glb file
+func parseGLB(data []byte) (doc, bin []byte, err error) {
+	const (
+		chunkJSON = 0x4e4f534a
+		chunkBIN  = 0x004e4942
+	)
+
+	if len(data) < 12 {
+		return nil, nil, errors.New("glb header is truncated")
+	}
+	if v := binary.LittleEndian.Uint32(data[4:]); v != 2 {
+		return nil, nil, fmt.Errorf("unsupported glb version %v", v)
+	}
+	if n := binary.LittleEndian.Uint32(data[8:]); int(n) <= len(data) {
+		data = data[:n]
+	}
+
+	for offset := 12; offset+8 <= len(data); {
+		length := int(binary.LittleEndian.Uint32(data[offset:]))
+		kind := binary.LittleEndian.Uint32(data[offset+4:])
+		offset += 8
+		if length < 0 || offset+length > len(data) {
+			return nil, nil, errors.New("glb chunk is truncated")
+		}
+
+		switch kind {
+		case chunkJSON:
+			doc = data[offset : offset+length]
+		case chunkBIN:
+			if bin == nil {
+				bin = data[offset : offset+length]
+			}
+		}
+		offset += length
+	}
+
+	if doc == nil {
+		return nil, nil, errors.New("glb without json chunk")
+	}
+	return doc, bin, nil
+}
+
+// contents of data uris or files relative to the gltf file
+func (l *gltfLoader) resolve(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		i := strings.Index(uri, ",")
+		if i < 0 || !strings.HasSuffix(uri[:i], ";base64") {
+			return nil, errors.New("data uri is not base64 encoded")
+		}
+		return base64.StdEncoding.DecodeString(uri[i+1:])
+	}
+
+	path, err := url.PathUnescape(uri)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(filepath.Join(l.dir, filepath.FromSlash(path)))
+}
+
+func (l *gltfLoader) loadBuffers() error {
+	for i, b := range l.doc.Buffers {
+		var data []byte
+		if b.URI == "" {
+			if i != 0 || l.bin == nil {
+				return fmt.Errorf("buffer %v has no data", i)
+			}
+			data = l.bin
+		} else {
+			var err error
+			if data, err = l.resolve(b.URI); err != nil {
+				return err
+			}
+		}
+
+		if len(data) < b.ByteLength {
+			return fmt.Errorf("buffer %v is shorter than %v bytes", i, b.ByteLength)
+		}
+		l.buffers = append(l.buffers, data)
+	}
+
+	return nil
+}
+
+func (l *gltfLoader) bufferView(i int) ([]byte, gltfBufferView, error) {
+	if i < 0 || i >= len(l.doc.BufferViews) {
+		return nil, gltfBufferView{}, fmt.Errorf("missing buffer view %v", i)
+	}
+	v := l.doc.BufferViews[i]
+
+	if v.Buffer < 0 || v.Buffer >= len(l.buffers) {
+		return nil, v, fmt.Errorf("buffer view %v references missing buffer %v", i, v.Buffer)
+	}
+	b := l.buffers[v.Buffer]
+	if v.ByteOffset < 0 || v.ByteLength < 0 || v.ByteOffset+v.ByteLength > len(b) {
+		return nil, v, fmt.Errorf("buffer view %v exceeds its buffer", i)
+	}
+
+	return b[v.ByteOffset : v.ByteOffset+v.ByteLength], v, nil
+}
+
+func gltfComponents(t string) int {
+	switch t {
+	case "SCALAR":
+		return 1
+	case "VEC2":
+		return 2
+	case "VEC3":
+		return 3
+	case "VEC4", "MAT2":
+		return 4
+	case "MAT3":
+		return 9
+	case "MAT4":
+		return 16
+	}
+	return 0
+}
+
+func gltfComponentSize(t int) int {
+	switch t {
+	case 5120, 5121: // byte, unsigned byte
+		return 1
+	case 5122, 5123: // short, unsigned short
+		return 2
+	case 5125, 5126: // unsigned int, float
+		return 4
+	}
+	return 0
+}
+
+// component at the start of b, normalized integers are mapped to [0, 1] or [-1, 1]
+func gltfComponent(b []byte, t int, normalized bool) float64 {
+	var v, max float64
+
+	switch t {
+	case 5120:
+		v, max = float64(int8(b[0])), 127
+	case 5121:
+		v, max = float64(b[0]), 255
+	case 5122:
+		v, max = float64(int16(binary.LittleEndian.Uint16(b))), 32767
+	case 5123:
+		v, max = float64(binary.LittleEndian.Uint16(b)), 65535
+	case 5125:
+		return float64(binary.LittleEndian.Uint32(b))
+	default:
+		return float64(m.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+
+	if normalized {
+		return m.Max(v/max, -1)
+	}
+	return v
+}
+
+// elements of an accessor as consecutive components
+func (l *gltfLoader) accessor(i int) (values []float64, components int, err error) {
+	if i < 0 || i >= len(l.doc.Accessors) {
+		return nil, 0, fmt.Errorf("missing accessor %v", i)
+	}
+	a := l.doc.Accessors[i]
+
+	components = gltfComponents(a.Type)
+	size := gltfComponentSize(a.ComponentType)
+	if components == 0 || size == 0 || a.Count < 0 {
+		return nil, 0, fmt.Errorf("accessor %v has unsupported type %v of %v", i, a.Type, a.ComponentType)
+	}
+
+	// count elements of n components with stride from data, checked before allocating
+	read := func(data []byte, offset, stride, count, n, t int, normalized bool) ([]float64, error) {
+		size := gltfComponentSize(t)
+		if size == 0 {
+			return nil, fmt.Errorf("accessor %v has unsupported component type %v", i, t)
+		}
+		if stride == 0 {
+			stride = n * size
+		}
+		if count > len(data) || (count > 0 && (offset < 0 || stride < 0 || offset+(count-1)*stride+n*size > len(data))) {
+			return nil, fmt.Errorf("accessor %v exceeds its buffer view", i)
+		}
+
+		out := make([]float64, count*n)
+		for e := 0; e < count; e++ {
+			for c := 0; c < n; c++ {
+				out[e*n+c] = gltfComponent(data[offset+e*stride+c*size:], t, normalized)
+			}
+		}
+		return out, nil
+	}
+
+	if a.BufferView != nil {
+		data, view, err := l.bufferView(*a.BufferView)
+		if err != nil {
+			return nil, 0, err
+		}
+		if values, err = read(data, a.ByteOffset, view.ByteStride, a.Count, components, a.ComponentType, a.Normalized); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		// zeros, nothing bounds the count but this limit
+		if a.Count > gltfMaxElements {
+			return nil, 0, fmt.Errorf("accessor %v without buffer view has %v elements, more than %v", i, a.Count, gltfMaxElements)
+		}
+		values = make([]float64, a.Count*components)
+	}
+
+	// replaced elements
+	if s := a.Sparse; s != nil {
+		if s.Count < 0 || s.Count > a.Count {
+			return nil, 0, fmt.Errorf("sparse accessor %v replaces %v of %v elements", i, s.Count, a.Count)
+		}
+
+		data, _, err := l.bufferView(s.Indices.BufferView)
+		if err != nil {
+			return nil, 0, err
+		}
+		indices, err := read(data, s.Indices.ByteOffset, 0, s.Count, 1, s.Indices.ComponentType, false)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if data, _, err = l.bufferView(s.Values.BufferView); err != nil {
+			return nil, 0, err
+		}
+		replaced, err := read(data, s.Values.ByteOffset, 0, s.Count, components, a.ComponentType, a.Normalized)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for j, e := range indices {
+			if int(e) >= a.Count {
+				return nil, 0, fmt.Errorf("sparse accessor %v replaces missing element %v", i, e)
+			}
+			copy(values[int(e)*components:], replaced[j*components:(j+1)*components])
+		}
+	}
+
+	return values, components, nil
+}
+
+// accessor elements as vectors, missing components are zero
+func (l *gltfLoader) vectors(i int) ([]math.Vector, error) {
+	values, n, err := l.accessor(i)
+	if err != nil {
+		return nil, err
+	}
+	if n > 4 {
+		return nil, fmt.Errorf("accessor %v is not a vector", i)
+	}
+
+	vs := make([]math.Vector, len(values)/n)
+	for e := range vs {
+		copy(vs[e][:], values[e*n:(e+1)*n])
+	}
+	return vs, nil
+}
+
+func (l *gltfLoader) load() (*GLTF, error) {
+	g := &GLTF{
+		Nodes: make([]*Group, len(l.doc.Nodes)),
+		names: make(map[string]*Group),
+	}
+
+	// nodes and their hierarchy
+	for i, n := range l.doc.Nodes {
+		node := NewGroup()
+
+		switch {
+		case len(n.Matrix) == 16:
+			var mat math.Matrix
+			copy(mat[:], n.Matrix)
+			p, r, s := mat.Decompose()
+			node.SetPosition(p)
+			node.SetRotation(r)
+			node.SetScale(s)
+
+		default:
+			if len(n.Translation) == 3 {
+				node.SetPosition(math.Vector{n.Translation[0], n.Translation[1], n.Translation[2]})
+			}
+			if len(n.Rotation) == 4 {
+				node.SetRotation(math.Quaternion{n.Rotation[0], n.Rotation[1], n.Rotation[2], n.Rotation[3]})
+			}
+			if len(n.Scale) == 3 {
+				node.SetScale(math.Vector{n.Scale[0], n.Scale[1], n.Scale[2]})
+			}
+		}
+
+		g.Nodes[i] = node
+		if n.Name != "" {
+			g.names[n.Name] = node
+		}
+	}
+
+	node := func(i int) (*Group, error) {
+		if i < 0 || i >= len(g.Nodes) {
+			return nil, fmt.Errorf("missing node %v", i)
+		}
+		return g.Nodes[i], nil
+	}
+
+	for i, n := range l.doc.Nodes {
+		for _, c := range n.Children {
+			child, err := node(c)
+			if err != nil {
+				return nil, err
+			}
+			if child.Parent() != nil {
+				return nil, fmt.Errorf("node %v has more than one parent", c)
+			}
+
+			// no cycles
+			var parent Object
+			for parent = g.Nodes[i]; parent != nil && parent != Object(child); parent = parent.Parent() {
+			}
+			if parent != nil {
+				return nil, fmt.Errorf("node %v is its own ancestor", c)
+			}
+
+			g.Nodes[i].AddChild(child)
+		}
+	}
+
+	// skins before meshes that reference them
+	for i, s := range l.doc.Skins {
+		skin := &GLTFSkin{Name: s.Name}
+
+		for _, j := range s.Joints {
+			joint, err := node(j)
+			if err != nil {
+				return nil, err
+			}
+			skin.Joints = append(skin.Joints, joint)
+			skin.InverseBindMatrices = append(skin.InverseBindMatrices, math.Identity())
+		}
+
+		if s.InverseBindMatrices != nil {
+			values, n, err := l.accessor(*s.InverseBindMatrices)
+			if err != nil {
+				return nil, err
+			}
+			if n != 16 || len(values) < 16*len(skin.Joints) {
+				return nil, fmt.Errorf("skin %v has too few inverse bind matrices", i)
+			}
+			for j := range skin.InverseBindMatrices {
+				copy(skin.InverseBindMatrices[j][:], values[j*16:])
+			}
+		}
+
+		if s.Skeleton != nil {
+			var err error
+			if skin.Skeleton, err = node(*s.Skeleton); err != nil {
+				return nil, err
+			}
+		}
+
+		g.Skins = append(g.Skins, skin)
+	}
+
+	// contents of the nodes
+	var lights []gltfLight
+	if l.doc.Extensions.Lights != nil {
+		lights = l.doc.Extensions.Lights.Lights
+	}
+
+	for i, n := range l.doc.Nodes {
+		if n.Mesh != nil {
+			meshes, skinned, err := l.mesh(*n.Mesh)
+			if err != nil {
+				return nil, err
+			}
+
+			for j, mesh := range meshes {
+				g.Nodes[i].AddChild(mesh)
+
+				if n.Skin != nil && skinned[j].Joints != nil {
+					if *n.Skin < 0 || *n.Skin >= len(g.Skins) {
+						return nil, fmt.Errorf("node %v references missing skin %v", i, *n.Skin)
+					}
+					skinned[j].Mesh, skinned[j].Skin = mesh, g.Skins[*n.Skin]
+					g.SkinnedMeshes = append(g.SkinnedMeshes, skinned[j])
+				}
+			}
+		}
+
+		if n.Camera != nil {
+			camera, err := l.camera(*n.Camera)
+			if err != nil {
+				return nil, err
+			}
+			g.Nodes[i].AddChild(camera)
+			g.Cameras = append(g.Cameras, camera)
+		}
+
+		if e := n.Extensions.Light; e != nil {
+			if e.Light < 0 || e.Light >= len(lights) {
+				return nil, fmt.Errorf("node %v references missing light %v", i, e.Light)
+			}
+			info := lights[e.Light]
+
+			light := GLTFLight{
+				Name:           info.Name,
+				Type:           info.Type,
+				Color:          math.Color{1, 1, 1},
+				Intensity:      1,
+				Range:          info.Range,
+				OuterConeAngle: math.Pi / 4,
+				Node:           g.Nodes[i],
+			}
+			if len(info.Color) == 3 {
+				light.Color = math.Color{info.Color[0], info.Color[1], info.Color[2]}
+			}
+			if info.Intensity != nil {
+				light.Intensity = *info.Intensity
+			}
+			if s := info.Spot; s != nil {
+				light.InnerConeAngle = s.InnerConeAngle
+				if s.OuterConeAngle != nil {
+					light.OuterConeAngle = *s.OuterConeAngle
+				}
+			}
+			g.Lights = append(g.Lights, light)
+		}
+	}
+
+	// scenes of root nodes
+	for _, s := range l.doc.Scenes {
+		scene := NewGroup()
+		for _, i := range s.Nodes {
+			root, err := node(i)
+			if err != nil {
+				return nil, err
+			}
+			if root.Parent() != nil {
+				return nil, fmt.Errorf("root node %v has a parent", i)
+			}
+			scene.AddChild(root)
+		}
+		g.Scenes = append(g.Scenes, scene)
+	}
+
+	switch {
+	case len(g.Scenes) == 0:
+		// all root nodes
+		scene := NewGroup()
+		for _, n := range g.Nodes {
+			if n.Parent() == nil {
+				scene.AddChild(n)
+			}
+		}
+		g.Scene = scene
+		g.Scenes = []*Group{scene}
+
+	case l.doc.Scene != nil:
+		if *l.doc.Scene < 0 || *l.doc.Scene >= len(g.Scenes) {
+			return nil, fmt.Errorf("missing scene %v", *l.doc.Scene)
+		}
+		g.Scene = g.Scenes[*l.doc.Scene]
+
+	default:
+		g.Scene = g.Scenes[0]
+	}
+
+	for i := range l.doc.Animations {
+		a, err := l.animation(i, g.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		g.Animations = append(g.Animations, a)
+	}
+
+	return g, nil
+}
+
+// one mesh per triangle primitive, joints and weights of skinned primitives
+func (l *gltfLoader) mesh(i int) ([]*Mesh, []GLTFSkinnedMesh, error) {
+	if i < 0 || i >= len(l.doc.Meshes) {
+		return nil, nil, fmt.Errorf("missing mesh %v", i)
+	}
+
+	var meshes []*Mesh
+	var skinned []GLTFSkinnedMesh
+
+	for _, p := range l.doc.Meshes[i].Primitives {
+		mode := 4
+		if p.Mode != nil {
+			mode = *p.Mode
+		}
+		if mode < 4 {
+			continue // points and lines
+		}
+
+		geo, influences, err := l.geometry(p.Attributes, p.Indices, mode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("mesh %v: %v", i, err)
+		}
+
+		mat, err := l.material(p.Material)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		meshes = append(meshes, NewMesh(geo, mat))
+		skinned = append(skinned, influences)
+	}
+
+	return meshes, skinned, nil
+}
+
+func (l *gltfLoader) geometry(attributes map[string]int, indices *int, mode int) (*Geometry, GLTFSkinnedMesh, error) {
+	var influences GLTFSkinnedMesh
+
+	a, found := attributes["POSITION"]
+	if !found {
+		return nil, influences, errors.New("primitive without positions")
+	}
+	positions, err := l.vectors(a)
+	if err != nil {
+		return nil, influences, err
+	}
+	n := len(positions)
+
+	// optional attributes of the same count
+	optional := func(name string) ([]math.Vector, error) {
+		a, found := attributes[name]
+		if !found {
+			return nil, nil
+		}
+		vs, err := l.vectors(a)
+		if err == nil && len(vs) != n {
+			err = fmt.Errorf("%v count %v does not match vertex count %v", name, len(vs), n)
+		}
+		return vs, err
+	}
+
+	normals, err := optional("NORMAL")
+	if err != nil {
+		return nil, influences, err
+	}
+	uvs, err := optional("TEXCOORD_0")
+	if err != nil {
+		return nil, influences, err
+	}
+	tangents, err := optional("TANGENT")
+	if err != nil {
+		return nil, influences, err
+	}
+	rgba, err := optional("COLOR_0")
+	if err != nil {
+		return nil, influences, err
+	}
+	var colors []math.Color
+	for _, c := range rgba {
+		colors = append(colors, math.Color{c[0], c[1], c[2]})
+	}
+
+	// indices of the vertices in drawing order
+	var order []int
+	if indices != nil {
+		values, _, err := l.accessor(*indices)
+		if err != nil {
+			return nil, influences, err
+		}
+		order = make([]int, len(values))
+		for j, v := range values {
+			order[j] = int(v)
+		}
+	} else {
+		order = make([]int, n)
+		for j := range order {
+			order[j] = j
+		}
+	}
+
+	var faces []int
+	switch mode {
+	case 5: // triangle strip
+		for j := 0; j+2 < len(order); j++ {
+			if j%2 == 0 {
+				faces = append(faces, order[j], order[j+1], order[j+2])
+			} else {
+				faces = append(faces, order[j+1], order[j], order[j+2])
+			}
+		}
+	case 6: // triangle fan
+		for j := 1; j+1 < len(order); j++ {
+			faces = append(faces, order[0], order[j], order[j+1])
+		}
+	default:
+		faces = order[:len(order)/3*3]
+	}
+
+	geo, err := NewIndexedGeometry(positions, normals, uvs, colors, faces)
+	if err != nil {
+		return nil, influences, err
+	}
+	for j, t := range tangents {
+		geo.vertices[j].tangent = t
+	}
+	if normals == nil {
+		geo.ComputeVertexNormals(0) // flat
+		geo.ComputeBoundary()
+	}
+
+	// skinning
+	joints, err := optional("JOINTS_0")
+	if err != nil {
+		return nil, influences, err
+	}
+	weights, err := optional("WEIGHTS_0")
+	if err != nil {
+		return nil, influences, err
+	}
+	if joints != nil && weights != nil {
+		influences.Joints = make([][4]int, n)
+		for j, v := range joints {
+			influences.Joints[j] = [4]int{int(v[0]), int(v[1]), int(v[2]), int(v[3])}
+		}
+		influences.Weights = weights
+	}
+
+	return geo, influences, nil
+}
+
+func (l *gltfLoader) material(i *int) (*Material, error) {
+	if i == nil {
+		if l.fallback == nil {
+			var err error
+			if l.fallback, err = NewMaterial("phong"); err != nil {
+				return nil, err
+			}
+		}
+		return l.fallback, nil
+	}
+
+	if mat, found := l.materials[*i]; found {
+		return mat, nil
+	}
+	if *i < 0 || *i >= len(l.doc.Materials) {
+		return nil, fmt.Errorf("missing material %v", *i)
+	}
+	info := l.doc.Materials[*i]
+
+	mat, err := NewMaterial("phong")
+	if err != nil {
+		return nil, err
+	}
+
+	if pbr := info.PBRMetallicRoughness; pbr != nil {
+		if f := pbr.BaseColorFactor; len(f) == 4 {
+			mat.SetUniform("diffuse", math.Color{f[0], f[1], f[2]})
+			if info.AlphaMode == "BLEND" {
+				mat.SetUniform("opacity", f[3])
+			}
+		}
+
+		if t := pbr.BaseColorTexture; t != nil && t.TexCoord == 0 {
+			tex, err := l.texture(t.Index)
+			if err != nil {
+				return nil, err
+			}
+			mat.SetUniform("diffuseMap", tex)
+		}
+	}
+
+	if f := info.EmissiveFactor; len(f) == 3 {
+		mat.SetUniform("emissive", math.Color{f[0], f[1], f[2]})
+	}
+
+	l.materials[*i] = mat
+	return mat, nil
+}
+
+func (l *gltfLoader) texture(i int) (*ImageTexture, error) {
+	if tex, found := l.textures[i]; found {
+		return tex, nil
+	}
+	if i < 0 || i >= len(l.doc.Textures) {
+		return nil, fmt.Errorf("missing texture %v", i)
+	}
+	t := l.doc.Textures[i]
+
+	if t.Source == nil || *t.Source < 0 || *t.Source >= len(l.doc.Images) {
+		return nil, fmt.Errorf("texture %v has no image", i)
+	}
+	source := l.doc.Images[*t.Source]
+
+	var data []byte
+	var err error
+	if source.BufferView != nil {
+		data, _, err = l.bufferView(*source.BufferView)
+	} else {
+		data, err = l.resolve(source.URI)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("image %v: %v", *t.Source, err)
+	}
+	tex := NewTextureFromImage(img)
+
+	if t.Sampler != nil {
+		if *t.Sampler < 0 || *t.Sampler >= len(l.doc.Samplers) {
+			return nil, fmt.Errorf("texture %v references missing sampler %v", i, *t.Sampler)
+		}
+		s := l.doc.Samplers[*t.Sampler]
+
+		tex.SetWrap(gltfWrap(s.WrapS), gltfWrap(s.WrapT))
+		mag, min := tex.Filter()
+		if f, ok := gltfFilter(s.MagFilter); ok {
+			mag = f
+		}
+		if f, ok := gltfFilter(s.MinFilter); ok {
+			min = f
+		}
+		tex.SetFilter(mag, min)
+	}
+
+	l.textures[i] = tex
+	return tex, nil
+}
+
+func gltfWrap(w int) TextureWrap {
+	switch w {
+	case 33071:
+		return ClampToEdgeWrapping
+	case 33648:
+		return MirroredRepeatWrapping
+	}
+	return RepeatWrapping
+}
+
+func gltfFilter(f int) (TextureFilter, bool) {
+	switch f {
+	case 9728:
+		return NearestFilter, true
+	case 9729:
+		return LinearFilter, true
+	case 9984:
+		return NearestMipMapNearestFilter, true
+	case 9985:
+		return LinearMipMapNearestFilter, true
+	case 9986:
+		return NearestMipMapLinearFilter, true
+	case 9987:
+		return LinearMipMapLinearFilter, true
+	}
+	return 0, false
+}
+
+func (l *gltfLoader) camera(i int) (Camera, error) {
+	if i < 0 || i >= len(l.doc.Cameras) {
+		return nil, fmt.Errorf("missing camera %v", i)
+	}
+	c := l.doc.Cameras[i]
+
+	switch {
+	case c.Type == "perspective" && c.Perspective != nil:
+		p := c.Perspective
+		aspect, far := p.AspectRatio, p.ZFar
+		if aspect <= 0 {
+			aspect = 1
+		}
+		if far <= 0 {
+			far = gltfDefaultFar
+		}
+		return NewPerspectiveCamera(p.YFov/math.DEG2RAD, aspect, p.ZNear, far), nil
+
+	case c.Type == "orthographic" && c.Orthographic != nil:
+		o := c.Orthographic
+		return NewOrthographicCamera(-o.XMag, o.XMag, o.YMag, -o.YMag, o.ZNear, o.ZFar), nil
+	}
+
+	return nil, fmt.Errorf("camera %v has unknown type %q", i, c.Type)
+}
+
+// animations of node transforms, morph target weights are skipped
+func (l *gltfLoader) animation(i int, nodes []*Group) (*GLTFAnimation, error) {
+	a := l.doc.Animations[i]
+	anim := &GLTFAnimation{Name: a.Name}
+
+	for _, c := range a.Channels {
+		if c.Target.Node == nil || c.Target.Path == "weights" {
+			continue
+		}
+		if *c.Target.Node < 0 || *c.Target.Node >= len(nodes) {
+			return nil, fmt.Errorf("animation %v targets missing node %v", i, *c.Target.Node)
+		}
+		if c.Sampler < 0 || c.Sampler >= len(a.Samplers) {
+			return nil, fmt.Errorf("animation %v references missing sampler %v", i, c.Sampler)
+		}
+		s := a.Samplers[c.Sampler]
+
+		ch := GLTFChannel{
+			Node:          nodes[*c.Target.Node],
+			Path:          c.Target.Path,
+			Interpolation: s.Interpolation,
+		}
+		if ch.Interpolation == "" {
+			ch.Interpolation = "LINEAR"
+		}
+
+		times, _, err := l.accessor(s.Input)
+		if err != nil {
+			return nil, err
+		}
+		if ch.Values, err = l.vectors(s.Output); err != nil {
+			return nil, err
+		}
+
+		keys := len(ch.Values)
+		if ch.Interpolation == "CUBICSPLINE" {
+			keys /= 3
+		}
+		if len(times) == 0 || len(times) != keys {
+			return nil, fmt.Errorf("animation %v has %v times for %v keys", i, len(times), keys)
+		}
+		ch.Times = times
+
+		anim.Channels = append(anim.Channels, ch)
+		if t := times[len(times)-1]; t > anim.Duration {
+			anim.Duration = t
+		}
+	}
+
+	return anim, nil
+}
+
+// value at time t in seconds, clamped to the keyframes
+func (c GLTFChannel) Sample(t float64) math.Vector {
+	n := len(c.Times)
+	cubic := c.Interpolation == "CUBICSPLINE"
+
+	value := func(k int) math.Vector {
+		if cubic {
+			return c.Values[3*k+1]
+		}
+		return c.Values[k]
+	}
+
+	if t <= c.Times[0] {
+		return value(0)
+	}
+	if t >= c.Times[n-1] {
+		return value(n - 1)
+	}
+
+	k := 0
+	for k+1 < n-1 && c.Times[k+1] <= t {
+		k++
+	}
+	dt := c.Times[k+1] - c.Times[k]
+	s := (t - c.Times[k]) / dt
+
+	var v math.Vector
+	switch c.Interpolation {
+	case "STEP":
+		return value(k)
+
+	case "CUBICSPLINE":
+		// hermite spline with tangents scaled by the key distance
+		p0, m0 := c.Values[3*k+1], c.Values[3*k+2].MulScalar(dt)
+		p1, m1 := c.Values[3*(k+1)+1], c.Values[3*(k+1)].MulScalar(dt)
+		s2, s3 := s*s, s*s*s
+		v = p0.MulScalar(2*s3 - 3*s2 + 1).
+			Add(m0.MulScalar(s3 - 2*s2 + s)).
+			Add(p1.MulScalar(-2*s3 + 3*s2)).
+			Add(m1.MulScalar(s3 - s2))
+
+	default:
+		a, b := value(k), value(k+1)
+		if c.Path == "rotation" {
+			q := math.Quaternion(a).Slerp(math.Quaternion(b), s)
+			return math.Vector(q)
+		}
+		v = a.Add(b.Sub(a).MulScalar(s))
+	}
+
+	if c.Path == "rotation" {
+		v = math.Vector(math.Quaternion(v).Normalize())
+	}
+	return v
+}
+
+// set the node transforms to the state at time t in seconds
+func (a *GLTFAnimation) Apply(t float64) {
+	for _, c := range a.Channels {
+		v := c.Sample(t)
+
+		switch c.Path {
+		case "translation":
+			c.Node.SetPosition(math.Vector{v[0], v[1], v[2]})
+		case "rotation":
+			c.Node.SetRotation(math.Quaternion(v))
+		case "scale":
+			c.Node.SetScale(math.Vector{v[0], v[1], v[2]})
+		}
+	}
+}
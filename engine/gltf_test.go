@@ -0,0 +1,319 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// little endian buffer of the values, padded to 4 bytes
+func testBuffer(values ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	for buf.Len()%4 != 0 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+func testDataURI(b []byte) string {
+	return "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(b)
+}
+
+func TestGLTFLoader_Accessor(t *testing.T) {
+	buf := testBuffer(
+		[]float32{1, 2, 3, 0, 4, 5, 6, 0}, // vec3 with a stride of 16 bytes
+		[]uint8{255, 0, 51, 0},            // normalized color
+		[]int16{-32767, 32767, -32768, 0},
+		[]uint16{1, 0},     // sparse index, padded
+		[]float32{7, 8, 9}, // sparse value
+	)
+
+	l := &gltfLoader{buffers: [][]byte{buf}}
+	view := 0
+	l.doc.BufferViews = []gltfBufferView{
+		{Buffer: 0, ByteOffset: 0, ByteLength: 32, ByteStride: 16},
+		{Buffer: 0, ByteOffset: 32, ByteLength: 4},
+		{Buffer: 0, ByteOffset: 36, ByteLength: 8},
+		{Buffer: 0, ByteOffset: 44, ByteLength: 4},
+		{Buffer: 0, ByteOffset: 48, ByteLength: 12},
+	}
+	views := []int{0, 1, 2}
+	l.doc.Accessors = []gltfAccessor{
+		{BufferView: &view, ComponentType: 5126, Count: 2, Type: "VEC3"},
+		{BufferView: &views[1], ComponentType: 5121, Normalized: true, Count: 1, Type: "VEC3"},
+		{BufferView: &views[2], ComponentType: 5122, Normalized: true, Count: 2, Type: "VEC2"},
+		{BufferView: &view, ComponentType: 5126, Count: 3, Type: "VEC3"}, // exceeds the view
+		{BufferView: &views[0], ComponentType: 5126, Count: 2, Type: "VEC3"},
+		{ComponentType: 5126, Count: 3, Type: "VEC3"},
+	}
+
+	// sparse on top of the data and without data
+	for _, i := range []int{4, 5} {
+		l.doc.Accessors[i].Sparse = &struct {
+			Count   int
+			Indices struct {
+				BufferView    int
+				ByteOffset    int
+				ComponentType int
+			}
+			Values struct {
+				BufferView int
+				ByteOffset int
+			}
+		}{Count: 1}
+		l.doc.Accessors[i].Sparse.Indices.BufferView = 3
+		l.doc.Accessors[i].Sparse.Indices.ComponentType = 5123
+		l.doc.Accessors[i].Sparse.Values.BufferView = 4
+	}
+
+	// sparse counts out of range
+	for _, n := range []int{-1, 3} {
+		a := l.doc.Accessors[4]
+		sparse := *a.Sparse
+		sparse.Count = n
+		a.Sparse = &sparse
+		l.doc.Accessors = append(l.doc.Accessors, a)
+	}
+
+	// counts not backed by data
+	l.doc.Accessors = append(l.doc.Accessors,
+		gltfAccessor{BufferView: &view, ComponentType: 5126, Count: 1e12, Type: "VEC3"},
+		gltfAccessor{ComponentType: 5126, Count: 1 << 30, Type: "VEC3"},
+	)
+
+	tests := []struct {
+		accessor int
+		expected []float64
+		valid    bool
+	}{
+		{0, []float64{1, 2, 3, 4, 5, 6}, true},
+		{1, []float64{1, 0, 0.2}, true},
+		{2, []float64{-1, 1, -1, 0}, true},
+		{3, nil, false},
+		{4, []float64{1, 2, 3, 7, 8, 9}, true},
+		{5, []float64{0, 0, 0, 7, 8, 9, 0, 0, 0}, true},
+		{6, nil, false},
+		{7, nil, false},
+		{8, nil, false},
+		{9, nil, false},
+		{10, nil, false}, // missing
+	}
+
+	for _, c := range tests {
+		values, _, err := l.accessor(c.accessor)
+		if (err == nil) != c.valid {
+			t.Errorf("accessor(%v) error %v", c.accessor, err)
+			continue
+		}
+		if fmt.Sprint(values) != fmt.Sprint(c.expected) && c.valid {
+			t.Errorf("accessor(%v) != %v (got %v)", c.accessor, c.expected, values)
+		}
+	}
+}
+
+func TestGLTFLoader_Geometry(t *testing.T) {
+	// unit quad as list, strip and fan
+	buf := testBuffer(
+		[]float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0},
+		[]uint16{0, 1, 2, 0, 2, 3},
+		[]uint16{1, 2, 0, 3},
+	)
+
+	l := &gltfLoader{buffers: [][]byte{buf}}
+	positions, list, strip := 0, 1, 2
+	l.doc.BufferViews = []gltfBufferView{
+		{ByteOffset: 0, ByteLength: 48},
+		{ByteOffset: 48, ByteLength: 12},
+		{ByteOffset: 60, ByteLength: 8},
+	}
+	l.doc.Accessors = []gltfAccessor{
+		{BufferView: &positions, ComponentType: 5126, Count: 4, Type: "VEC3"},
+		{BufferView: &list, ComponentType: 5123, Count: 6, Type: "SCALAR"},
+		{BufferView: &strip, ComponentType: 5123, Count: 4, Type: "SCALAR"},
+	}
+
+	tests := []struct {
+		indices *int
+		mode    int
+		faces   int
+	}{
+		{&list, 4, 2},
+		{&strip, 5, 2},
+		{nil, 6, 2},
+		{nil, 4, 1}, // incomplete triangle dropped
+	}
+
+	for _, c := range tests {
+		geo, _, err := l.geometry(map[string]int{"POSITION": 0}, c.indices, c.mode)
+		if err != nil {
+			t.Errorf("geometry(mode %v) error %v", c.mode, err)
+			continue
+		}
+
+		if len(geo.faces) != c.faces {
+			t.Errorf("geometry(mode %v) faces != %v (got %v)", c.mode, c.faces, len(geo.faces))
+		}
+		for _, f := range geo.faces {
+			a, b, cc := geo.vertices[f.A], geo.vertices[f.B], geo.vertices[f.C]
+			if n, _ := faceNormal(a.position, b.position, cc.position); n[2] <= 0 {
+				t.Errorf("geometry(mode %v) face %v is not counter clockwise", c.mode, f)
+			}
+			if a.normal.DistanceTo(math.Vector{0, 0, 1}) > 1e-9 {
+				t.Errorf("geometry(mode %v) normal != %v (got %v)", c.mode, math.Vector{0, 0, 1}, a.normal)
+			}
+		}
+	}
+
+	if _, _, err := l.geometry(map[string]int{"POSITION": 0, "NORMAL": 1}, nil, 4); err == nil {
+		t.Errorf("geometry() accepted normals of a different count")
+	}
+}
+
+func TestParseGLB(t *testing.T) {
+	doc := []byte(`{"asset":{"version":"2.0"}}  `)
+	bin := testBuffer([]float32{1, 2})
+
+	chunk := func(kind uint32, data []byte) []byte {
+		return append(testBuffer(uint32(len(data)), kind), data...)
+	}
+	body := append(chunk(0x4e4f534a, doc), chunk(0x004e4942, bin)...)
+	glb := append(testBuffer([]byte("glTF"), uint32(2), uint32(12+len(body))), body...)
+
+	d, b, err := parseGLB(glb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(d, doc) || !bytes.Equal(b, bin) {
+		t.Errorf("parseGLB() != %q, %v (got %q, %v)", doc, bin, d, b)
+	}
+
+	if _, _, err := parseGLB(glb[:len(glb)-4]); err == nil {
+		t.Errorf("parseGLB() accepted a truncated file")
+	}
+}
+
+func TestParseGLTF(t *testing.T) {
+	buf := testBuffer(
+		[]float32{0, 1},             // times
+		[]float32{0, 0, 0, 2, 4, 6}, // translations
+		[]float32{2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 1}, // inverse bind matrices
+		[]float32{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1},
+	)
+
+	doc := fmt.Sprintf(`{
+		"asset": {"version": "2.0"},
+		"scene": 0,
+		"scenes": [{"nodes": [0]}],
+		"nodes": [
+			{"name": "root", "children": [1, 2], "translation": [1, 2, 3]},
+			{"name": "arm", "matrix": [0,0,-2,0, 0,2,0,0, 2,0,0,0, 10,0,0,1], "camera": 0},
+			{"name": "lamp", "extensions": {"KHR_lights_punctual": {"light": 0}}}
+		],
+		"cameras": [{"type": "perspective", "perspective": {"yfov": 0.5, "znear": 0.1}}],
+		"extensions": {"KHR_lights_punctual": {"lights": [{"type": "spot", "color": [1, 0, 0], "spot": {"innerConeAngle": 0.2}}]}},
+		"skins": [{"joints": [0, 1], "inverseBindMatrices": 2}],
+		"animations": [{
+			"channels": [{"sampler": 0, "target": {"node": 1, "path": "translation"}}],
+			"samplers": [{"input": 0, "output": 1}]
+		}],
+		"buffers": [{"uri": %q, "byteLength": %v}],
+		"bufferViews": [
+			{"buffer": 0, "byteOffset": 0, "byteLength": 8},
+			{"buffer": 0, "byteOffset": 8, "byteLength": 24},
+			{"buffer": 0, "byteOffset": 32, "byteLength": 128}
+		],
+		"accessors": [
+			{"bufferView": 0, "componentType": 5126, "count": 2, "type": "SCALAR"},
+			{"bufferView": 1, "componentType": 5126, "count": 2, "type": "VEC3"},
+			{"bufferView": 2, "componentType": 5126, "count": 2, "type": "MAT4"}
+		]
+	}`, testDataURI(buf), len(buf))
+
+	g, err := parseGLTF([]byte(doc), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, arm, lamp := g.Node("root"), g.Node("arm"), g.Node("lamp")
+	if root == nil || arm == nil || lamp == nil || g.Node("missing") != nil {
+		t.Fatalf("Node() missing named nodes")
+	}
+	if root.Parent() != g.Scene || arm.Parent() != root || lamp.Parent() != root {
+		t.Errorf("parseGLTF() hierarchy is wrong")
+	}
+	if p := root.Position(); p.DistanceTo(math.Vector{1, 2, 3}) > 1e-9 {
+		t.Errorf("parseGLTF() translation != %v (got %v)", math.Vector{1, 2, 3}, p)
+	}
+	if s := arm.Scale(); s.DistanceTo(math.Vector{2, 2, 2}) > 1e-6 {
+		t.Errorf("parseGLTF() matrix scale != %v (got %v)", math.Vector{2, 2, 2}, s)
+	}
+
+	if len(g.Cameras) != 1 || g.Cameras[0].Parent() != arm {
+		t.Errorf("parseGLTF() camera is not attached to its node")
+	}
+	if len(g.Lights) != 1 || g.Lights[0].Node != lamp || g.Lights[0].Color != (math.Color{1, 0, 0}) ||
+		g.Lights[0].Intensity != 1 || g.Lights[0].OuterConeAngle != math.Pi/4 {
+		t.Errorf("parseGLTF() light %+v", g.Lights)
+	}
+
+	if len(g.Skins) != 1 || len(g.Skins[0].Joints) != 2 || g.Skins[0].Joints[1] != arm {
+		t.Fatalf("parseGLTF() skin %+v", g.Skins)
+	}
+	if ibm := g.Skins[0].InverseBindMatrices[0]; ibm[0] != 2 || ibm[5] != 2 || ibm[15] != 1 {
+		t.Errorf("parseGLTF() inverse bind matrix\n%v", ibm)
+	}
+
+	if len(g.Animations) != 1 || g.Animations[0].Duration != 1 {
+		t.Fatalf("parseGLTF() animations %+v", g.Animations)
+	}
+	g.Animations[0].Apply(0.25)
+	if p := arm.Position(); p.DistanceTo(math.Vector{0.5, 1, 1.5}) > 1e-6 {
+		t.Errorf("Apply(0.25) position != %v (got %v)", math.Vector{0.5, 1, 1.5}, p)
+	}
+
+	// invalid documents
+	for _, doc := range []string{
+		`{"asset": {"version": "1.0"}}`,
+		`{"asset": {"version": "2.0"}, "extensionsRequired": ["KHR_draco_mesh_compression"]}`,
+		`{"asset": {"version": "2.0"}, "nodes": [{"children": [1]}, {"children": [0]}]}`,
+		`{"asset": {"version": "2.0"}, "nodes": [{"children": [1]}]}`,
+		`{"asset": {"version": "2.0"}, "buffers": [{"byteLength": 4}]}`,
+	} {
+		if _, err := parseGLTF([]byte(doc), "."); err == nil {
+			t.Errorf("parseGLTF(%v) accepted an invalid document", doc)
+		}
+	}
+}
+
+func TestGLTFChannel_Sample(t *testing.T) {
+	half := math.Vector(math.QuaternionFromAxisAngle(math.Vector{0, 1, 0}, math.Pi/2))
+
+	tests := []struct {
+		channel  GLTFChannel
+		time     float64
+		expected math.Vector
+	}{
+		{GLTFChannel{Path: "translation", Interpolation: "LINEAR", Times: []float64{1, 3}, Values: []math.Vector{{0}, {4}}}, 0, math.Vector{0}},
+		{GLTFChannel{Path: "translation", Interpolation: "LINEAR", Times: []float64{1, 3}, Values: []math.Vector{{0}, {4}}}, 2.5, math.Vector{3}},
+		{GLTFChannel{Path: "translation", Interpolation: "LINEAR", Times: []float64{1, 3}, Values: []math.Vector{{0}, {4}}}, 5, math.Vector{4}},
+		{GLTFChannel{Path: "scale", Interpolation: "STEP", Times: []float64{0, 1, 2}, Values: []math.Vector{{1}, {2}, {3}}}, 1.9, math.Vector{2}},
+		{GLTFChannel{Path: "rotation", Interpolation: "LINEAR", Times: []float64{0, 1}, Values: []math.Vector{{0, 0, 0, 1}, {0, 1, 0, 0}}}, 0.5, half},
+		// linear spline without tangents and a smooth one
+		{GLTFChannel{Path: "translation", Interpolation: "CUBICSPLINE", Times: []float64{0, 2}, Values: []math.Vector{{}, {0}, {}, {}, {2}, {}}}, 1, math.Vector{1}},
+		{GLTFChannel{Path: "translation", Interpolation: "CUBICSPLINE", Times: []float64{0, 2}, Values: []math.Vector{{}, {0}, {1}, {1}, {2}, {}}}, 1, math.Vector{1}},
+	}
+
+	for _, c := range tests {
+		if r := c.channel.Sample(c.time); r.DistanceTo(c.expected) > 1e-9 && r.DistanceTo(c.expected.Negate()) > 1e-9 {
+			t.Errorf("Sample(%v, %v) != %v (got %v)", c.channel.Interpolation, c.time, c.expected, r)
+		}
+	}
+
+}
@@ -0,0 +1,485 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	m "math"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// json layout of written files, the loader structs have no tags and no optional fields
+type gltfOutDocument struct {
+	Asset struct {
+		Version   string `json:"version"`
+		Generator string `json:"generator,omitempty"`
+	} `json:"asset"`
+
+	Scene       int                 `json:"scene"`
+	Scenes      []gltfOutScene      `json:"scenes"`
+	Nodes       []gltfOutNode       `json:"nodes,omitempty"`
+	Meshes      []gltfOutMesh       `json:"meshes,omitempty"`
+	Accessors   []gltfOutAccessor   `json:"accessors,omitempty"`
+	BufferViews []gltfOutBufferView `json:"bufferViews,omitempty"`
+	Buffers     []gltfOutBuffer     `json:"buffers,omitempty"`
+	Materials   []gltfOutMaterial   `json:"materials,omitempty"`
+	Textures    []gltfOutTexture    `json:"textures,omitempty"`
+	Images      []gltfOutImage      `json:"images,omitempty"`
+	Samplers    []gltfOutSampler    `json:"samplers,omitempty"`
+}
+
+type gltfOutScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type gltfOutNode struct {
+	Children    []int     `json:"children,omitempty"`
+	Translation []float64 `json:"translation,omitempty"`
+	Rotation    []float64 `json:"rotation,omitempty"`
+	Scale       []float64 `json:"scale,omitempty"`
+	Mesh        *int      `json:"mesh,omitempty"`
+}
+
+type gltfOutPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    int            `json:"indices"`
+	Material   *int           `json:"material,omitempty"`
+}
+
+type gltfOutMesh struct {
+	Primitives []gltfOutPrimitive `json:"primitives"`
+}
+
+type gltfOutAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min,omitempty"`
+	Max           []float64 `json:"max,omitempty"`
+}
+
+type gltfOutBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target,omitempty"`
+}
+
+type gltfOutBuffer struct {
+	URI        string `json:"uri,omitempty"`
+	ByteLength int    `json:"byteLength"`
+}
+
+type gltfOutMaterial struct {
+	PBRMetallicRoughness struct {
+		BaseColorFactor  []float64 `json:"baseColorFactor"`
+		BaseColorTexture *struct {
+			Index int `json:"index"`
+		} `json:"baseColorTexture,omitempty"`
+		MetallicFactor  float64 `json:"metallicFactor"`
+		RoughnessFactor float64 `json:"roughnessFactor"`
+	} `json:"pbrMetallicRoughness"`
+	EmissiveFactor []float64 `json:"emissiveFactor,omitempty"`
+	AlphaMode      string    `json:"alphaMode,omitempty"`
+	DoubleSided    bool      `json:"doubleSided,omitempty"`
+}
+
+type gltfOutTexture struct {
+	Sampler int `json:"sampler"`
+	Source  int `json:"source"`
+}
+
+type gltfOutImage struct {
+	URI        string `json:"uri,omitempty"`
+	MimeType   string `json:"mimeType,omitempty"`
+	BufferView *int   `json:"bufferView,omitempty"`
+}
+
+type gltfOutSampler struct {
+	MagFilter int `json:"magFilter,omitempty"`
+	MinFilter int `json:"minFilter,omitempty"`
+	WrapS     int `json:"wrapS"`
+	WrapT     int `json:"wrapT"`
+}
+
+type gltfMeshKey struct {
+	geometry *Geometry
+	material *Material
+}
+
+type gltfExporter struct {
+	doc  gltfOutDocument
+	bin  bytes.Buffer
+	bake bool
+
+	// uri of a texture file, empty to embed the image
+	texture func(*ImageTexture) (string, error)
+
+	meshes    map[gltfMeshKey]int
+	materials map[*Material]int
+	textures  map[*ImageTexture]int
+}
+
+func newGLTFExporter(bake bool, texture func(*ImageTexture) (string, error)) *gltfExporter {
+	e := &gltfExporter{
+		bake:      bake,
+		texture:   texture,
+		meshes:    make(map[gltfMeshKey]int),
+		materials: make(map[*Material]int),
+		textures:  make(map[*ImageTexture]int),
+	}
+	e.doc.Asset.Version = "2.0"
+	e.doc.Asset.Generator = "gisp"
+	return e
+}
+
+// write an object tree to a gltf file with a .bin buffer next to it, or to a single glb file
+// depending on the extension. Transformations stay on the nodes or are baked into the vertices,
+// textures loaded from files are referenced, generated ones embedded
+func ExportGLTF(path string, o Object, bake bool) error {
+	dir := filepath.Dir(path)
+	e := newGLTFExporter(bake, func(t *ImageTexture) (string, error) {
+		if t.Path() == "" {
+			return "", nil
+		}
+		u := url.URL{Path: filepath.ToSlash(exportPath(dir, t.Path()))}
+		return u.String(), nil
+	})
+
+	if err := e.add(o); err != nil {
+		return err
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".glb" {
+		data, err := e.encodeGLB()
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, data, 0644)
+	}
+
+	binPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".bin"
+	if e.bin.Len() > 0 {
+		u := url.URL{Path: filepath.Base(binPath)}
+		e.doc.Buffers = []gltfOutBuffer{{URI: u.String(), ByteLength: e.bin.Len()}}
+		if err := ioutil.WriteFile(binPath, e.bin.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(e.doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// add the object tree as the default scene
+func (e *gltfExporter) add(o Object) error {
+	var roots []int
+
+	if e.bake {
+		// one node per renderable without transformation
+		for _, em := range exportMeshes(o, math.Identity()) {
+			vertices, faces := bakeGeometry(em.mesh.Geometry(), em.matrix)
+			mesh, err := e.mesh(vertices, faces, em.mesh.Material())
+			if err != nil {
+				return err
+			}
+			roots = append(roots, e.addNode(gltfOutNode{Mesh: &mesh}))
+		}
+	} else {
+		root, err := e.node(o)
+		if err != nil {
+			return err
+		}
+		roots = append(roots, root)
+	}
+
+	e.doc.Scenes = append(e.doc.Scenes, gltfOutScene{Nodes: roots})
+	e.doc.Scene = len(e.doc.Scenes) - 1
+
+	if e.bin.Len() > 0 {
+		e.doc.Buffers = []gltfOutBuffer{{ByteLength: e.bin.Len()}}
+	}
+	return nil
+}
+
+func (e *gltfExporter) addNode(n gltfOutNode) int {
+	e.doc.Nodes = append(e.doc.Nodes, n)
+	return len(e.doc.Nodes) - 1
+}
+
+// node of an object with its local transformation, meshes are shared between instances
+func (e *gltfExporter) node(o Object) (int, error) {
+	var n gltfOutNode
+
+	if p := o.Position(); p != (math.Vector{}) {
+		n.Translation = []float64{p[0], p[1], p[2]}
+	}
+	if r := o.Rotation(); r != (math.Quaternion{}) && r != (math.Quaternion{0, 0, 0, 1}) {
+		n.Rotation = []float64{r[0], r[1], r[2], r[3]}
+	}
+	if s := o.Scale(); s != (math.Vector{1, 1, 1}) {
+		n.Scale = []float64{s[0], s[1], s[2]}
+	}
+
+	if r, ok := o.(Renderable); ok && r.Geometry() != nil {
+		key := gltfMeshKey{r.Geometry(), r.Material()}
+		mesh, found := e.meshes[key]
+		if !found {
+			var err error
+			if mesh, err = e.mesh(r.Geometry().vertices, r.Geometry().faces, r.Material()); err != nil {
+				return 0, err
+			}
+			e.meshes[key] = mesh
+		}
+		n.Mesh = &mesh
+	}
+
+	// children after the parent
+	i := e.addNode(n)
+	for _, c := range o.Children() {
+		child, err := e.node(c)
+		if err != nil {
+			return 0, err
+		}
+		e.doc.Nodes[i].Children = append(e.doc.Nodes[i].Children, child)
+	}
+	return i, nil
+}
+
+func (e *gltfExporter) mesh(vertices []Vertex, faces []Face, mat *Material) (int, error) {
+	n := len(vertices)
+	positions := make([]float64, 0, n*3)
+	normals := make([]float64, 0, n*3)
+	uvs := make([]float64, 0, n*2)
+	colors := make([]float64, 0, n*3)
+	tangents := make([]float64, 0, n*4)
+
+	colored, tangent := false, false
+	for _, v := range vertices {
+		positions = append(positions, v.position[0], v.position[1], v.position[2])
+		normals = append(normals, v.normal[0], v.normal[1], v.normal[2])
+		uvs = append(uvs, v.uv[0], v.uv[1])
+		colors = append(colors, v.color.R, v.color.G, v.color.B)
+		tangents = append(tangents, v.tangent[0], v.tangent[1], v.tangent[2], v.tangent[3])
+
+		colored = colored || v.color != (math.Color{1, 1, 1})
+		tangent = tangent || v.tangent != (math.Vector{})
+	}
+
+	p := gltfOutPrimitive{
+		Attributes: map[string]int{
+			"POSITION":   e.floatAccessor(positions, "VEC3", true),
+			"NORMAL":     e.floatAccessor(normals, "VEC3", false),
+			"TEXCOORD_0": e.floatAccessor(uvs, "VEC2", false),
+		},
+	}
+	if colored {
+		p.Attributes["COLOR_0"] = e.floatAccessor(colors, "VEC3", false)
+	}
+	if tangent {
+		p.Attributes["TANGENT"] = e.floatAccessor(tangents, "VEC4", false)
+	}
+
+	indices := make([]int, 0, len(faces)*3)
+	for _, f := range faces {
+		indices = append(indices, f.A, f.B, f.C)
+	}
+	p.Indices = e.indexAccessor(indices, n)
+
+	if mat != nil {
+		i, err := e.material(mat)
+		if err != nil {
+			return 0, err
+		}
+		p.Material = &i
+	}
+
+	e.doc.Meshes = append(e.doc.Meshes, gltfOutMesh{Primitives: []gltfOutPrimitive{p}})
+	return len(e.doc.Meshes) - 1, nil
+}
+
+// append data to the binary buffer on a 4 byte boundary
+func (e *gltfExporter) bufferView(data []byte, target int) int {
+	for e.bin.Len()%4 != 0 {
+		e.bin.WriteByte(0)
+	}
+
+	e.doc.BufferViews = append(e.doc.BufferViews, gltfOutBufferView{
+		ByteOffset: e.bin.Len(),
+		ByteLength: len(data),
+		Target:     target,
+	})
+	e.bin.Write(data)
+	return len(e.doc.BufferViews) - 1
+}
+
+// float32 vertex attribute, positions need their bounds
+func (e *gltfExporter) floatAccessor(values []float64, typ string, bounds bool) int {
+	components := gltfComponents(typ)
+	data := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], m.Float32bits(float32(v)))
+	}
+
+	a := gltfOutAccessor{
+		BufferView:    e.bufferView(data, 34962), // array buffer
+		ComponentType: 5126,
+		Count:         len(values) / components,
+		Type:          typ,
+	}
+
+	if bounds && a.Count > 0 {
+		a.Min = make([]float64, components)
+		a.Max = make([]float64, components)
+		for i := range a.Min {
+			a.Min[i], a.Max[i] = m.Inf(1), m.Inf(-1)
+		}
+		for i, v := range values {
+			// bounds of the stored float32 values
+			v = float64(float32(v))
+			a.Min[i%components] = m.Min(a.Min[i%components], v)
+			a.Max[i%components] = m.Max(a.Max[i%components], v)
+		}
+	}
+
+	e.doc.Accessors = append(e.doc.Accessors, a)
+	return len(e.doc.Accessors) - 1
+}
+
+// unsigned short indices, unsigned int if there are more vertices
+func (e *gltfExporter) indexAccessor(indices []int, vertices int) int {
+	componentType, size := 5123, 2
+	if vertices > m.MaxUint16+1 {
+		componentType, size = 5125, 4
+	}
+
+	data := make([]byte, len(indices)*size)
+	for i, v := range indices {
+		if size == 2 {
+			binary.LittleEndian.PutUint16(data[i*2:], uint16(v))
+		} else {
+			binary.LittleEndian.PutUint32(data[i*4:], uint32(v))
+		}
+	}
+
+	e.doc.Accessors = append(e.doc.Accessors, gltfOutAccessor{
+		BufferView:    e.bufferView(data, 34963), // element array buffer
+		ComponentType: componentType,
+		Count:         len(indices),
+		Type:          "SCALAR",
+	})
+	return len(e.doc.Accessors) - 1
+}
+
+// phong colors as a rough, non metallic material
+func (e *gltfExporter) material(mat *Material) (int, error) {
+	if i, found := e.materials[mat]; found {
+		return i, nil
+	}
+
+	var out gltfOutMaterial
+	diffuse := materialColor(mat, "diffuse", math.Color{1, 1, 1})
+	opacity := materialFloat(mat, "opacity", 1)
+	out.PBRMetallicRoughness.BaseColorFactor = []float64{diffuse.R, diffuse.G, diffuse.B, opacity}
+	out.PBRMetallicRoughness.RoughnessFactor = 1
+	if opacity < 1 {
+		out.AlphaMode = "BLEND"
+	}
+
+	if c := materialColor(mat, "emissive", math.Color{0, 0, 0}); c != (math.Color{0, 0, 0}) {
+		out.EmissiveFactor = []float64{c.R, c.G, c.B}
+	}
+
+	if t := materialTexture(mat); t != nil {
+		i, err := e.image(t)
+		if err != nil {
+			return 0, err
+		}
+		out.PBRMetallicRoughness.BaseColorTexture = &struct {
+			Index int `json:"index"`
+		}{i}
+	}
+
+	e.doc.Materials = append(e.doc.Materials, out)
+	e.materials[mat] = len(e.doc.Materials) - 1
+	return e.materials[mat], nil
+}
+
+// texture with its sampler and image, referenced by uri or embedded as png
+func (e *gltfExporter) image(t *ImageTexture) (int, error) {
+	if i, found := e.textures[t]; found {
+		return i, nil
+	}
+
+	uri, err := e.texture(t)
+	if err != nil {
+		return 0, err
+	}
+
+	img := gltfOutImage{URI: uri}
+	if uri == "" {
+		data, err := encodeTexturePNG(t)
+		if err != nil {
+			return 0, err
+		}
+		view := e.bufferView(data, 0)
+		img = gltfOutImage{MimeType: "image/png", BufferView: &view}
+	}
+	e.doc.Images = append(e.doc.Images, img)
+
+	s, r := t.Wrap()
+	mag, min := t.Filter()
+	e.doc.Samplers = append(e.doc.Samplers, gltfOutSampler{
+		MagFilter: int(mag),
+		MinFilter: int(min),
+		WrapS:     int(s),
+		WrapT:     int(r),
+	})
+
+	e.doc.Textures = append(e.doc.Textures, gltfOutTexture{
+		Sampler: len(e.doc.Samplers) - 1,
+		Source:  len(e.doc.Images) - 1,
+	})
+	e.textures[t] = len(e.doc.Textures) - 1
+	return e.textures[t], nil
+}
+
+// header, json chunk and binary chunk, both padded to 4 bytes
+func (e *gltfExporter) encodeGLB() ([]byte, error) {
+	doc, err := json.Marshal(e.doc)
+	if err != nil {
+		return nil, err
+	}
+	for len(doc)%4 != 0 {
+		doc = append(doc, ' ')
+	}
+
+	bin := append([]byte(nil), e.bin.Bytes()...)
+	for len(bin)%4 != 0 {
+		bin = append(bin, 0)
+	}
+
+	length := 12 + 8 + len(doc)
+	if len(bin) > 0 {
+		length += 8 + len(bin)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("glTF")
+	binary.Write(&out, binary.LittleEndian, []uint32{2, uint32(length), uint32(len(doc)), 0x4E4F534A})
+	out.Write(doc)
+	if len(bin) > 0 {
+		binary.Write(&out, binary.LittleEndian, []uint32{uint32(len(bin)), 0x004E4942})
+		out.Write(bin)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"testing"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// loader of the exported document and buffer
+func testGLTFReload(t *testing.T, e *gltfExporter) *gltfLoader {
+	glb, err := e.encodeGLB()
+	if err != nil {
+		t.Fatalf("encodeGLB failed: %v", err)
+	}
+	doc, bin, err := parseGLB(glb)
+	if err != nil {
+		t.Fatalf("parseGLB failed: %v", err)
+	}
+
+	l := &gltfLoader{bin: bin}
+	if err := json.Unmarshal(doc, &l.doc); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if err := l.loadBuffers(); err != nil {
+		t.Fatalf("loadBuffers failed: %v", err)
+	}
+	return l
+}
+
+func TestGLTFExporter_Nodes(t *testing.T) {
+	root := NewGroup()
+	root.SetPosition(math.Vector{1, 2, 3})
+
+	child := NewGroup()
+	child.SetRotation(math.QuaternionFromAxisAngle(math.Vector{0, 1, 0}, math.Pi/2))
+	child.SetScale(math.Vector{2, 2, 2})
+	root.AddChild(child)
+
+	e := newGLTFExporter(false, nil)
+	if err := e.add(root); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+
+	glb, err := e.encodeGLB()
+	if err != nil {
+		t.Fatalf("encodeGLB failed: %v", err)
+	}
+	g, err := parseGLTF(glb, ".")
+	if err != nil {
+		t.Fatalf("parseGLTF failed: %v", err)
+	}
+
+	if len(g.Nodes) != 2 || g.Nodes[1].Parent() != g.Nodes[0] || g.Nodes[0].Parent() != g.Scene {
+		t.Fatalf("parseGLTF() hierarchy is wrong")
+	}
+	if p := g.Nodes[0].Position(); p.DistanceTo(root.Position()) > 1e-9 {
+		t.Errorf("position != %v (got %v)", root.Position(), p)
+	}
+	if s := g.Nodes[1].Scale(); s.DistanceTo(child.Scale()) > 1e-9 {
+		t.Errorf("scale != %v (got %v)", child.Scale(), s)
+	}
+	if r := g.Nodes[1].Rotation(); r != child.Rotation() {
+		t.Errorf("rotation != %v (got %v)", child.Rotation(), r)
+	}
+}
+
+func TestGLTFExporter_Mesh(t *testing.T) {
+	geo := NewCubeGeometry(2)
+	for i := range geo.vertices {
+		geo.vertices[i].color = math.Color{0, 1, 0}
+	}
+
+	root := NewGroup()
+	root.SetPosition(math.Vector{0, 5, 0})
+	mesh := NewMesh(geo, nil)
+	mesh.SetScale(math.Vector{1, 1, -3}) // mirrored
+	instance := NewMesh(geo, nil)
+	root.AddChild(mesh, instance)
+
+	tests := []struct {
+		bake   bool
+		nodes  int
+		meshes int
+		matrix []math.Matrix // by mesh
+	}{
+		{false, 3, 1, []math.Matrix{math.Identity()}},
+		{true, 2, 2, []math.Matrix{root.Matrix().Mul(mesh.Matrix()), root.Matrix().Mul(instance.Matrix())}},
+	}
+
+	for _, c := range tests {
+		e := newGLTFExporter(c.bake, nil)
+		if err := e.add(root); err != nil {
+			t.Fatalf("add(bake %v) failed: %v", c.bake, err)
+		}
+		l := testGLTFReload(t, e)
+
+		if len(l.doc.Nodes) != c.nodes || len(l.doc.Meshes) != c.meshes {
+			t.Errorf("add(bake %v) != %v nodes, %v meshes (got %v, %v)",
+				c.bake, c.nodes, c.meshes, len(l.doc.Nodes), len(l.doc.Meshes))
+			continue
+		}
+
+		for i, mat := range c.matrix {
+			p := l.doc.Meshes[i].Primitives[0]
+			loaded, _, err := l.geometry(p.Attributes, p.Indices, 4)
+			if err != nil {
+				t.Errorf("geometry(bake %v, mesh %v) failed: %v", c.bake, i, err)
+				continue
+			}
+			if len(loaded.vertices) != len(geo.vertices) || len(loaded.faces) != len(geo.faces) {
+				t.Errorf("geometry(bake %v, mesh %v) != %v vertices, %v faces (got %v, %v)", c.bake, i,
+					len(geo.vertices), len(geo.faces), len(loaded.vertices), len(loaded.faces))
+				continue
+			}
+
+			for j, v := range loaded.vertices {
+				o := geo.vertices[j]
+				pos := o.position
+				pos[3] = 1
+				pos = mat.Transform(pos)
+				pos[3] = 0
+
+				if v.position.DistanceTo(pos) > 1e-5 {
+					t.Errorf("geometry(bake %v, mesh %v) vertex %v != %v (got %v)", c.bake, i, j, pos, v.position)
+				}
+				if v.uv.DistanceTo(o.uv) > 1e-6 || v.color != (math.Color{0, 1, 0}) {
+					t.Errorf("geometry(bake %v, mesh %v) vertex %v uv %v, color %v", c.bake, i, j, v.uv, v.color)
+				}
+			}
+			if v := testVolume(loaded); v <= 0 {
+				t.Errorf("geometry(bake %v, mesh %v) is inside out, volume %v", c.bake, i, v)
+			}
+		}
+	}
+}
+
+func TestGLTFExporter_Material(t *testing.T) {
+	tex := NewTextureFromImage(image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	mat := &Material{
+		uniforms: map[string]interface{}{
+			"diffuse":    math.Color{1, 0.5, 0},
+			"opacity":    0.5,
+			"emissive":   math.Color{0, 0, 1},
+			"diffuseMap": tex,
+		},
+	}
+
+	root := NewGroup()
+	root.AddChild(NewMesh(NewPlaneGeometry(1, 1), mat), NewMesh(NewCubeGeometry(1), mat))
+
+	e := newGLTFExporter(false, func(*ImageTexture) (string, error) { return "", nil })
+	if err := e.add(root); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	l := testGLTFReload(t, e)
+
+	if len(l.doc.Materials) != 1 || len(l.doc.Textures) != 1 || len(l.doc.Images) != 1 {
+		t.Fatalf("add() != 1 material, texture and image (got %v, %v, %v)",
+			len(l.doc.Materials), len(l.doc.Textures), len(l.doc.Images))
+	}
+
+	info := l.doc.Materials[0]
+	if f := info.PBRMetallicRoughness.BaseColorFactor; len(f) != 4 || f[0] != 1 || f[1] != 0.5 || f[2] != 0 || f[3] != 0.5 {
+		t.Errorf("base color != [1 0.5 0 0.5] (got %v)", f)
+	}
+	if info.AlphaMode != "BLEND" {
+		t.Errorf("alpha mode != BLEND (got %v)", info.AlphaMode)
+	}
+	if f := info.EmissiveFactor; len(f) != 3 || f[2] != 1 {
+		t.Errorf("emissive != [0 0 1] (got %v)", f)
+	}
+
+	// embedded png
+	img := l.doc.Images[0]
+	if img.BufferView == nil || img.MimeType != "image/png" {
+		t.Fatalf("image is not embedded: %+v", img)
+	}
+	data, _, err := l.bufferView(*img.BufferView)
+	if err != nil {
+		t.Fatalf("bufferView failed: %v", err)
+	}
+	if decoded, _, err := image.Decode(bytes.NewReader(data)); err != nil || decoded.Bounds().Dx() != 2 {
+		t.Errorf("embedded image can not be decoded: %v", err)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	//"image"
 	"io"
+	"log"
+	m "math"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -15,402 +17,944 @@ import (
 
 // TODO: caching
 
+// maximum angle between faces smoothed by computed normals
+const objCreaseAngle = math.Pi / 3
+
+// vertex limit of a single mesh, larger groups are split
+const objMaxVertices = m.MaxUint16 + 1
+
+// statements without geometry for triangle meshes, skipped with a warning
+var objUnsupported = map[string]bool{
+	"l": true, "p": true, "vp": true, "mg": true,
+	"cstype": true, "deg": true, "bmat": true, "step": true,
+	"curv": true, "curv2": true, "surf": true,
+	"parm": true, "trim": true, "hole": true, "scrv": true, "sp": true, "end": true,
+	"con": true, "lod": true, "bevel": true, "c_interp": true, "d_interp": true,
+	"usemap": true, "maplib": true, "shadow_obj": true, "trace_obj": true,
+	"ctech": true, "stech": true, "call": true, "csh": true,
+}
+
+// face corner, indices are zero based, -1 if missing
+type objVertexKey struct {
+	v, vt, vn int
+	smooth    int // smoothing group, unique per face for flat faces
+}
+
+// position shared by the faces of a smoothing group
+type objSmoothKey struct {
+	v, smooth int
+}
+
+// faces of one object, group and material
+type objMesh struct {
+	object   int // index of the object, -1 for the root
+	group    string
+	material string
+
+	geo     *Geometry
+	keys    map[objVertexKey]int
+	smooth  []objSmoothKey // per vertex
+	grouped bool           // faces follow smoothing groups
+}
+
+type objFile struct {
+	objects  []string
+	meshes   []*objMesh
+	libs     []string // material libraries
+	warnings []string
+}
+
+// line based statements of object and material files
+type statementReader struct {
+	name     string // used in messages
+	line     int
+	warnings []string
+	skipped  map[string]bool // keywords already warned about
+}
+
+type objParser struct {
+	statementReader
+	file *objFile
+
+	positions []math.Vector
+	colors    []math.Color
+	uvs       []math.Vector
+	normals   []math.Vector
+
+	object   int
+	group    string
+	material string
+	smooth   int  // current smoothing group, 0 is off
+	grouped  bool // smoothing groups are in use
+	faces    int
+
+	mesh *objMesh
+}
+
 func LoadObject(obj, mtl string) (Object, error) {
-	// load materials
-	materials := map[string]Material{}
+	file, err := os.Open(obj)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	parsed, err := parseOBJ(file, obj)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range parsed.warnings {
+		log.Printf("%v\n", w)
+	}
+
+	// load materials, an explicit file replaces the referenced libraries
+	libs := parsed.libs
 	if mtl != "" {
-		var err error
-		materials, err = loadMTL(mtl)
+		libs = []string{mtl}
+	} else {
+		basePath := filepath.Dir(obj) + string(filepath.Separator)
+		for i, l := range libs {
+			libs[i] = basePath + l
+		}
+	}
+
+	materials := map[string]*Material{}
+	for _, l := range libs {
+		loaded, err := loadMTL(l)
 		if err != nil {
-			return nil, err
+			if mtl != "" {
+				return nil, err
+			}
+			log.Printf("could not load mtl file: %v\n", err)
+			continue
+		}
+		for n, mat := range loaded {
+			materials[n] = mat
 		}
 	}
 
-	// open object file, init reader
-	file, err := os.Open(obj)
-	if err != nil {
-		return nil, err
+	var fallback *Material
+	material := func(name string) (*Material, error) {
+		if mat, ok := materials[name]; ok {
+			return mat, nil
+		}
+		if name != "" {
+			log.Printf("%v: unknown material %q\n", obj, name)
+		}
+		if fallback == nil {
+			var err error
+			if fallback, err = NewMaterial("phong"); err != nil {
+				return nil, err
+			}
+		}
+		materials[name] = fallback
+		return fallback, nil
 	}
-	defer file.Close()
-	reader := bufio.NewReader(file)
-	basePath := filepath.Dir(obj) + string(filepath.Separator)
 
 	// root object
 	group := NewGroup()
-	object := group
+	objects := make([]*Group, len(parsed.objects))
+	for i := range objects {
+		objects[i] = NewGroup()
+		group.AddChild(objects[i])
+	}
 
-	// starting mesh
-	geo := NewGeometry()
-	mat, err := NewMaterial("phong")
-	if err != nil {
+	for _, om := range parsed.meshes {
+		mat, err := material(om.material)
+		if err != nil {
+			return nil, err
+		}
+
+		if om.object < 0 {
+			group.AddChild(NewMesh(om.geo, mat))
+		} else {
+			objects[om.object].AddChild(NewMesh(om.geo, mat))
+		}
+	}
+
+	return group, nil
+}
+
+// parse an object file without creating materials, name is used in messages
+func parseOBJ(r io.Reader, name string) (*objFile, error) {
+	p := &objParser{
+		statementReader: statementReader{name: name},
+		file:            &objFile{},
+		object:          -1,
+	}
+
+	if err := p.read(r, p.parse); err != nil {
 		return nil, err
 	}
-	mesh := NewMesh(geo, mat)
+	p.file.warnings = p.warnings
 
-	// cache
-	var (
-		vertices []math.Vector
-		normals  []math.Vector
-		uvs      []math.Vector
-		color    = math.Color{1, 1, 1}
-	)
+	var meshes []*objMesh
+	for _, om := range p.file.meshes {
+		om.computeNormals()
+		om.keys, om.smooth = nil, nil
 
-	for {
-		if line, err := reader.ReadString('\n'); err == nil {
-			fields := strings.Split(strings.TrimSpace(line), " ")
-			value := strings.TrimSpace(strings.Join(fields[1:], " "))
+		// split vertices of computed normals can exceed the limit again
+		for _, part := range om.split() {
+			part.geo.ComputeBoundary()
+			meshes = append(meshes, part)
+		}
+	}
+	p.file.meshes = meshes
 
-			switch strings.ToLower(fields[0]) {
+	return p.file, nil
+}
 
-			case "v": // vertex: x, y, z
-				// v 9.035167 173.402832 -2.713000
-				x, err := strconv.ParseFloat(fields[1], 64)
-				if err != nil {
-					return nil, err
-				}
+// calls parse with the fields of every statement, continued lines are joined and comments removed
+func (r *statementReader) read(in io.Reader, parse func(fields []string) error) error {
+	scanner := bufio.NewScanner(in)
+	var statement string
+	for scanner.Scan() {
+		r.line++
+		line := scanner.Text()
+
+		// continued on the next line
+		if strings.HasSuffix(line, "\\") {
+			statement += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		statement += line
 
-				y, err := strconv.ParseFloat(fields[2], 64)
-				if err != nil {
-					return nil, err
-				}
+		if err := r.dispatch(statement, parse); err != nil {
+			return err
+		}
+		statement = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return r.dispatch(statement, parse)
+}
 
-				z, err := strconv.ParseFloat(fields[3], 64)
-				if err != nil {
-					return nil, err
-				}
+func (r *statementReader) dispatch(statement string, parse func(fields []string) error) error {
+	fields := strings.Fields(statement)
 
-				vertices = append(vertices, math.Vector{x, y, z})
+	// trailing comments
+	for i, f := range fields {
+		if strings.HasPrefix(f, "#") {
+			fields = fields[:i]
+			break
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return parse(fields)
+}
 
-			case "vt": // texture: u, v
-				// vt 0.748573 0.750412
-				u, err := strconv.ParseFloat(fields[1], 64)
-				if err != nil {
-					return nil, err
-				}
+func (r *statementReader) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("%v:%v: %v", r.name, r.line, fmt.Sprintf(format, a...))
+}
 
-				v, err := strconv.ParseFloat(fields[2], 64)
-				if err != nil {
-					return nil, err
-				}
+func (r *statementReader) warnf(format string, a ...interface{}) {
+	r.warnings = append(r.warnings, fmt.Sprintf("%v:%v: %v", r.name, r.line, fmt.Sprintf(format, a...)))
+}
 
-				uvs = append(uvs, math.Vector{u, 1.0 - v})
+// warns once per keyword
+func (r *statementReader) skip(keyword string, known bool) {
+	if r.skipped == nil {
+		r.skipped = map[string]bool{}
+	}
+	if r.skipped[keyword] {
+		return
+	}
+	r.skipped[keyword] = true
 
-			case "vn": // normal: x, y, z
-				// vn 0.000000 0.000000 -1.000000
-				x, err := strconv.ParseFloat(fields[1], 64)
-				if err != nil {
-					return nil, err
-				}
+	if known {
+		r.warnf("unsupported statement %q skipped", keyword)
+	} else {
+		r.warnf("unknown statement %q skipped", keyword)
+	}
+}
 
-				y, err := strconv.ParseFloat(fields[2], 64)
-				if err != nil {
-					return nil, err
-				}
+// floats of the fields, at least min values
+func (r *statementReader) floats(fields []string, min int) ([]float64, error) {
+	if len(fields) < min {
+		return nil, r.errorf("expected %v values, got %v", min, len(fields))
+	}
 
-				z, err := strconv.ParseFloat(fields[3], 64)
-				if err != nil {
-					return nil, err
-				}
+	values := make([]float64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, r.errorf("invalid number %q", f)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
 
-				normals = append(normals, math.Vector{x, y, z})
-
-			case "f": // face
-				// f 3 8 4 - vertex
-				// f 1/4 2/5 3/6 - vertex/uv
-				// f 24//24 25//24 13//24 - vertex//normal
-				// f 5/1/1 1/2/1 4/3/1 - vertex/uv/normal
-				// 8/11/7 - 8 vertex index (1 based), 11 texture index, 7 normal index
-
-				// quad instead of tri, split up
-				// f 5/1/1 1/2/1 4/3/1 3/4/2
-				seg := [][]string{}
-				if len(fields) == 5 {
-					seg = append(seg, []string{"f", fields[1], fields[2], fields[4]})
-					seg = append(seg, []string{"f", fields[2], fields[3], fields[4]})
-				} else {
-					seg = append(seg, fields)
-				}
+// zero based index of a one based or negative relative reference
+func (p *objParser) index(ref string, count int, kind string) (int, error) {
+	i, err := strconv.Atoi(ref)
+	if err != nil {
+		return 0, p.errorf("invalid %v index %q", kind, ref)
+	}
 
-				for _, fields := range seg {
-					var face [3]Vertex
-					var v uint64
-
-					for i, f := range fields[1:4] {
-						a := strings.Split(f, "/")
-						face[i].color = color
-
-						// vertex
-						if v, err = strconv.ParseUint(a[0], 10, 64); err != nil {
-							return nil, err
-						}
-						face[i].position = vertices[v-1]
-
-						// uv
-						if len(a) > 1 && a[1] != "" {
-							if v, err = strconv.ParseUint(a[1], 10, 64); err != nil {
-								return nil, err
-							}
-							face[i].uv = uvs[v-1]
-						}
-
-						// normal
-						if len(a) == 3 {
-							if v, err = strconv.ParseUint(a[2], 10, 64); err != nil {
-								return nil, err
-							}
-							face[i].normal = normals[v-1]
-						}
-					}
-
-					geo.AddFace(face[0], face[1], face[2])
-				}
+	switch {
+	case i > 0 && i <= count:
+		return i - 1, nil
+	case i < 0 && -i <= count:
+		return count + i, nil
+	}
+	return 0, p.errorf("%v index %v out of range (%v defined)", kind, i, count)
+}
 
-			case "o": // new object
-				object = NewGroup()
-				group.AddChild(object)
+func (p *objParser) parse(fields []string) error {
+	value := strings.Join(fields[1:], " ")
 
-			case "g": // mesh within object
-				if geo.VerticesCount() > 0 {
-					geo.MergeVertices()
-					geo.ComputeBoundary()
+	switch keyword := strings.ToLower(fields[0]); keyword {
+	case "v": // vertex: x, y, z, optional r, g, b
+		// v 9.035167 173.402832 -2.713000
+		v, err := p.floats(fields[1:], 3)
+		if err != nil {
+			return err
+		}
 
-					object.AddChild(mesh)
+		color := math.Color{1, 1, 1}
+		if len(v) >= 6 {
+			color = math.Color{v[3], v[4], v[5]}
+		}
 
-					geo = NewGeometry()
-					mesh = NewMesh(geo, mat)
-				}
+		p.positions = append(p.positions, math.Vector{v[0], v[1], v[2]})
+		p.colors = append(p.colors, color)
 
-			case "usemtl": // material name for the element following it
-				if m, ok := materials[value]; ok {
-					mat = &m
-					mesh.SetMaterial(mat)
-				} else {
-					mat, err = NewMaterial("phong")
-					if err != nil {
-						return nil, err
-					}
-				}
+	case "vt": // texture: u, optional v, w
+		// vt 0.748573 0.750412
+		v, err := p.floats(fields[1:], 1)
+		if err != nil {
+			return err
+		}
+		v = append(v, 0)
 
-			case "mtllib": // mtl file
-				if mtl == "" {
-					if materials, err = loadMTL(basePath + value); err != nil {
-						//return nil, err
-						fmt.Println("could not load mtl file:", err.Error())
-					}
-				}
+		p.uvs = append(p.uvs, math.Vector{v[0], 1.0 - v[1]})
+
+	case "vn": // normal: x, y, z
+		// vn 0.000000 0.000000 -1.000000
+		v, err := p.floats(fields[1:], 3)
+		if err != nil {
+			return err
+		}
 
-			case "s": // smooth shading
-			case "#": // comment
-			case "": // empty line
-			default:
-				return nil, fmt.Errorf("unknown object line type: %s", line)
+		p.normals = append(p.normals, math.Vector{v[0], v[1], v[2]})
+
+	case "f", "fo": // face
+		// f 3 8 4 - vertex
+		// f 1/4 2/5 3/6 - vertex/uv
+		// f 24//24 25//24 13//24 - vertex//normal
+		// f 5/1/1 1/2/1 4/3/1 - vertex/uv/normal
+		// f -4 -3 -2 -1 - relative to the last defined vertices
+		return p.face(fields[1:])
+
+	case "o": // new object
+		p.file.objects = append(p.file.objects, value)
+		p.object = len(p.file.objects) - 1
+		p.mesh = nil
+
+	case "g": // mesh within object
+		p.group = value
+		p.mesh = nil
+
+	case "usemtl": // material name for the element following it
+		if value != p.material {
+			p.material = value
+			p.mesh = nil
+		}
+
+	case "mtllib": // mtl files
+		p.file.libs = append(p.file.libs, fields[1:]...)
+
+	case "s": // smoothing group
+		switch v := strings.ToLower(value); v {
+		case "off", "0":
+			p.smooth = 0
+		default:
+			s, err := strconv.Atoi(v)
+			if err != nil || s < 0 {
+				return p.errorf("invalid smoothing group %q", value)
 			}
-		} else if err == io.EOF {
+			p.smooth = s
+		}
+		p.grouped = true
+
+	default:
+		p.skip(keyword, objUnsupported[keyword])
+	}
+
+	return nil
+}
+
+func (p *objParser) face(refs []string) error {
+	if len(refs) < 3 {
+		p.warnf("face with %v vertices skipped", len(refs))
+		return nil
+	}
+	p.faces++
+
+	// flat faces never share vertices
+	smooth := p.smooth
+	if p.grouped && smooth == 0 {
+		smooth = -p.faces
+	}
+
+	keys := make([]objVertexKey, len(refs))
+	for i, ref := range refs {
+		a := strings.Split(ref, "/")
+		if len(a) > 3 {
+			return p.errorf("invalid face vertex %q", ref)
+		}
+		k := objVertexKey{vt: -1, vn: -1, smooth: smooth}
+
+		var err error
+		if k.v, err = p.index(a[0], len(p.positions), "vertex"); err != nil {
+			return err
+		}
+		if len(a) > 1 && a[1] != "" {
+			if k.vt, err = p.index(a[1], len(p.uvs), "texture"); err != nil {
+				return err
+			}
+		}
+		if len(a) > 2 && a[2] != "" {
+			if k.vn, err = p.index(a[2], len(p.normals), "normal"); err != nil {
+				return err
+			}
+		}
+		keys[i] = k
+	}
+
+	if p.mesh == nil || len(p.mesh.geo.vertices)+len(keys) > objMaxVertices {
+		p.mesh = &objMesh{
+			object:   p.object,
+			group:    p.group,
+			material: p.material,
+			geo:      NewGeometry(),
+			keys:     map[objVertexKey]int{},
+		}
+		p.file.meshes = append(p.file.meshes, p.mesh)
+	}
+	if p.grouped {
+		p.mesh.grouped = true
+	}
+
+	indices := make([]int, len(keys))
+	positions := make([]math.Vector, len(keys))
+	for i, k := range keys {
+		indices[i] = p.vertex(k)
+		positions[i] = p.positions[k.v]
+	}
+
+	for _, f := range triangulatePolygon(positions) {
+		p.mesh.geo.AddIndexedFace(indices[f.A], indices[f.B], indices[f.C])
+	}
+
+	return nil
+}
+
+// index of the face corner in the current mesh, reused if already added
+func (p *objParser) vertex(k objVertexKey) int {
+	if i, ok := p.mesh.keys[k]; ok {
+		return i
+	}
+
+	v := Vertex{
+		position: p.positions[k.v],
+		color:    p.colors[k.v],
+	}
+	if k.vt >= 0 {
+		v.uv = p.uvs[k.vt]
+	}
+	if k.vn >= 0 {
+		v.normal = p.normals[k.vn]
+	}
+
+	i := p.mesh.geo.AddVertex(v)
+	p.mesh.keys[k] = i
+	p.mesh.smooth = append(p.mesh.smooth, objSmoothKey{k.v, k.smooth})
+	return i
+}
+
+// triangles of a planar polygon in its winding order, projected onto the plane
+// of its largest extent and ear clipped, falls back to a fan if that fails
+func triangulatePolygon(points []math.Vector) []Face {
+	n := len(points)
+	if n == 3 {
+		return []Face{{0, 1, 2}}
+	}
+
+	// newell normal
+	var normal math.Vector
+	for i, a := range points {
+		b := points[(i+1)%n]
+		normal[0] += (a[1] - b[1]) * (a[2] + b[2])
+		normal[1] += (a[2] - b[2]) * (a[0] + b[0])
+		normal[2] += (a[0] - b[0]) * (a[1] + b[1])
+	}
+
+	// drop the dominant axis, the remaining two form a right handed basis
+	u, v := 0, 1
+	switch ax, ay, az := m.Abs(normal[0]), m.Abs(normal[1]), m.Abs(normal[2]); {
+	case ax >= ay && ax >= az:
+		u, v = 1, 2
+	case ay >= az:
+		u, v = 2, 0
+	}
+
+	contour := make([]math.Vector, n)
+	order := make([]int, n)
+	for i, pt := range points {
+		contour[i] = math.Vector{pt[u], pt[v]}
+		order[i] = i
+	}
+
+	// triangulation expects counter clockwise, flip the result back afterwards
+	flipped := signedArea(contour) < 0
+	if flipped {
+		reversePoints(contour)
+		for i := range order {
+			order[i] = n - 1 - i
+		}
+	}
+
+	faces := Triangulate(contour, nil)
+	if len(faces) == 0 {
+		for i := 1; i < n-1; i++ {
+			faces = append(faces, Face{0, i, i + 1})
+		}
+		return faces
+	}
+
+	for i, f := range faces {
+		f = Face{order[f.A], order[f.B], order[f.C]}
+		if flipped {
+			f.B, f.C = f.C, f.B
+		}
+		faces[i] = f
+	}
+	return faces
+}
+
+// meshes within the vertex limit with the faces in their order
+func (om *objMesh) split() []*objMesh {
+	g := om.geo
+	if len(g.vertices) <= objMaxVertices {
+		return []*objMesh{om}
+	}
+
+	var parts []*objMesh
+	var part *objMesh
+	var remap map[int]int // vertex index, index in the part
+	for _, f := range g.faces {
+		if part == nil || len(part.geo.vertices)+3 > objMaxVertices {
+			part = &objMesh{
+				object:   om.object,
+				group:    om.group,
+				material: om.material,
+				geo:      NewGeometry(),
+				grouped:  om.grouped,
+			}
+			parts = append(parts, part)
+			remap = map[int]int{}
+		}
+
+		idx := [3]int{f.A, f.B, f.C}
+		for j, i := range idx {
+			n, found := remap[i]
+			if !found {
+				n = part.geo.AddVertex(g.vertices[i])
+				remap[i] = n
+			}
+			idx[j] = n
+		}
+		part.geo.AddIndexedFace(idx[0], idx[1], idx[2])
+	}
+
+	return parts
+}
+
+// fill in missing normals, smoothed within smoothing groups or by crease angle without them
+func (om *objMesh) computeNormals() {
+	g := om.geo
+
+	missing := false
+	for _, v := range g.vertices {
+		if v.normal == (math.Vector{}) {
+			missing = true
 			break
-		} else {
-			return nil, err
 		}
 	}
+	if !missing {
+		return
+	}
+
+	if !om.grouped && !om.hasNormals() {
+		g.ComputeVertexNormals(objCreaseAngle)
+		return
+	}
 
-	// close last mesh
-	if geo.VerticesCount() > 0 {
-		geo.MergeVertices()
-		geo.ComputeBoundary()
+	sums := make(map[objSmoothKey]math.Vector)
+	for _, f := range g.faces {
+		idx := [3]int{f.A, f.B, f.C}
+		p := [3]math.Vector{g.vertices[f.A].position, g.vertices[f.B].position, g.vertices[f.C].position}
 
-		object.AddChild(mesh)
+		n, area := faceNormal(p[0], p[1], p[2])
+		for j := 0; j < 3; j++ {
+			k := om.smooth[idx[j]]
+			w := area * cornerAngle(p[j], p[(j+1)%3], p[(j+2)%3])
+			sums[k] = sums[k].Add(n.MulScalar(w))
+		}
 	}
 
-	return group, nil
+	for i, v := range g.vertices {
+		if v.normal == (math.Vector{}) {
+			g.vertices[i].normal = sums[om.smooth[i]].Normalize()
+		}
+	}
+	g.needsUpdate = true
 }
 
-func loadMTL(path string) (map[string]Material, error) {
-	// open file, init reader
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+func (om *objMesh) hasNormals() bool {
+	for k := range om.keys {
+		if k.vn >= 0 {
+			return true
+		}
 	}
-	defer file.Close()
-	reader := bufio.NewReader(file)
+	return false
+}
+
+// texture map statement of a material file
+type mtlMap struct {
+	path   string
+	scale  math.Vector // -s
+	offset math.Vector // -o
+	clamp  bool        // -clamp
+	bump   float64     // -bm
+}
+
+// uv scale and offset for the mapTransform uniform, the v axis of the uvs is flipped while loading
+func (t mtlMap) transform() math.Vector {
+	return math.Vector{t.scale[0], t.scale[1], t.offset[0], 1 - t.scale[1] - t.offset[1]}
+}
 
-	type info struct {
-		name       string
-		ka, kd, ks *math.Color // ambient, diffuse, specular color
-		ns         float64     // specular exponent
-		ni         float64     // optical density
-		d          float64     // dissolve
-		illum      int         // illumination model
-		map_kd     string      // diffuse texture map
+type mtlMaterial struct {
+	name           string
+	ka, kd, ks, ke *math.Color // ambient, diffuse, specular, emissive color
+	ns             float64     // specular exponent
+	ni             float64     // optical density, not used by the materials
+	d              float64     // dissolve, 1 is opaque
+	illum          int         // illumination model
+	maps           map[string]mtlMap
+}
+
+// number of option arguments, variable for -s, -o and -t
+var mtlMapOptions = map[string]int{
+	"-blendu": 1, "-blendv": 1, "-boost": 1, "-cc": 1, "-clamp": 1,
+	"-bm": 1, "-imfchan": 1, "-texres": 1, "-type": 1, "-mm": 2,
+	"-o": 3, "-s": 3, "-t": 3,
+}
+
+type mtlParser struct {
+	statementReader
+	materials []*mtlMaterial
+	current   *mtlMaterial
+}
+
+// parse a material file without loading textures, name is used in messages
+func parseMTL(r io.Reader, name string) ([]*mtlMaterial, []string, error) {
+	p := &mtlParser{
+		statementReader: statementReader{name: name},
 	}
 
-	basePath := filepath.Dir(path) + string(filepath.Separator)
+	if err := p.read(r, p.parse); err != nil {
+		return nil, nil, err
+	}
+	return p.materials, p.warnings, nil
+}
 
-	// cache
-	var tmp *info
-	materials := map[string]*info{}
+func (p *mtlParser) parse(fields []string) error {
+	keyword := strings.ToLower(fields[0])
+	if keyword == "newmtl" {
+		p.current = &mtlMaterial{
+			name:  strings.Join(fields[1:], " "),
+			d:     1,
+			illum: 2,
+			maps:  map[string]mtlMap{},
+		}
+		p.materials = append(p.materials, p.current)
+		return nil
+	}
 
-	for {
-		if line, err := reader.ReadString('\n'); err == nil {
-			fields := strings.Split(strings.TrimSpace(line), " ")
-			value := strings.TrimSpace(strings.Join(fields[1:], " "))
+	if p.current == nil {
+		return p.errorf("statement %q before newmtl", fields[0])
+	}
+	mat := p.current
 
-			switch strings.ToLower(fields[0]) {
-			case "newmtl": // name
-				tmp = &info{
-					name: value,
-				}
-				materials[value] = tmp
+	switch keyword {
+	case "ka", "kd", "ks", "ke": // ambient, diffuse, specular, emissive color
+		c, err := p.color(fields[1:])
+		if err != nil || c == nil {
+			return err
+		}
 
-			case "ns": // specular exponent
-				v, err := strconv.ParseFloat(fields[1], 64)
-				if err != nil {
-					return nil, err
-				}
-				tmp.ns = v
+		switch keyword {
+		case "ka":
+			mat.ka = c
+		case "kd":
+			mat.kd = c
+		case "ks":
+			mat.ks = c
+		case "ke":
+			mat.ke = c
+		}
 
-			case "ka": // ambient color
-				r, err := strconv.ParseFloat(fields[1], 64)
-				if err != nil {
-					return nil, err
-				}
+	case "ns", "ni": // specular exponent, optical density
+		v, err := p.floats(fields[1:], 1)
+		if err != nil {
+			return err
+		}
 
-				g, err := strconv.ParseFloat(fields[2], 64)
-				if err != nil {
-					return nil, err
-				}
+		if keyword == "ns" {
+			mat.ns = v[0]
+		} else {
+			mat.ni = v[0]
+		}
 
-				b, err := strconv.ParseFloat(fields[3], 64)
-				if err != nil {
-					return nil, err
-				}
+	case "d", "tr": // dissolve or its inverse transparency
+		// d -halo 0.5
+		args := fields[1:]
+		if len(args) > 0 && strings.ToLower(args[0]) == "-halo" {
+			p.skip("d -halo", true)
+			args = args[1:]
+		}
 
-				c := math.ColorFromRGB(r, g, b)
-				tmp.ka = &c
+		v, err := p.floats(args, 1)
+		if err != nil {
+			return err
+		}
 
-			case "kd": // diffuse color
-				r, err := strconv.ParseFloat(fields[1], 64)
-				if err != nil {
-					return nil, err
-				}
+		if keyword == "d" {
+			mat.d = v[0]
+		} else {
+			mat.d = 1 - v[0]
+		}
 
-				g, err := strconv.ParseFloat(fields[2], 64)
-				if err != nil {
-					return nil, err
-				}
+	case "illum": // illumination model
+		v, err := strconv.Atoi(strings.Join(fields[1:], " "))
+		if err != nil || v < 0 || v > 10 {
+			return p.errorf("invalid illumination model %q", strings.Join(fields[1:], " "))
+		}
+		mat.illum = v
 
-				b, err := strconv.ParseFloat(fields[3], 64)
-				if err != nil {
-					return nil, err
-				}
+	case "map_ka", "map_kd", "map_ks", "map_ke", "map_ns", "map_d",
+		"map_bump", "bump", "disp", "decal", "refl", "norm":
+		// map_Kd -s 2 2 1 -clamp on textures/diffuse.png
+		t, err := p.texture(fields[1:])
+		if err != nil {
+			return err
+		}
 
-				c := math.ColorFromRGB(r, g, b)
-				tmp.kd = &c
+		if keyword == "bump" {
+			keyword = "map_bump"
+		}
+		mat.maps[keyword] = t
 
-			case "ks": // specular color
-				r, err := strconv.ParseFloat(fields[1], 64)
-				if err != nil {
-					return nil, err
-				}
+	case "tf", "sharpness": // transmission filter, reflection sharpness
+		p.skip(keyword, true)
 
-				g, err := strconv.ParseFloat(fields[2], 64)
-				if err != nil {
-					return nil, err
-				}
+	default:
+		p.skip(keyword, false)
+	}
 
-				b, err := strconv.ParseFloat(fields[3], 64)
-				if err != nil {
-					return nil, err
-				}
+	return nil
+}
 
-				c := math.ColorFromRGB(r, g, b)
-				tmp.ks = &c
+// rgb color, a single value for gray, spectral and xyz colors are skipped
+func (p *mtlParser) color(fields []string) (*math.Color, error) {
+	if len(fields) > 0 {
+		if t := strings.ToLower(fields[0]); t == "spectral" || t == "xyz" {
+			p.skip(t+" color", true)
+			return nil, nil
+		}
+	}
 
-			case "ni": // optical density
-				v, err := strconv.ParseFloat(fields[1], 64)
-				if err != nil {
-					return nil, err
-				}
-				tmp.ni = v
+	v, err := p.floats(fields, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(v) < 3 {
+		v = []float64{v[0], v[0], v[0]}
+	}
 
-			case "d": // disolve
-				v, err := strconv.ParseFloat(fields[1], 64)
-				if err != nil {
-					return nil, err
-				}
-				tmp.d = v
+	c := math.ColorFromRGB(v[0], v[1], v[2])
+	return &c, nil
+}
+
+// options followed by the file name, which may contain spaces
+func (p *mtlParser) texture(fields []string) (mtlMap, error) {
+	t := mtlMap{
+		scale: math.Vector{1, 1, 1},
+		bump:  1,
+	}
 
-			case "illum": // illumination model
-				v, err := strconv.ParseInt(fields[1], 10, 64)
-				if err != nil {
-					return nil, err
+	for len(fields) > 0 {
+		option := strings.ToLower(fields[0])
+		n, ok := mtlMapOptions[option]
+		if !ok {
+			break
+		}
+		fields = fields[1:]
+
+		var args []string
+		for len(args) < n && len(args) < len(fields) {
+			// -s, -o and -t take one to three numbers
+			if n == 3 && len(args) > 0 {
+				if _, err := strconv.ParseFloat(fields[len(args)], 64); err != nil {
+					break
 				}
-				tmp.illum = int(v)
+			}
+			args = append(args, fields[len(args)])
+		}
+		if len(args) == 0 {
+			return t, p.errorf("missing value of texture option %v", option)
+		}
+		fields = fields[len(args):]
 
-			case "map_kd": // diffuse texture map
-				tmp.map_kd = basePath + value
+		switch option {
+		case "-s", "-o":
+			v, err := p.floats(args, 1)
+			if err != nil {
+				return t, err
+			}
+			if option == "-s" {
+				copy(t.scale[:], v)
+			} else {
+				copy(t.offset[:], v)
+			}
 
-			case "#": // comment
-			case "": // empty line
-			default:
-				return nil, fmt.Errorf("unknown material line type: %s", line)
+		case "-clamp":
+			t.clamp = strings.ToLower(args[0]) == "on"
+
+		case "-bm":
+			v, err := p.floats(args, 1)
+			if err != nil {
+				return t, err
 			}
-		} else if err == io.EOF {
-			break
-		} else {
-			return nil, err
+			t.bump = v[0]
 		}
 	}
 
-	results := map[string]Material{}
+	if len(fields) == 0 {
+		return t, p.errorf("missing texture file name")
+	}
 
-	for n, i := range materials {
-		// invert transparency
-		//i.d = 1 - i.d
+	// exporters on windows write backslashes
+	t.path = filepath.FromSlash(strings.Replace(strings.Join(fields, " "), "\\", "/", -1))
+	return t, nil
+}
+
+// program of the illumination model, color only without lighting or phong shading
+func (i *mtlMaterial) program() string {
+	if i.illum == 0 {
+		return "basic"
+	}
+	return "phong"
+}
+
+type mtlTextureKey struct {
+	path  string
+	clamp bool
+}
 
-		m, err := NewMaterial("phong")
+// materials of a material file, textures are shared between them
+func loadMTL(path string) (map[string]*Material, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	infos, warnings, err := parseMTL(file, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		log.Printf("%v\n", w)
+	}
+
+	basePath := filepath.Dir(path) + string(filepath.Separator)
+	textures := map[mtlTextureKey]*ImageTexture{}
+	texture := func(t mtlMap) (*ImageTexture, error) {
+		key := mtlTextureKey{t.path, t.clamp}
+		if tx, ok := textures[key]; ok {
+			return tx, nil
+		}
+
+		p := t.path
+		if !filepath.IsAbs(p) {
+			p = basePath + p
+		}
+		tx, err := LoadTexture(p)
 		if err != nil {
 			return nil, err
 		}
+		if t.clamp {
+			tx.SetWrap(ClampToEdgeWrapping, ClampToEdgeWrapping)
+		}
 
-		// diffuse
-		if i.kd != nil {
-			//m.SetDiffuseColor(*i.kd)
-			m.SetUniform("diffuse", *i.kd)
+		textures[key] = tx
+		return tx, nil
+	}
+
+	results := make(map[string]*Material, len(infos))
+	unsupported := map[string]bool{}
+
+	for _, i := range infos {
+		mat, err := NewMaterial(i.program())
+		if err != nil {
+			return nil, err
 		}
 
-		// ambient
+		if i.kd != nil {
+			mat.SetUniform("diffuse", *i.kd)
+		}
 		if i.ka != nil {
-			//m.SetAmbientColor(*i.ka)
-			m.SetUniform("ambient", *i.ka)
-		} else if i.kd != nil {
-			//m.SetAmbientColor(m.DiffuseColor())
-			m.SetUniform("ambient", m.Uniform("ambient"))
+			mat.SetUniform("ambient", *i.ka)
+		}
+		if i.ke != nil {
+			mat.SetUniform("emissive", *i.ke)
 		}
 
-		// specular
-		if i.ks != nil {
-			//m.SetSpecularColor(*i.ks)
-			m.SetUniform("specular", *i.ks)
+		// highlights from illumination model 2 on
+		if i.ks != nil && i.illum >= 2 {
+			mat.SetUniform("specular", *i.ks)
 		}
+		if i.ns > 0 {
+			mat.SetUniform("shininess", i.ns)
+		}
+
+		mat.SetUniform("opacity", m.Max(0, m.Min(1, i.d)))
 
-		// diffuse texture map
-		if i.map_kd != "" {
-			tx, err := LoadTexture(i.map_kd)
+		for name, t := range i.maps {
+			if name != "map_kd" {
+				unsupported[name] = true
+				continue
+			}
+
+			tx, err := texture(t)
 			if err != nil {
 				return nil, err
 			}
-
-			//m.SetDiffuseMap(tx)
-			m.SetUniform("diffuseMap", tx)
+			mat.SetUniform("diffuseMap", tx)
+			mat.SetUniform("mapTransform", t.transform())
 		}
 
-		//m.SetShininess(i.ns) // specular exponent
-		m.SetUniform("shininess", i.ns)
-		//i.ni // optical density
-		//m.SetOpacity(i.d) // dissolve
-		m.SetUniform("opacity", i.d)
-		//i.illum // illumination model
+		results[i.name] = mat
+	}
 
-		results[n] = *m
+	for name := range unsupported {
+		log.Printf("%v: %v is not supported by the materials\n", path, name)
 	}
 
 	return results, nil
@@ -0,0 +1,331 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	m "math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+func TestParseOBJ(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		meshes   int
+		faces    int
+		vertices int
+		warnings int
+	}{
+		{"triangle", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n", 1, 1, 3, 0},
+		{"whitespace", "v\t0 0  0\n  v 1\t\t0 0\nv 0 1 0 \r\nf 1  2\t3", 1, 1, 3, 0},
+		{"no trailing newline", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3", 1, 1, 3, 0},
+		{"continuation", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 \\\n3\n", 1, 1, 3, 0},
+		{"comments", "# cube\nv 0 0 0\nv 1 0 0\nv 0 1 0 # top\nf 1 2 3\n", 1, 1, 3, 0},
+		{"quad", "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nvt 0 0\nvt 1 0\nvt 1 1\nvt 0 1\nf 1/1 2/2 3/3 4/4\n", 1, 2, 4, 0},
+		{"ngon", "v 0 0 0\nv 2 0 0\nv 2 1 0\nv 1 1 0\nv 1 2 0\nv 0 2 0\nf 1 2 3 4 5 6\n", 1, 4, 6, 0},
+		{"relative", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf -3 -2 -1\nv 1 1 0\nf -3 -1 -2\n", 1, 2, 4, 0},
+		{"shared", "v 0 0 0\nv 1 0 0\nv 0 1 0\nv 1 1 0\nf 1 2 3\nf 2 4 3\n", 1, 2, 4, 0},
+		{"materials", "v 0 0 0\nv 1 0 0\nv 0 1 0\nv 1 1 0\nusemtl a\nf 1 2 3\nusemtl b\nf 1 2 3\nusemtl b\nf 2 4 3\n", 2, 3, 7, 0},
+		{"groups", "v 0 0 0\nv 1 0 0\nv 0 1 0\no a\ng x\nf 1 2 3\ng y\nf 1 2 3\no b\nf 1 2 3\n", 3, 3, 9, 0},
+		{"unsupported", "v 0 0 0\nv 1 0 0\nv 0 1 0\nvp 0.5\nl 1 2\nl 2 3\np 1\nmg 1 0.5\nf 1 2 3\n", 1, 1, 3, 4},
+		{"unknown", "v 0 0 0\nv 1 0 0\nv 0 1 0\nfoo bar\nf 1 2 3\n", 1, 1, 3, 1},
+		{"degenerate", "v 0 0 0\nv 1 0 0\nf 1 2\n", 0, 0, 0, 1},
+	}
+
+	for _, c := range tests {
+		file, err := parseOBJ(strings.NewReader(c.data), "test.obj")
+		if err != nil {
+			t.Errorf("parseOBJ(%v) failed: %v", c.name, err)
+			continue
+		}
+
+		var faces, vertices int
+		for _, om := range file.meshes {
+			faces += len(om.geo.faces)
+			vertices += len(om.geo.vertices)
+		}
+
+		if len(file.meshes) != c.meshes || faces != c.faces || vertices != c.vertices {
+			t.Errorf("parseOBJ(%v) != %v meshes, %v faces, %v vertices (got %v, %v, %v)",
+				c.name, c.meshes, c.faces, c.vertices, len(file.meshes), faces, vertices)
+		}
+		if len(file.warnings) != c.warnings {
+			t.Errorf("parseOBJ(%v) != %v warnings (got %v)", c.name, c.warnings, file.warnings)
+		}
+	}
+}
+
+func TestParseOBJ_VertexLimit(t *testing.T) {
+	// tents of two perpendicular faces, the crease splits the shared vertices
+	var b bytes.Buffer
+	tents := objMaxVertices/4 - 1 // one mesh while parsing
+	for i := 0; i < tents; i++ {
+		x := float64(3 * i)
+		fmt.Fprintf(&b, "v %v 0 0\nv %v 0 1\nv %v 0 0.5\nv %v 1 0.5\n", x, x, x+1, x)
+		fmt.Fprintf(&b, "f %v %v %v\nf %v %v %v\n", 4*i+1, 4*i+2, 4*i+3, 4*i+2, 4*i+1, 4*i+4)
+	}
+
+	file, err := parseOBJ(&b, "test.obj")
+	if err != nil {
+		t.Fatalf("parseOBJ() failed: %v", err)
+	}
+
+	var faces, vertices int
+	for _, om := range file.meshes {
+		if len(om.geo.vertices) > objMaxVertices {
+			t.Errorf("parseOBJ() mesh with %v vertices exceeds the limit", len(om.geo.vertices))
+		}
+		faces += len(om.geo.faces)
+		vertices += len(om.geo.vertices)
+	}
+	if len(file.meshes) != 2 || faces != 2*tents || vertices != 6*tents {
+		t.Errorf("parseOBJ() != 2 meshes, %v faces, %v vertices (got %v, %v, %v)",
+			2*tents, 6*tents, len(file.meshes), faces, vertices)
+	}
+}
+
+func TestParseOBJ_Errors(t *testing.T) {
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"v 0 0 0\nv 1 0\n", "test.obj:2:"},
+		{"v 0 0 0\nv 1 0 x\n", "test.obj:2:"},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\n\nf 1 2 4\n", "test.obj:5:"},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 0 1 2\n", "test.obj:4:"},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf -4 -2 -1\n", "test.obj:4:"},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1/1 2/1 3/1\n", "test.obj:4:"},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nvn 0 0 1\nf 1//1 2//1 3//2\n", "test.obj:5:"},
+		{"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1/1/1/1 2 3\n", "test.obj:4:"},
+		{"s x\n", "test.obj:1:"},
+	}
+
+	for _, c := range tests {
+		_, err := parseOBJ(strings.NewReader(c.data), "test.obj")
+		if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+			t.Errorf("parseOBJ(%q) != %v (got %v)", c.data, c.err, err)
+		}
+	}
+}
+
+func TestParseOBJ_Attributes(t *testing.T) {
+	data := "v 0 0 0 1 0 0\nv 1 0 0\nv 0 1 0\nvt 0.25 0.75\nvn 0 0 -1\nf 1/1/1 2/1/1 3/1/1\n"
+
+	file, err := parseOBJ(strings.NewReader(data), "test.obj")
+	if err != nil {
+		t.Fatalf("parseOBJ failed: %v", err)
+	}
+
+	v := file.meshes[0].geo.vertices[0]
+	if v.color != (math.Color{1, 0, 0}) {
+		t.Errorf("color != %v (got %v)", math.Color{1, 0, 0}, v.color)
+	}
+	if v.uv.DistanceTo(math.Vector{0.25, 0.25}) > 1e-9 {
+		t.Errorf("uv != %v (got %v)", math.Vector{0.25, 0.25}, v.uv)
+	}
+	if v.normal.DistanceTo(math.Vector{0, 0, -1}) > 1e-9 {
+		t.Errorf("normal != %v (got %v)", math.Vector{0, 0, -1}, v.normal)
+	}
+	if c := file.meshes[0].geo.vertices[1].color; c != (math.Color{1, 1, 1}) {
+		t.Errorf("default color != %v (got %v)", math.Color{1, 1, 1}, c)
+	}
+}
+
+func TestParseOBJ_SmoothingGroups(t *testing.T) {
+	// two faces folded by 90 degrees along the x axis
+	const fold = "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nv 0 0 1\nv 1 0 1\n"
+
+	tests := []struct {
+		data     string
+		vertices int
+		normal   math.Vector // of the first vertex
+	}{
+		{fold + "s 1\nf 1 2 3 4\nf 1 5 6 2\n", 6, math.Vector{0, 1, 1}.Normalize()},
+		{fold + "s 1\nf 1 2 3 4\ns 2\nf 1 5 6 2\n", 8, math.Vector{0, 0, 1}},
+		{fold + "s off\nf 1 2 3 4\nf 1 5 6 2\n", 8, math.Vector{0, 0, 1}},
+		{fold + "f 1 2 3 4\nf 1 5 6 2\n", 8, math.Vector{0, 0, 1}}, // crease angle
+	}
+
+	for _, c := range tests {
+		file, err := parseOBJ(strings.NewReader(c.data), "test.obj")
+		if err != nil {
+			t.Errorf("parseOBJ(%q) failed: %v", c.data, err)
+			continue
+		}
+
+		geo := file.meshes[0].geo
+		if len(geo.vertices) != c.vertices {
+			t.Errorf("parseOBJ(%q) != %v vertices (got %v)", c.data, c.vertices, len(geo.vertices))
+		}
+		if n := geo.vertices[0].normal; n.DistanceTo(c.normal) > 1e-9 {
+			t.Errorf("parseOBJ(%q) != normal %v (got %v)", c.data, c.normal, n)
+		}
+	}
+}
+
+func TestTriangulatePolygon(t *testing.T) {
+	// concave l-shape with an area of 3 in every plane and winding
+	shape := []math.Vector{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}
+	planes := []func(p math.Vector) math.Vector{
+		func(p math.Vector) math.Vector { return math.Vector{p[0], p[1], 0} },
+		func(p math.Vector) math.Vector { return math.Vector{p[1], p[0], 0} },
+		func(p math.Vector) math.Vector { return math.Vector{0, p[0], p[1]} },
+		func(p math.Vector) math.Vector { return math.Vector{p[0], 3, p[1]} },
+		func(p math.Vector) math.Vector { return math.Vector{p[1], -1, p[0]} },
+	}
+
+	for i, plane := range planes {
+		points := make([]math.Vector, len(shape))
+		for j, p := range shape {
+			points[j] = plane(p)
+		}
+		a, b, c := points[0], points[1], points[5]
+		expected, _ := faceNormal(a, b, c)
+
+		faces := triangulatePolygon(points)
+		if len(faces) != 4 {
+			t.Errorf("triangulatePolygon(%v) != 4 faces (got %v)", i, len(faces))
+			continue
+		}
+
+		var area float64
+		for _, f := range faces {
+			n, a := faceNormal(points[f.A], points[f.B], points[f.C])
+			if n.DistanceTo(expected) > 1e-9 {
+				t.Errorf("triangulatePolygon(%v) face %v != normal %v (got %v)", i, f, expected, n)
+			}
+			area += a / 2
+		}
+		if area < 3-1e-9 || area > 3+1e-9 {
+			t.Errorf("triangulatePolygon(%v) != area 3 (got %v)", i, area)
+		}
+	}
+}
+
+func TestParseMTL(t *testing.T) {
+	data := `# Blender MTL File
+newmtl plain
+Ns 96.078431
+Ka 0.000000 0.000000 0.000000
+Kd 0.640000	0.640000  0.640000
+Ks 0.5
+Ke 0.1 0.2 0.3
+Ni 1.450000
+d 0.75
+illum 1
+
+newmtl textured glass
+Tr 0.25
+illum 0
+Kd spectral glass.rfl
+map_Kd -s 2 2 -o 0.5 0.25 0 -clamp on textures\wood grain.png
+map_Bump -bm 0.5 normal.png
+bump other.png
+map_Pr roughness.png
+Pr 0.5
+`
+
+	materials, warnings, err := parseMTL(strings.NewReader(data), "test.mtl")
+	if err != nil {
+		t.Fatalf("parseMTL failed: %v", err)
+	}
+	if len(materials) != 2 {
+		t.Fatalf("parseMTL != 2 materials (got %v)", len(materials))
+	}
+	if len(warnings) != 3 {
+		t.Errorf("parseMTL != 3 warnings (got %v)", warnings)
+	}
+
+	plain := materials[0]
+	tests := []struct {
+		name     string
+		got      interface{}
+		expected interface{}
+	}{
+		{"name", plain.name, "plain"},
+		{"ns", plain.ns, 96.078431},
+		{"ka", *plain.ka, math.Color{0, 0, 0}},
+		{"kd", *plain.kd, math.Color{0.64, 0.64, 0.64}},
+		{"ks", *plain.ks, math.Color{0.5, 0.5, 0.5}},
+		{"ke", *plain.ke, math.Color{0.1, 0.2, 0.3}},
+		{"ni", plain.ni, 1.45},
+		{"d", plain.d, 0.75},
+		{"illum", plain.illum, 1},
+		{"program", plain.program(), "phong"},
+	}
+
+	glass := materials[1]
+	tests = append(tests, []struct {
+		name     string
+		got      interface{}
+		expected interface{}
+	}{
+		{"name", glass.name, "textured glass"},
+		{"tr", glass.d, 0.75},
+		{"spectral", glass.kd == nil, true},
+		{"program", glass.program(), "basic"},
+		{"maps", len(glass.maps), 2},
+		{"map_kd", glass.maps["map_kd"].path, filepath.FromSlash("textures/wood grain.png")},
+		{"map_kd -s", glass.maps["map_kd"].scale, math.Vector{2, 2, 1}},
+		{"map_kd -o", glass.maps["map_kd"].offset, math.Vector{0.5, 0.25, 0}},
+		{"map_kd -clamp", glass.maps["map_kd"].clamp, true},
+		{"bump", glass.maps["map_bump"].path, "other.png"},
+	}...)
+
+	for _, c := range tests {
+		if c.got != c.expected {
+			t.Errorf("parseMTL(%v) != %v (got %v)", c.name, c.expected, c.got)
+		}
+	}
+}
+
+func TestParseMTL_Errors(t *testing.T) {
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"Kd 1 1 1\n", "test.mtl:1:"},
+		{"newmtl a\n\nKd 1 x 1\n", "test.mtl:3:"},
+		{"newmtl a\nd\n", "test.mtl:2:"},
+		{"newmtl a\nillum 11\n", "test.mtl:2:"},
+		{"newmtl a\nmap_Kd -s 2 2\n", "test.mtl:2:"},
+		{"newmtl a\nmap_Kd -clamp\n", "test.mtl:2:"},
+	}
+
+	for _, c := range tests {
+		_, _, err := parseMTL(strings.NewReader(c.data), "test.mtl")
+		if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+			t.Errorf("parseMTL(%q) != %v (got %v)", c.data, c.err, err)
+		}
+	}
+}
+
+func TestMtlMap_Transform(t *testing.T) {
+	tests := []struct {
+		scale, offset math.Vector
+		uv            math.Vector // in file space
+	}{
+		{math.Vector{1, 1, 1}, math.Vector{}, math.Vector{0.25, 0.75}},
+		{math.Vector{2, 2, 1}, math.Vector{}, math.Vector{0.25, 0.75}},
+		{math.Vector{1, 1, 1}, math.Vector{0.5, 0.25}, math.Vector{0.25, 0.75}},
+		{math.Vector{2, 4, 1}, math.Vector{0.1, 0.3}, math.Vector{0.6, 0.2}},
+	}
+
+	for _, c := range tests {
+		tr := mtlMap{scale: c.scale, offset: c.offset}.transform()
+
+		// lookup in file space, flipped like the loaded uvs
+		expected := 1 - (c.uv[1]*c.scale[1] + c.offset[1])
+		if got := (1-c.uv[1])*tr[1] + tr[3]; m.Abs(got-expected) > 1e-9 {
+			t.Errorf("mtlMap{%v, %v}.transform() v != %v (got %v)", c.scale, c.offset, expected, got)
+		}
+		expected = c.uv[0]*c.scale[0] + c.offset[0]
+		if got := c.uv[0]*tr[0] + tr[2]; m.Abs(got-expected) > 1e-9 {
+			t.Errorf("mtlMap{%v, %v}.transform() u != %v (got %v)", c.scale, c.offset, expected, got)
+		}
+	}
+}
@@ -83,6 +83,7 @@ func init() {
 				uniform vec3 diffuse;
 				uniform float opacity;
 				uniform sampler2D diffuseMap;
+				uniform vec4 mapTransform; // uv scale xy, offset zw
 
 				// Output data
 				out vec4 fragmentColor;
@@ -91,7 +92,7 @@ func init() {
 				{
 					fragmentColor = vec4( diffuse, opacity );
 
-					vec4 texelColor = texture( diffuseMap, UV );
+					vec4 texelColor = texture( diffuseMap, UV * mapTransform.xy + mapTransform.zw );
 					fragmentColor = fragmentColor * texelColor;
 
 					fragmentColor = fragmentColor * vec4( Color, opacity );
@@ -103,9 +104,10 @@ func init() {
 				"modelViewMatrix":  nil, //[16]float32{},
 				"normalMatrix":     nil, //[9]float32{}, // matrix.Matrix3Float32()
 
-				"diffuseMap": nil, // texture
-				"opacity":    1.0,
-				"diffuse":    math.Color{1, 1, 1},
+				"diffuseMap":   nil, // texture
+				"mapTransform": math.Vector{1, 1, 0, 0},
+				"opacity":      1.0,
+				"diffuse":      math.Color{1, 1, 1},
 			},
 			attributes: map[string]uint{
 				"vertexPosition": 3,
@@ -137,7 +139,7 @@ func init() {
 				out vec3 Color;
 
 				out vec3 Position; //Position_worldspace
-				//out vec3 eyeDir;   //EyeDirection_cameraspace 
+				out vec3 eyeDir;   //EyeDirection_cameraspace 
 				out vec3 lightDir; //LightDirection_cameraspace 
 				out vec3 Normal;   //Normal_cameraspace 
 
@@ -151,7 +153,7 @@ func init() {
 
 					// Direction from vertex to camera, cameraspace
 					//EyeDirection_cameraspace 
-					vec3 eyeDir = vec3(0.0, 0.0, 0.0) - (viewMatrix * modelMatrix * vec4(vertexPosition, 1.0)).xyz;
+					eyeDir = vec3(0.0, 0.0, 0.0) - (viewMatrix * modelMatrix * vec4(vertexPosition, 1.0)).xyz;
 
 					// Direction from vertex to light, cameraspace
 					//LightPosition_worldspace
@@ -177,7 +179,7 @@ func init() {
 				in vec3 Color;
 
 				in vec3 Position; //Position_worldspace
-				//in vec3 eyeDir;   //EyeDirection_cameraspace 
+				in vec3 eyeDir;   //EyeDirection_cameraspace 
 				in vec3 lightDir; //LightDirection_cameraspace 
 				in vec3 Normal;   //Normal_cameraspace 
 
@@ -186,6 +188,11 @@ func init() {
 				uniform vec3 diffuse;
 				uniform float opacity;
 				uniform sampler2D diffuseMap;
+				uniform vec4 mapTransform; // uv scale xy, offset zw
+				uniform vec3 ambient;
+				uniform vec3 emissive;
+				uniform vec3 specular;
+				uniform float shininess;
 
 				// Output data
 				out vec4 fragmentColor;
@@ -211,12 +218,12 @@ func init() {
 					float cosTheta = clamp(dot(n, l), 0, 1);
 					
 					// Cosine of the angle between the Eye vector and the Reflect vector,
-					//float cosAlpha = clamp(dot(normalize(eyeDir), reflect(-l, n)), 0, 1);
+					float cosAlpha = clamp(dot(normalize(eyeDir), reflect(-l, n)), 0, 1);
 
 					// Material properties
-					vec3 materialDiffuseColor = texture(diffuseMap, UV).rgb;
-					vec3 materialAmbientColor = vec3(0.5, 0.5, 0.5) * materialDiffuseColor;
-					//vec3 materialSpecularColor = vec3(0.3, 0.3, 0.3);
+					vec3 materialDiffuseColor = diffuse * texture(diffuseMap, UV * mapTransform.xy + mapTransform.zw).rgb;
+					vec3 materialAmbientColor = vec3(0.5, 0.5, 0.5) * ambient * materialDiffuseColor; // ambient light 0.5
+					vec3 materialSpecularColor = specular;
 
 					// Combine colors
 					//fragmentColor = vec4(diffuse, opacity);
@@ -224,9 +231,10 @@ func init() {
 					//fragmentColor = fragmentColor * vec4( Color, opacity );
 
 					fragmentColor = vec4( 
+							emissive +
 							materialAmbientColor +
-							materialDiffuseColor * lightColor * lightPower * cosTheta / (distance * distance) /*+
-							materialSpecularColor * lightColor * lightPower * pow(cosAlpha, 5.0) / (distance * distance)*/,
+							materialDiffuseColor * lightColor * lightPower * cosTheta / (distance * distance) +
+							materialSpecularColor * lightColor * lightPower * pow(cosAlpha, shininess) / (distance * distance),
 						opacity);
 				}`,
 			uniforms: map[string]interface{}{
@@ -236,13 +244,15 @@ func init() {
 				"modelViewMatrix":  nil, //[16]float32{},
 				"normalMatrix":     nil, //[9]float32{}, // matrix.Matrix3Float32()
 
-				"diffuseMap": nil, // texture
-				"opacity":    1.0,
-				"diffuse":    math.Color{1, 1, 1},
+				"diffuseMap":   nil, // texture
+				"mapTransform": math.Vector{1, 1, 0, 0},
+				"opacity":      1.0,
+				"diffuse":      math.Color{1, 1, 1},
 
-				"ambient":  math.Color{1, 1, 1},
-				"emissive": math.Color{1, 1, 1},
-				"specular": math.Color{1, 1, 1},
+				"ambient":   math.Color{1, 1, 1},
+				"emissive":  math.Color{0, 0, 0},
+				"specular":  math.Color{0, 0, 0},
+				"shininess": 30.0,
 			},
 			attributes: map[string]uint{
 				"vertexPosition": 3,
@@ -407,6 +417,86 @@ func init() {
 				"vertexColor":    3,
 			},
 		},
+		"tonemap": {
+			vertex: `
+				#version 330 core
+
+				// Input vertex data, different for all executions of this shader.
+				in vec3 vertexPosition;
+				in vec3 vertexNormal;
+				in vec2 vertexUV;
+				in vec2 vertexUV2;
+				in vec3 vertexColor;
+
+				// Values that stay constant for the whole mesh.
+				uniform mat4 projectionMatrix;
+				uniform mat4 viewMatrix;
+				uniform mat4 modelMatrix;
+				uniform mat4 modelViewMatrix;
+				uniform mat3 normalMatrix;
+
+				// Output data, will be interpolated for each fragment.
+				out vec2 UV;
+
+				void main(){
+					// Output position of the vertex, clipspace
+					gl_Position = projectionMatrix * modelViewMatrix * vec4(vertexPosition, 1.0);
+
+					// UV of the vertex
+					UV = vertexUV;
+				}`,
+			fragment: `
+				#version 330 core
+
+				// Interpolated values from the vertex shaders
+				in vec2 UV;
+
+				// Values that stay constant for the whole mesh.
+				uniform float exposure;
+				uniform int toneMapping;
+				uniform sampler2D diffuseMap;
+
+				// Output data
+				out vec4 fragmentColor;
+
+				// https://knarkowicz.wordpress.com/2016/01/06/aces-filmic-tone-mapping-curve/
+				vec3 aces(vec3 x) {
+					return clamp((x * (2.51 * x + 0.03)) / (x * (2.43 * x + 0.59) + 0.14), 0.0, 1.0);
+				}
+
+				void main()
+				{
+					vec4 hdr = texture(diffuseMap, UV);
+					vec3 color = hdr.rgb * exposure;
+
+					if (toneMapping == 1) {
+						color = color / (1.0 + color); // reinhard
+					} else if (toneMapping == 2) {
+						color = aces(color);
+					} else {
+						color = clamp(color, 0.0, 1.0);
+					}
+
+					fragmentColor = vec4(color, hdr.a);
+				}`,
+			uniforms: map[string]interface{}{
+				"projectionMatrix": nil, //[16]float32{}, // matrix.Float32()
+				"viewMatrix":       nil, //[16]float32{},
+				"modelMatrix":      nil, //[16]float32{},
+				"modelViewMatrix":  nil, //[16]float32{},
+				"normalMatrix":     nil, //[9]float32{}, // matrix.Matrix3Float32()
+
+				"diffuseMap":  nil, // hdr rendertarget
+				"exposure":    1.0,
+				"toneMapping": ToneMappingACES,
+			},
+			attributes: map[string]uint{
+				"vertexPosition": 3,
+				"vertexNormal":   3,
+				"vertexUV":       2,
+				"vertexColor":    3,
+			},
+		},
 		"blend": {
 			vertex: `
 				#version 330 core
@@ -493,6 +583,7 @@ func init() {
 
 				// Output data, will be interpolated for each fragment.
 				out vec2 UV;
+				out vec3 Color;
 
 				void main(){
 					// Output position of the vertex, clipspace
@@ -500,26 +591,78 @@ func init() {
 
 					// UV of the vertex
 					UV = vertexUV;
+
+					// per character color
+					Color = vertexColor;
 				}`,
 			fragment: `
 				#version 330 core
 
 				// Interpolated values from the vertex shaders
 				in vec2 UV;
+				in vec3 Color;
 
 				// Values that stay constant for the whole mesh.
 				uniform vec3 diffuse;
 				uniform float smoothing;
 				uniform sampler2D distanceFieldMap;
+				uniform float spread; // pixels of the distance range
+
+				uniform vec3 outlineColor;
+				uniform float outlineWidth; // pixels
+
+				uniform vec3 shadowColor;
+				uniform float shadowOpacity;
+				uniform vec4 shadowOffset; // pixels, y down
+				uniform float shadowSoftness; // pixels
+
+				uniform vec3 glowColor;
+				uniform float glowOpacity;
+				uniform float glowWidth; // pixels
 
 				// Output data
 				out vec4 fragmentColor;
 
+				// coverage of the shape grown by width pixels
+				float coverage(float distance, float width)
+				{
+					float edge = 0.5 - width / (2.0 * spread);
+					return smoothstep(edge - smoothing, edge + smoothing, distance);
+				}
+
+				vec4 over(vec4 top, vec4 bottom)
+				{
+					float a = top.a + bottom.a * (1.0 - top.a);
+					if (a <= 0.0) {
+						return vec4(0.0);
+					}
+					return vec4((top.rgb * top.a + bottom.rgb * bottom.a * (1.0 - top.a)) / a, a);
+				}
+
 				void main()
 				{
 					float distance = texture(distanceFieldMap, UV).a;
-					float opacity = smoothstep(0.5 - smoothing, 0.5 + smoothing, distance);
-					fragmentColor = vec4(diffuse, opacity);
+
+					// fill and outline
+					float fill = coverage(distance, 0.0);
+					vec4 color = vec4(mix(outlineColor, diffuse * Color, fill), coverage(distance, outlineWidth));
+
+					// glow fading out over its width
+					if (glowOpacity > 0.0 && glowWidth > 0.0) {
+						float glow = clamp((distance - 0.5) * 2.0 * spread / glowWidth + 1.0, 0.0, 1.0);
+						color = over(color, vec4(glowColor, glow * glowOpacity));
+					}
+
+					// shadow of the outlined shape
+					if (shadowOpacity > 0.0) {
+						vec2 offset = shadowOffset.xy / vec2(textureSize(distanceFieldMap, 0));
+						float shadowDistance = texture(distanceFieldMap, UV - offset).a;
+						float shadowEdge = 0.5 - (outlineWidth + shadowSoftness) / (2.0 * spread);
+						float shadow = smoothstep(shadowEdge - smoothing, 0.5 - outlineWidth / (2.0 * spread) + smoothing, shadowDistance);
+						color = over(color, vec4(shadowColor, shadow * shadowOpacity));
+					}
+
+					fragmentColor = color;
 				}`,
 			uniforms: map[string]interface{}{
 				"projectionMatrix": nil, //[16]float32{}, // matrix.Float32()
@@ -531,6 +674,19 @@ func init() {
 				"distanceFieldMap": nil, // texture
 				"smoothing":        0.25,
 				"diffuse":          math.Color{1, 1, 1},
+				"spread":           4.0,
+
+				"outlineColor": math.Color{0, 0, 0},
+				"outlineWidth": 0.0,
+
+				"shadowColor":    math.Color{0, 0, 0},
+				"shadowOpacity":  0.0,
+				"shadowOffset":   math.Vector{},
+				"shadowSoftness": 0.0,
+
+				"glowColor":   math.Color{1, 1, 1},
+				"glowOpacity": 0.0,
+				"glowWidth":   0.0,
 			},
 			attributes: map[string]uint{
 				"vertexPosition": 3,
@@ -630,7 +786,17 @@ func init() {
 	}
 }
 
+// operator of the tonemap shader, maps hdr colors to the displayable range
+type ToneMapping int
+
+const (
+	ToneMappingLinear   ToneMapping = iota // clamp
+	ToneMappingReinhard                    // c / (1 + c)
+	ToneMappingACES                        // filmic curve
+)
+
 type Material struct {
+	shader     string // name in the library
 	program    *program
 	wireframe  bool
 	opaque     bool
@@ -707,6 +873,7 @@ func NewMaterial(name string) (*Material, error) {
 
 	// new material
 	mat := &Material{
+		shader:     name,
 		program:    prg,
 		uniforms:   make(map[string]interface{}),
 		attributes: make(map[string]uint),
@@ -724,6 +891,32 @@ func NewMaterial(name string) (*Material, error) {
 	return mat, nil
 }
 
+// material of the same program with its own uniform values, textures are shared
+func (m *Material) Clone() *Material {
+	mat := &Material{
+		shader:     m.shader,
+		program:    m.program,
+		wireframe:  m.wireframe,
+		opaque:     m.opaque,
+		uniforms:   make(map[string]interface{}, len(m.uniforms)),
+		attributes: make(map[string]uint, len(m.attributes)),
+	}
+
+	for n, v := range m.uniforms {
+		mat.uniforms[n] = v
+	}
+	for n, v := range m.attributes {
+		mat.attributes[n] = v
+	}
+
+	return mat
+}
+
+// library name of the shader program
+func (m *Material) Shader() string {
+	return m.shader
+}
+
 func (m *Material) SetWireframe(b bool) {
 	m.wireframe = b
 }
@@ -783,6 +976,11 @@ func (m *Material) DisableAttributes() {
 	}
 }
 
+func (m *Material) HasAttribute(name string) bool {
+	_, ok := m.attributes[name]
+	return ok
+}
+
 func (m *Material) EnableAttribute(name string) {
 	if _, ok := m.attributes[name]; !ok {
 		//return err
@@ -810,7 +1008,13 @@ func (m *Material) Uniform(name string) interface{} {
 	return m.uniforms[name]
 }
 
+// uploads all uniforms as given
 func (m *Material) UpdateUniforms() /*error*/ {
+	m.updateUniforms(false)
+}
+
+// colors are given gamma encoded and converted to linear for a renderer with srgb output
+func (m *Material) updateUniforms(linear bool) {
 	var usedTextureUnits int
 
 	for n, v := range m.uniforms {
@@ -826,6 +1030,14 @@ func (m *Material) UpdateUniforms() /*error*/ {
 
 		case nil: // ignore nil
 
+		case math.Color:
+			if linear {
+				t = t.Linear()
+			}
+			if err := m.UpdateUniform(n, t); err != nil {
+				panic(err.Error())
+			}
+
 		default:
 			if err := m.UpdateUniform(n, v); err != nil {
 				//return err
@@ -833,14 +1045,14 @@ func (m *Material) UpdateUniforms() /*error*/ {
 			}
 		}
 	}
-
-	return //nil
 }
 
 func (m *Material) UpdateUniform(name string, value interface{}) error {
 	switch t := value.(type) {
 	case int:
 		m.program.uniforms[name].Uniform1i(t)
+	case ToneMapping:
+		m.program.uniforms[name].Uniform1i(int(t))
 	case float64:
 		m.program.uniforms[name].Uniform1f(float32(t))
 	case float32:
@@ -853,6 +1065,8 @@ func (m *Material) UpdateUniform(name string, value interface{}) error {
 
 	case math.Color:
 		m.program.uniforms[name].Uniform3f(float32(t.R), float32(t.G), float32(t.B))
+	case math.Vector:
+		m.program.uniforms[name].Uniform4f(float32(t[0]), float32(t[1]), float32(t[2]), float32(t[3]))
 
 	case bool:
 		if t {
@@ -17,8 +17,9 @@ type Renderable interface {
 type Mesh struct {
 	Renderable
 
-	geometry *Geometry
-	material *Material
+	geometry      *Geometry
+	material      *Material
+	boundsVersion int // geometry boundary the scene proxy was fitted to
 
 	// 3d
 	position math.Vector
@@ -60,6 +61,19 @@ func (m *Mesh) Geometry() *Geometry {
 
 func (m *Mesh) SetGeometry(g *Geometry) {
 	m.geometry = g
+	if g != nil {
+		m.boundsVersion = g.boundsVersion
+	}
+
+	// backward search for root
+	var root, parent Object
+	for parent = m; parent != nil; parent = parent.Parent() {
+		root = parent
+	}
+
+	if scene, ok := root.(*Scene); ok {
+		scene.UpdateObject(m)
+	}
 }
 
 func (m *Mesh) Material() *Material {
@@ -120,15 +134,33 @@ func (o *Mesh) Matrix() math.Matrix {
 func (o *Mesh) UpdateMatrixWorld(force bool) {
 	m := o.Matrix()
 
-	if o.matrixWorldNeedsUpdate || force {
-		if p := o.Parent(); p == nil {
-			o.matrixWorld = m
-		} else {
-			o.matrixWorld = p.MatrixWorld().Mul(m)
+	// positions changed since the last refit
+	boundsChanged := o.geometry != nil && o.geometry.boundsVersion != o.boundsVersion
+
+	if o.matrixWorldNeedsUpdate || force || boundsChanged {
+		if o.matrixWorldNeedsUpdate || force {
+			if p := o.Parent(); p == nil {
+				o.matrixWorld = m
+			} else {
+				o.matrixWorld = p.MatrixWorld().Mul(m)
+			}
+
+			o.matrixWorldNeedsUpdate = false
+			force = true
+		}
+		if o.geometry != nil {
+			o.boundsVersion = o.geometry.boundsVersion
 		}
 
-		o.matrixWorldNeedsUpdate = false
-		force = true
+		// backward search for root
+		var root, parent Object
+		for parent = o; parent != nil; parent = parent.Parent() {
+			root = parent
+		}
+
+		if scene, ok := root.(*Scene); ok {
+			scene.UpdateObject(o)
+		}
 	}
 
 	for _, c := range o.Children() {
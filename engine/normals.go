@@ -0,0 +1,206 @@
+package engine
+
+import (
+	m "math"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// normal of the triangle and twice its area
+func faceNormal(a, b, c math.Vector) (math.Vector, float64) {
+	n := b.Sub(a).Cross(c.Sub(a))
+	n[3] = 0
+
+	l := n.Length()
+	if l == 0 {
+		return n, 0
+	}
+	return n.MulScalar(1 / l), l
+}
+
+// angle at corner a
+func cornerAngle(a, b, c math.Vector) float64 {
+	e1, e2 := b.Sub(a), c.Sub(a)
+	e1[3], e2[3] = 0, 0
+
+	l := e1.Length() * e2.Length()
+	if l == 0 {
+		return 0
+	}
+	return m.Acos(m.Max(-1, m.Min(1, e1.Dot(e2)/l)))
+}
+
+// flat shading, vertices shared between faces are duplicated
+func (g *Geometry) ComputeFaceNormals() {
+	vertices := make([]Vertex, 0, len(g.faces)*3)
+	lines := make([]Line, 0, len(g.faces)*3)
+
+	for i, f := range g.faces {
+		a, b, c := g.vertices[f.A], g.vertices[f.B], g.vertices[f.C]
+		n, _ := faceNormal(a.position, b.position, c.position)
+		a.normal, b.normal, c.normal = n, n, n
+
+		o := len(vertices)
+		vertices = append(vertices, a, b, c)
+		g.faces[i] = Face{o, o + 1, o + 2}
+		lines = append(lines, g.faces[i].ToLines()...)
+	}
+
+	g.vertices = vertices
+	g.lines = lines
+	g.needsUpdate = true
+}
+
+// smooth normals weighted by face area and corner angle, faces meeting at an angle
+// above crease keep a hard edge and get separate vertices, crease >= Pi smoothes everything
+func (g *Geometry) ComputeVertexNormals(crease float64) {
+	type corner struct {
+		face, index int
+		weight      float64
+	}
+
+	// face normals and corner weights
+	normals := make([]math.Vector, len(g.faces))
+	corners := make(map[math.Vector][]corner) // by position
+	var positions []math.Vector               // in order of appearance
+
+	for i, f := range g.faces {
+		idx := [3]int{f.A, f.B, f.C}
+		p := [3]math.Vector{g.vertices[f.A].position, g.vertices[f.B].position, g.vertices[f.C].position}
+
+		n, area := faceNormal(p[0], p[1], p[2])
+		normals[i] = n
+
+		for j := 0; j < 3; j++ {
+			w := area * cornerAngle(p[j], p[(j+1)%3], p[(j+2)%3])
+			if _, found := corners[p[j]]; !found {
+				positions = append(positions, p[j])
+			}
+			corners[p[j]] = append(corners[p[j]], corner{i, idx[j], w})
+		}
+	}
+
+	threshold := m.Cos(crease)
+	smooth := crease >= m.Pi
+
+	// split vertices with differing normals
+	assigned := make([]bool, len(g.vertices))
+	splits := make(map[int][]int) // vertex index, copies
+
+	for _, p := range positions {
+		cs := corners[p]
+		for _, c := range cs {
+			var sum math.Vector
+			for _, o := range cs {
+				if smooth || normals[c.face].Dot(normals[o.face]) >= threshold {
+					sum = sum.Add(normals[o.face].MulScalar(o.weight))
+				}
+			}
+			n := sum.Normalize()
+
+			index := c.index
+			switch {
+			case !assigned[index]:
+				assigned[index] = true
+				g.vertices[index].normal = n
+
+			case !g.vertices[index].normal.Equals(n, 6):
+				found := false
+				for _, s := range splits[c.index] {
+					if g.vertices[s].normal.Equals(n, 6) {
+						index, found = s, true
+						break
+					}
+				}
+
+				if !found {
+					v := g.vertices[c.index]
+					v.normal = n
+					g.vertices = append(g.vertices, v)
+					index = len(g.vertices) - 1
+					splits[c.index] = append(splits[c.index], index)
+				}
+			}
+
+			f := &g.faces[c.face]
+			switch {
+			case f.A == c.index:
+				f.A = index
+			case f.B == c.index:
+				f.B = index
+			default:
+				f.C = index
+			}
+		}
+	}
+
+	if len(splits) > 0 {
+		g.lines = g.lines[:0]
+		for _, f := range g.faces {
+			g.lines = append(g.lines, f.ToLines()...)
+		}
+	}
+
+	g.needsUpdate = true
+}
+
+// per vertex tangents in uv direction for normal mapping, angle weighted and
+// orthogonalized against the normal like MikkTSpace, w holds the handedness of the bitangent
+func (g *Geometry) ComputeTangents() {
+	tangents := make([]math.Vector, len(g.vertices))
+	bitangents := make([]math.Vector, len(g.vertices))
+
+	for _, f := range g.faces {
+		idx := [3]int{f.A, f.B, f.C}
+		a, b, c := g.vertices[f.A], g.vertices[f.B], g.vertices[f.C]
+
+		e1, e2 := b.position.Sub(a.position), c.position.Sub(a.position)
+		du1, dv1 := b.uv[0]-a.uv[0], b.uv[1]-a.uv[1]
+		du2, dv2 := c.uv[0]-a.uv[0], c.uv[1]-a.uv[1]
+
+		det := du1*dv2 - du2*dv1
+		if det == 0 {
+			continue // degenerated uv mapping
+		}
+		r := 1 / det
+
+		t := e1.MulScalar(dv2 * r).Sub(e2.MulScalar(dv1 * r))
+		bt := e2.MulScalar(du1 * r).Sub(e1.MulScalar(du2 * r))
+		t[3], bt[3] = 0, 0
+		t, bt = t.Normalize(), bt.Normalize()
+
+		p := [3]math.Vector{a.position, b.position, c.position}
+		for j, i := range idx {
+			w := cornerAngle(p[j], p[(j+1)%3], p[(j+2)%3])
+			tangents[i] = tangents[i].Add(t.MulScalar(w))
+			bitangents[i] = bitangents[i].Add(bt.MulScalar(w))
+		}
+	}
+
+	for i := range g.vertices {
+		n := g.vertices[i].normal
+		n[3] = 0
+		t := tangents[i]
+
+		// gram-schmidt
+		t = t.Sub(n.MulScalar(n.Dot(t))).Normalize()
+		if t.Length() == 0 {
+			// any perpendicular
+			t = n.Cross(math.Vector{1, 0, 0})
+			if t.Length() < 1e-6 {
+				t = n.Cross(math.Vector{0, 1, 0})
+			}
+			t = t.Normalize()
+		}
+
+		t[3] = 1
+		if n.Cross(t).Dot(bitangents[i]) < 0 {
+			t[3] = -1
+		}
+
+		g.vertices[i].tangent = t
+	}
+
+	g.hasTangents = true
+	g.needsUpdate = true
+}
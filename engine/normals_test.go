@@ -0,0 +1,94 @@
+package engine
+
+import (
+	m "math"
+	"testing"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+func testCube(t *testing.T) *Geometry {
+	positions := []math.Vector{
+		{-1, -1, -1}, {1, -1, -1}, {1, 1, -1}, {-1, 1, -1},
+		{-1, -1, 1}, {1, -1, 1}, {1, 1, 1}, {-1, 1, 1},
+	}
+	indices := []int{
+		0, 2, 1, 0, 3, 2, // back
+		4, 5, 6, 4, 6, 7, // front
+		0, 1, 5, 0, 5, 4, // bottom
+		3, 7, 6, 3, 6, 2, // top
+		0, 4, 7, 0, 7, 3, // left
+		1, 2, 6, 1, 6, 5, // right
+	}
+
+	geo, err := NewIndexedGeometry(positions, nil, nil, nil, indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return geo
+}
+
+func TestGeometry_ComputeVertexNormals(t *testing.T) {
+	tests := []struct {
+		crease   float64
+		vertices int
+		normal   math.Vector // of the vertex at 1,1,1 in the front face
+	}{
+		{m.Pi, 8, math.Vector{1, 1, 1}.Normalize()},
+		{m.Pi / 3, 24, math.Vector{0, 0, 1}},
+	}
+
+	for _, c := range tests {
+		geo := testCube(t)
+		geo.ComputeVertexNormals(c.crease)
+
+		if n := geo.VerticesCount(); n != c.vertices {
+			t.Errorf("ComputeVertexNormals(%v) vertices != %v (got %v)", c.crease, c.vertices, n)
+		}
+
+		front := geo.faces[2]
+		for _, i := range []int{front.A, front.B, front.C} {
+			v := geo.vertices[i]
+			if v.position.Equals(math.Vector{1, 1, 1}, 6) && !v.normal.Equals(c.normal, 6) {
+				t.Errorf("ComputeVertexNormals(%v) normal != %v (got %v)", c.crease, c.normal, v.normal)
+			}
+		}
+	}
+
+	geo := testCube(t)
+	geo.ComputeFaceNormals()
+	if n := geo.VerticesCount(); n != 36 {
+		t.Errorf("ComputeFaceNormals() vertices != 36 (got %v)", n)
+	}
+	if n := geo.vertices[geo.faces[4].A].normal; !n.Equals(math.Vector{0, -1, 0}, 6) {
+		t.Errorf("ComputeFaceNormals() bottom normal != 0,-1,0 (got %v)", n)
+	}
+}
+
+func TestGeometry_ComputeTangents(t *testing.T) {
+	positions := []math.Vector{{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0}}
+	normals := []math.Vector{{0, 0, 1}, {0, 0, 1}, {0, 0, 1}, {0, 0, 1}}
+
+	tests := []struct {
+		uvs     []math.Vector
+		tangent math.Vector
+	}{
+		{[]math.Vector{{0, 0}, {1, 0}, {1, 1}, {0, 1}}, math.Vector{1, 0, 0, 1}},
+		{[]math.Vector{{1, 0}, {0, 0}, {0, 1}, {1, 1}}, math.Vector{-1, 0, 0, -1}},  // mirrored
+		{[]math.Vector{{0, 0}, {0, 1}, {-1, 1}, {-1, 0}}, math.Vector{0, -1, 0, 1}}, // rotated
+	}
+
+	for _, c := range tests {
+		geo, err := NewIndexedGeometry(positions, normals, c.uvs, nil, []int{0, 1, 2, 2, 3, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		geo.ComputeTangents()
+
+		for i, v := range geo.vertices {
+			if !v.tangent.Equals(c.tangent, 6) {
+				t.Errorf("ComputeTangents() vertex %v != %v (got %v)", i, c.tangent, v.tangent)
+			}
+		}
+	}
+}
@@ -17,6 +17,11 @@ type Renderer struct {
 	width  int
 	height int
 	window *glfw.Window
+	srgb   bool
+
+	// gamma encoded, converted for srgb output
+	clearColor math.Color
+	clearAlpha float64
 
 	// state cache
 	currentMaterial     *Material // if mat != current, reset uniforms
@@ -34,6 +39,9 @@ type Renderer struct {
 
 	// renderpasses
 	passes []*RenderPass
+
+	// frame sequence capture
+	capture *frameCapture
 }
 
 func NewRenderer(title string, width, height int) (*Renderer, error) {
@@ -41,6 +49,7 @@ func NewRenderer(title string, width, height int) (*Renderer, error) {
 		title:  title,
 		width:  width,
 		height: height,
+		srgb:   true,
 	}
 
 	// initialize glfw
@@ -71,6 +80,7 @@ func (r *Renderer) initGLFW() error {
 	// create window
 	glfw.WindowHint(glfw.Resizable, 1)
 	glfw.WindowHint(glfw.Samples, 4)
+	glfw.WindowHint(glfw.SRGBCapable, 1)
 
 	window, err := glfw.CreateWindow(r.width, r.height, r.title, nil, nil)
 	if err != nil {
@@ -117,6 +127,9 @@ func (r *Renderer) initGL() error {
 	gl.Enable(gl.CULL_FACE)
 
 	gl.LineWidth(2)
+
+	// shaders work in linear space, encode for the display
+	r.SetSRGBOutput(r.srgb)
 	/*
 		gl.ShadeModel(gl.SMOOTH)
 
@@ -133,6 +146,12 @@ func (r *Renderer) initGL() error {
 // cleanup
 
 func (r *Renderer) Unload() {
+	if r.capture != nil {
+		if err := r.StopCapture(); err != nil {
+			log.Printf("could not capture frames: %v\n", err)
+		}
+	}
+
 	for _, p := range r.passes {
 		p.scene.Dispose()
 	}
@@ -162,9 +181,45 @@ func (r *Renderer) SetSize(width, height int) {
 }
 
 func (r *Renderer) SetClearColor(color math.Color, alpha float64) {
+	r.clearColor, r.clearAlpha = color, alpha
+
+	if r.srgb {
+		color = color.Linear()
+	}
 	gl.ClearColor(gl.GLclampf(color.R), gl.GLclampf(color.G), gl.GLclampf(color.B), gl.GLclampf(alpha))
 }
 
+// gamma encode the linear shader output written to the window, color uniforms and
+// vertex colors are converted to linear meanwhile, rendertargets always store linear values
+func (r *Renderer) SetSRGBOutput(b bool) {
+	if r.srgb != b {
+		r.srgb = b
+
+		// upload colors again
+		r.currentMaterial = nil
+		r.currentGeometry = nil
+		r.SetClearColor(r.clearColor, r.clearAlpha)
+	}
+
+	if b {
+		gl.Enable(gl.FRAMEBUFFER_SRGB)
+	} else {
+		gl.Disable(gl.FRAMEBUFFER_SRGB)
+	}
+}
+
+func (r *Renderer) SRGBOutput() bool {
+	return r.srgb
+}
+
+func (r *Renderer) SetVSync(b bool) {
+	if b {
+		glfw.SwapInterval(1)
+	} else {
+		glfw.SwapInterval(0)
+	}
+}
+
 func (r *Renderer) SetMouseVisible(show bool) {
 	if show {
 		r.window.SetInputMode(glfw.Cursor, glfw.CursorNormal)
@@ -269,6 +324,16 @@ func (r *Renderer) AddPass(p *RenderPass) {
 }
 
 func (r *Renderer) Render() {
+	r.renderPasses()
+
+	if r.capture != nil {
+		r.captureFrame()
+	}
+
+	r.SwapBuffers()
+}
+
+func (r *Renderer) renderPasses() {
 	for _, p := range r.passes {
 
 		if p.clear {
@@ -281,23 +346,10 @@ func (r *Renderer) Render() {
 			r.RenderScene(p.scene, p.camera, p.clear, p.target)
 		}
 	}
-
-	r.SwapBuffers()
 }
 
 func (r *Renderer) RenderScene(scene *Scene, camera Camera, clear bool, target *RenderTarget) {
-	// bind rendertarget
-	if r.currentRendertarget != target {
-		if target != nil {
-			target.BindFramebuffer()
-			gl.Viewport(0, 0, target.width, target.height)
-		} else {
-			r.currentRendertarget.Unbind()
-			gl.Viewport(0, 0, r.width, r.height)
-		}
-
-		r.currentRendertarget = target
-	}
+	r.bindRenderTarget(target)
 
 	// clear screen
 	if clear {
@@ -339,6 +391,22 @@ func (r *Renderer) RenderScene(scene *Scene, camera Camera, clear bool, target *
 	}
 }
 
+func (r *Renderer) bindRenderTarget(target *RenderTarget) {
+	if r.currentRendertarget == target {
+		return
+	}
+
+	if target != nil {
+		target.BindFramebuffer()
+		gl.Viewport(0, 0, target.width, target.height)
+	} else {
+		r.currentRendertarget.Unbind()
+		gl.Viewport(0, 0, r.width, r.height)
+	}
+
+	r.currentRendertarget = target
+}
+
 func (r *Renderer) SwapBuffers() {
 	// Swap buffers
 	r.window.SwapBuffers()
@@ -373,7 +441,7 @@ func (r *Renderer) renderObject(m Renderable, camera Camera) {
 	}
 
 	if refreshMaterial {
-		material.UpdateUniforms()
+		material.updateUniforms(r.srgb)
 	}
 
 	geometry := m.Geometry()
@@ -382,6 +450,7 @@ func (r *Renderer) renderObject(m Renderable, camera Camera) {
 
 		//program.DisableAttributes()
 		material.DisableAttributes()
+		geometry.setLinearColors(r.srgb)
 		geometry.BindVertexArray()
 
 		// vertices
@@ -408,6 +477,12 @@ func (r *Renderer) renderObject(m Renderable, camera Camera) {
 		//program.EnableAttribute("vertexColor")
 		//program.Attribute("vertexColor").AttribPointer(3, gl.FLOAT, false, 0, nil)
 		material.EnableAttribute("vertexColor")
+
+		// tangent, only for normal mapping shaders
+		if geometry.HasTangents() && material.HasAttribute("vertexTangent") {
+			geometry.BindTangentBuffer()
+			material.EnableAttribute("vertexTangent")
+		}
 	}
 
 	// for each object of same material and geometry
@@ -14,18 +14,39 @@ type RenderTarget struct {
 	renderBuffer  gl.Renderbuffer
 	initialized   bool
 
-	width, height int
-	needsUpdate   bool
+	width, height  int
+	internalFormat int
+	format, typ    gl.GLenum
+	needsUpdate    bool
 }
 
 func NewRenderTarget(w, h int) *RenderTarget {
 	return &RenderTarget{
-		width:       w,
-		height:      h,
-		needsUpdate: true,
+		width:          w,
+		height:         h,
+		internalFormat: gl.RGB,
+		format:         gl.RGB,
+		typ:            gl.UNSIGNED_BYTE,
+		needsUpdate:    true,
 	}
 }
 
+// floating point target for high dynamic range rendering, needs a tone mapping pass
+func NewHDRRenderTarget(w, h int) *RenderTarget {
+	return &RenderTarget{
+		width:          w,
+		height:         h,
+		internalFormat: gl.RGBA16F,
+		format:         gl.RGBA,
+		typ:            gl.FLOAT,
+		needsUpdate:    true,
+	}
+}
+
+func (t *RenderTarget) HDR() bool {
+	return t.typ == gl.FLOAT
+}
+
 // init frame buffers
 func (t *RenderTarget) init() {
 	t.frameBuffer = gl.GenFramebuffer()
@@ -47,9 +68,9 @@ func (t *RenderTarget) update() {
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
 
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGB,
+	gl.TexImage2D(gl.TEXTURE_2D, 0, t.internalFormat,
 		t.width, t.height,
-		0, gl.RGB, gl.UNSIGNED_BYTE, nil) // empty image
+		0, t.format, t.typ, nil) // empty image
 
 	// setup frame buffer
 	t.frameBuffer.Bind() // t.frameBuffer.BindTarget(gl.FRAMEBUFFER)
@@ -104,8 +125,8 @@ func (t *RenderTarget) Bind(slot int) {
 		t.update()
 	}
 
-	t.textureBuffer.Bind(gl.TEXTURE_2D)
 	gl.ActiveTexture(gl.TEXTURE0 + gl.GLenum(slot))
+	t.textureBuffer.Bind(gl.TEXTURE_2D)
 }
 
 func (t *RenderTarget) Unbind() {
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/der-antikeks/gisp/math"
 )
@@ -11,6 +12,11 @@ type Scene struct {
 
 	objects []Renderable
 	lights  []Object
+
+	// spatial index and visible objects cache
+	tree                *bvh
+	opaque, transparent []Renderable
+
 	/*
 		fog     struct {
 			fogNear  float64
@@ -37,6 +43,8 @@ type Scene struct {
 
 func NewScene() *Scene {
 	return &Scene{
+		tree: newBVH(),
+
 		up:    math.Vector{0, 1, 0},
 		scale: math.Vector{1, 1, 1},
 
@@ -48,21 +56,14 @@ func NewScene() *Scene {
 func (s *Scene) AddObject(o Object) {
 	switch ot := o.(type) {
 	case Renderable:
-		var found bool
-		for _, c := range s.objects {
-			if ot == c {
-				found = true
-				break
-			}
-		}
-
-		if !found {
+		if !s.tree.contains(ot) {
 			s.objects = append(s.objects, ot)
+			s.tree.insert(ot)
 		}
 
 	//case Light:
 	//	s.lights = append(s.lights, r)
-	case *Group:
+	case *Group, *Terrain, *Text:
 	case *Scene:
 	case Camera:
 	default:
@@ -89,10 +90,11 @@ func (s *Scene) RemoveObject(o Object) {
 			copy(s.objects[position:], s.objects[position+1:])
 			s.objects[len(s.objects)-1] = nil
 			s.objects = s.objects[:len(s.objects)-1]
+			s.tree.remove(ot)
 		}
 
 	//case Light:
-	case *Group:
+	case *Group, *Terrain, *Text:
 	case *Scene:
 	case Camera:
 	default:
@@ -104,27 +106,66 @@ func (s *Scene) RemoveObject(o Object) {
 	}
 }
 
+// refresh the bounding volume of the object, needed after its geometry changed
+func (s *Scene) UpdateObject(o Renderable) {
+	s.tree.touch(o)
+}
+
+// returned slices are reused by the next call
 func (s *Scene) VisibleObjects(f math.Frustum) (opaque, transparent []Renderable) {
-	opaque = make([]Renderable, len(s.objects))
-	transparent = make([]Renderable, len(s.objects))
-	var cntOp, cntTr int
+	s.tree.update()
+	s.opaque, s.transparent = s.opaque[:0], s.transparent[:0]
 
-	for _, o := range s.objects {
-		c, r := o.Geometry().Boundary().Sphere()
-
-		// transform sphere with modelmatrix
-		if f.IntersectsSphere(o.MatrixWorld().Transform(c), r*o.MatrixWorld().MaxScaleOnAxis()) {
-			if o.Material().Opaque() {
-				opaque[cntOp] = o
-				cntOp++
-			} else {
-				transparent[cntTr] = o
-				cntTr++
-			}
+	s.tree.intersectFrustum(f, func(o Renderable) {
+		if o.Material().Opaque() {
+			s.opaque = append(s.opaque, o)
+		} else {
+			s.transparent = append(s.transparent, o)
 		}
+	})
+
+	return s.opaque, s.transparent
+}
+
+// objects whose bounding sphere is hit by the ray, nearest first
+func (s *Scene) Raycast(r math.Ray) []Renderable {
+	s.tree.update()
+
+	var hits rayHits
+	s.tree.intersectRay(r, func(o Renderable, d float64) {
+		hits = append(hits, rayHit{o, d})
+	})
+	sort.Sort(hits)
+
+	result := make([]Renderable, len(hits))
+	for i, h := range hits {
+		result[i] = h.object
 	}
 
-	return opaque[:cntOp], transparent[:cntTr]
+	return result
+}
+
+type rayHit struct {
+	object   Renderable
+	distance float64
+}
+
+type rayHits []rayHit
+
+func (h rayHits) Len() int           { return len(h) }
+func (h rayHits) Less(i, j int) bool { return h[i].distance < h[j].distance }
+func (h rayHits) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+// objects whose bounding sphere intersects the region
+func (s *Scene) ObjectsInBoundary(b math.Boundary) []Renderable {
+	s.tree.update()
+
+	var result []Renderable
+	s.tree.intersectBoundary(b, func(o Renderable) {
+		result = append(result, o)
+	})
+
+	return result
 }
 
 func (s *Scene) Dispose() {
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"image"
+	m "math"
+)
+
+// larger than any squared distance within an image
+const edtInfinity = 1e20
+
+// signed distance field of the white, opaque parts of an image in linear time,
+// distances are in pixels between pixel centers and clamped to spread
+func generateDistanceField(in *image.RGBA, spread int) *image.RGBA {
+	rect := in.Bounds()
+	w, h := rect.Dx(), rect.Dy()
+	out := image.NewRGBA(rect)
+
+	// create mask
+	mask := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := in.Pix[in.PixOffset(rect.Min.X+x, rect.Min.Y+y):]
+			mask[y*w+x] = (p[0] >= 0x80 || p[1] >= 0x80 || p[2] >= 0x80) && p[3] >= 0x80
+		}
+	}
+
+	// distances of inside pixels to the outside and the other way round
+	outside := squaredDistances(mask, w, h, false)
+	inside := squaredDistances(mask, w, h, true)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+
+			d := -m.Sqrt(inside[i])
+			if mask[i] {
+				d = m.Sqrt(outside[i])
+			}
+
+			c := distanceToAlpha(d, spread)
+			p := out.Pix[out.PixOffset(rect.Min.X+x, rect.Min.Y+y):]
+			p[0], p[1], p[2], p[3] = c, c, c, c // premultiplied alpha white
+		}
+	}
+
+	return out
+}
+
+func distanceToAlpha(distance float64, spread int) uint8 {
+	return uint8(m.Min(1, m.Max(0, 0.5+0.5*(distance/float64(spread)))) * 0xff)
+}
+
+// squared euclidean distance of each pixel to the nearest one with the target value
+// http://cs.brown.edu/people/pfelzens/papers/dt-final.pdf
+func squaredDistances(mask []bool, w, h int, target bool) []float64 {
+	d := make([]float64, w*h)
+	for i, v := range mask {
+		if v != target {
+			d[i] = edtInfinity
+		}
+	}
+
+	n := maxInt(w, h)
+	f := make([]float64, n)
+	v := make([]int, n)
+	z := make([]float64, n+1)
+	r := make([]float64, n)
+
+	// columns, then rows
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			f[y] = d[y*w+x]
+		}
+		distanceTransform1D(f[:h], r, v, z)
+		for y := 0; y < h; y++ {
+			d[y*w+x] = r[y]
+		}
+	}
+	for y := 0; y < h; y++ {
+		copy(f, d[y*w:(y+1)*w])
+		distanceTransform1D(f[:w], r, v, z)
+		copy(d[y*w:(y+1)*w], r[:w])
+	}
+
+	return d
+}
+
+// lower envelope of the parabolas rooted at i, f[i], v and z are scratch space
+func distanceTransform1D(f, d []float64, v []int, z []float64) {
+	n := len(f)
+	if n == 0 {
+		return
+	}
+
+	k := 0
+	v[0] = 0
+	z[0], z[1] = m.Inf(-1), m.Inf(1)
+
+	intersection := func(q, p int) float64 {
+		return ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*q-2*p)
+	}
+
+	for q := 1; q < n; q++ {
+		s := intersection(q, v[k])
+		for s <= z[k] {
+			k--
+			s = intersection(q, v[k])
+		}
+		k++
+		v[k] = q
+		z[k], z[k+1] = s, m.Inf(1)
+	}
+
+	k = 0
+	for q := 0; q < n; q++ {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		p := v[k]
+		d[q] = float64((q-p)*(q-p)) + f[p]
+	}
+}
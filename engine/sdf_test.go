@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+	m "math"
+	"testing"
+)
+
+// nearest pixel of the other value by exhaustive search
+func bruteSquaredDistance(mask []bool, w, h, x, y int) float64 {
+	best := edtInfinity
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			if mask[j*w+i] != mask[y*w+x] {
+				best = m.Min(best, float64((i-x)*(i-x)+(j-y)*(j-y)))
+			}
+		}
+	}
+	return best
+}
+
+func TestSquaredDistances(t *testing.T) {
+	tests := []struct {
+		w, h   int
+		inside func(x, y int) bool
+	}{
+		{9, 7, func(x, y int) bool { return x == 4 && y == 3 }},
+		{16, 12, func(x, y int) bool { return (x-7)*(x-7)+(y-5)*(y-5) < 20 }},
+		{13, 13, func(x, y int) bool { return (x+y)%5 == 0 || x == 12 }},
+		{5, 5, func(x, y int) bool { return true }},
+	}
+
+	for n, c := range tests {
+		mask := make([]bool, c.w*c.h)
+		for y := 0; y < c.h; y++ {
+			for x := 0; x < c.w; x++ {
+				mask[y*c.w+x] = c.inside(x, y)
+			}
+		}
+
+		outside := squaredDistances(mask, c.w, c.h, false)
+		inside := squaredDistances(mask, c.w, c.h, true)
+
+		for i, v := range mask {
+			d := inside[i]
+			if v {
+				d = outside[i]
+			}
+			if e := bruteSquaredDistance(mask, c.w, c.h, i%c.w, i/c.w); m.Abs(d-e) > 1e-9 && e < edtInfinity {
+				t.Errorf("squaredDistances(%v) at %v, %v != %v (got %v)", n, i%c.w, i/c.w, e, d)
+			}
+		}
+	}
+}
+
+func TestGenerateDistanceField(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := 6; y < 14; y++ {
+		for x := 6; x < 14; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	tests := []struct {
+		x, y     int
+		expected uint8
+	}{
+		{0, 0, 0},      // far outside, clamped
+		{10, 10, 0xff}, // far inside, clamped
+		{6, 10, 0x9f},  // 1 pixel inside
+		{8, 7, 0xbf},   // 2 pixels inside
+		{13, 13, 0x9f}, // inner corner
+		{5, 10, 0x5f},  // 1 pixel outside
+		{10, 16, 0x1f}, // 3 pixels outside
+		{4, 4, 0x25},   // diagonal, 2.83 pixels outside
+	}
+
+	df := generateDistanceField(img, 4)
+	for _, c := range tests {
+		if p := df.RGBAAt(c.x, c.y); p.A != c.expected || p.R != p.A {
+			t.Errorf("generateDistanceField() at %v, %v != %#x (got %#x)", c.x, c.y, c.expected, p.A)
+		}
+	}
+}
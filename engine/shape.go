@@ -0,0 +1,365 @@
+package engine
+
+import (
+	m "math"
+	"sort"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+const pathDivisions = 12 // line segments per curve
+
+// 2d outline in the xy plane, curves are sampled into line segments
+type Path struct {
+	points []math.Vector
+}
+
+func NewPath() *Path {
+	return &Path{}
+}
+
+func NewPathFromPoints(points []math.Vector) *Path {
+	p := &Path{}
+	for _, pt := range points {
+		p.LineTo(pt[0], pt[1])
+	}
+	return p
+}
+
+// start a new outline
+func (p *Path) MoveTo(x, y float64) {
+	p.points = append(p.points[:0], math.Vector{x, y, 0})
+}
+
+func (p *Path) current() math.Vector {
+	if len(p.points) == 0 {
+		return math.Vector{}
+	}
+	return p.points[len(p.points)-1]
+}
+
+func (p *Path) LineTo(x, y float64) {
+	p.points = append(p.points, math.Vector{x, y, 0})
+}
+
+func (p *Path) QuadraticCurveTo(cx, cy, x, y float64) {
+	s := p.current()
+	for i := 1; i <= pathDivisions; i++ {
+		t := float64(i) / pathDivisions
+		k := 1 - t
+		p.LineTo(
+			k*k*s[0]+2*k*t*cx+t*t*x,
+			k*k*s[1]+2*k*t*cy+t*t*y,
+		)
+	}
+}
+
+func (p *Path) BezierCurveTo(c1x, c1y, c2x, c2y, x, y float64) {
+	s := p.current()
+	for i := 1; i <= pathDivisions; i++ {
+		t := float64(i) / pathDivisions
+		k := 1 - t
+		p.LineTo(
+			k*k*k*s[0]+3*k*k*t*c1x+3*k*t*t*c2x+t*t*t*x,
+			k*k*k*s[1]+3*k*k*t*c1y+3*k*t*t*c2y+t*t*t*y,
+		)
+	}
+}
+
+// circular arc around x, y from start to end angle in radians, connected with a line to the current point
+func (p *Path) Arc(x, y, radius, start, end float64, clockwise bool) {
+	delta := end - start
+	for delta < 0 {
+		delta += 2 * m.Pi
+	}
+	for delta > 2*m.Pi {
+		delta -= 2 * m.Pi
+	}
+	if clockwise {
+		delta -= 2 * m.Pi
+		if delta == -2*m.Pi {
+			delta = 0
+		}
+	}
+	if delta == 0 && end != start {
+		delta = 2 * m.Pi // full circle
+	}
+
+	divisions := int(m.Ceil(m.Abs(delta) / (m.Pi / 2) * pathDivisions / 2))
+	if divisions < 1 {
+		divisions = 1
+	}
+
+	for i := 0; i <= divisions; i++ {
+		a := start + delta*float64(i)/float64(divisions)
+		p.LineTo(x+radius*m.Cos(a), y+radius*m.Sin(a))
+	}
+}
+
+// outline without a closing duplicate of the first point
+func (p *Path) Points() []math.Vector {
+	pts := make([]math.Vector, 0, len(p.points))
+	for i, pt := range p.points {
+		if i > 0 && samePoint(pt, pts[len(pts)-1]) {
+			continue
+		}
+		pts = append(pts, pt)
+	}
+	if len(pts) > 1 && samePoint(pts[0], pts[len(pts)-1]) {
+		pts = pts[:len(pts)-1]
+	}
+	return pts
+}
+
+// filled 2d outline with holes
+type Shape struct {
+	Path
+	holes []*Path
+}
+
+func NewShape() *Shape {
+	return &Shape{}
+}
+
+func NewShapeFromPoints(points []math.Vector) *Shape {
+	return &Shape{Path: *NewPathFromPoints(points)}
+}
+
+func (s *Shape) AddHole(h *Path) {
+	s.holes = append(s.holes, h)
+}
+
+func (s *Shape) Holes() []*Path {
+	return s.holes
+}
+
+// counter clockwise outline and clockwise holes
+func (s *Shape) extract() (contour []math.Vector, holes [][]math.Vector) {
+	contour = s.Points()
+	if signedArea(contour) < 0 {
+		reversePoints(contour)
+	}
+
+	for _, h := range s.holes {
+		pts := h.Points()
+		if len(pts) < 3 {
+			continue
+		}
+		if signedArea(pts) > 0 {
+			reversePoints(pts)
+		}
+		holes = append(holes, pts)
+	}
+
+	return contour, holes
+}
+
+// positive for counter clockwise polygons
+func signedArea(pts []math.Vector) float64 {
+	var a float64
+	for i, p := range pts {
+		q := pts[(i+1)%len(pts)]
+		a += p[0]*q[1] - q[0]*p[1]
+	}
+	return a * 0.5
+}
+
+func reversePoints(pts []math.Vector) {
+	for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+		pts[i], pts[j] = pts[j], pts[i]
+	}
+}
+
+func samePoint(a, b math.Vector) bool {
+	return a.DistanceToSquared(b) < 1e-18
+}
+
+// z of the cross product of b-a and c-a
+func cross2(a, b, c math.Vector) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+func segmentsIntersect(a, b, c, d math.Vector) bool {
+	d1, d2 := cross2(c, d, a), cross2(c, d, b)
+	d3, d4 := cross2(a, b, c), cross2(a, b, d)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) &&
+		((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+func pointInTriangle(p, a, b, c math.Vector) bool {
+	return cross2(a, b, p) >= 0 && cross2(b, c, p) >= 0 && cross2(c, a, p) >= 0
+}
+
+// ear clipping of a counter clockwise contour with clockwise holes, returns counter clockwise
+// triangles with indices into the contour followed by all hole points
+func Triangulate(contour []math.Vector, holes [][]math.Vector) []Face {
+	points := append([]math.Vector(nil), contour...)
+	polygon := make([]int, len(contour))
+	for i := range polygon {
+		polygon[i] = i
+	}
+
+	// hole index lists, rightmost holes are bridged first
+	hs := make(triangulateHoles, len(holes))
+	for i, h := range holes {
+		hs[i].indices = make([]int, len(h))
+		for j, p := range h {
+			hs[i].indices[j] = len(points)
+			if p[0] > h[hs[i].right][0] {
+				hs[i].right = j
+			}
+			points = append(points, p)
+		}
+	}
+	for i := range hs {
+		hs[i].x = points[hs[i].indices[hs[i].right]][0]
+	}
+	sort.Stable(hs)
+
+	for n, h := range hs {
+		var blocking [][]int // holes not yet bridged
+		for _, o := range hs[n+1:] {
+			blocking = append(blocking, o.indices)
+		}
+		polygon = bridgeHole(points, polygon, h.indices, h.right, blocking)
+	}
+
+	return clipEars(points, polygon)
+}
+
+type triangulateHole struct {
+	indices []int
+	right   int     // index of the rightmost point
+	x       float64 // of the rightmost point
+}
+
+type triangulateHoles []triangulateHole
+
+func (h triangulateHoles) Len() int           { return len(h) }
+func (h triangulateHoles) Less(i, j int) bool { return h[i].x > h[j].x }
+func (h triangulateHoles) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+type bridgeCandidate struct {
+	at   int
+	dist float64
+}
+
+type bridgeCandidates []bridgeCandidate
+
+func (c bridgeCandidates) Len() int           { return len(c) }
+func (c bridgeCandidates) Less(i, j int) bool { return c[i].dist < c[j].dist }
+func (c bridgeCandidates) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// connect a hole to the nearest polygon vertex whose bridge crosses no edge
+func bridgeHole(points []math.Vector, polygon, hole []int, right int, blocking [][]int) []int {
+	mp := points[hole[right]]
+
+	candidates := make(bridgeCandidates, len(polygon))
+	for i, pi := range polygon {
+		candidates[i] = bridgeCandidate{i, points[pi].DistanceToSquared(mp)}
+	}
+	sort.Stable(candidates)
+
+	rings := append([][]int{polygon, hole}, blocking...)
+	visible := func(p math.Vector) bool {
+		for _, r := range rings {
+			for i, a := range r {
+				b := r[(i+1)%len(r)]
+				if segmentsIntersect(mp, p, points[a], points[b]) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	at := candidates[0].at
+	for _, c := range candidates {
+		if visible(points[polygon[c.at]]) {
+			at = c.at
+			break
+		}
+	}
+
+	// polygon ... P, M, hole ..., M, P, ...
+	merged := make([]int, 0, len(polygon)+len(hole)+2)
+	merged = append(merged, polygon[:at+1]...)
+	for i := 0; i <= len(hole); i++ {
+		merged = append(merged, hole[(right+i)%len(hole)])
+	}
+	merged = append(merged, polygon[at])
+	merged = append(merged, polygon[at+1:]...)
+
+	return merged
+}
+
+// collinear vertices are never dropped, an edge ending on them would leave a t-junction
+// with the side walls of extruded shapes
+func clipEars(points []math.Vector, polygon []int) []Face {
+	var faces []Face
+	const eps = 1e-12
+
+	for len(polygon) > 3 {
+		clipped := false
+		flat := -1 // first collinear corner
+
+		for i := 0; i < len(polygon); i++ {
+			n := len(polygon)
+			ia, ib, ic := polygon[(i+n-1)%n], polygon[i], polygon[(i+1)%n]
+			a, b, c := points[ia], points[ib], points[ic]
+
+			area := cross2(a, b, c)
+			if m.Abs(area) <= eps {
+				if flat < 0 {
+					flat = i
+				}
+				continue // collinear, clipped by a neighbouring ear
+			}
+			if area < 0 {
+				continue // reflex
+			}
+
+			ear := true
+			for _, j := range polygon {
+				p := points[j]
+				if j == ia || j == ib || j == ic || samePoint(p, a) || samePoint(p, b) || samePoint(p, c) {
+					continue
+				}
+				if pointInTriangle(p, a, b, c) {
+					ear = false
+					break
+				}
+			}
+			if !ear {
+				continue
+			}
+
+			faces = append(faces, Face{ia, ib, ic})
+			polygon = append(polygon[:i], polygon[i+1:]...)
+			clipped = true
+			break
+		}
+
+		if clipped {
+			continue
+		}
+
+		n := len(polygon)
+		if flat >= 0 {
+			// no ear left but a collinear corner, clip it as zero area ear
+			faces = append(faces, Face{polygon[(flat+n-1)%n], polygon[flat], polygon[(flat+1)%n]})
+			polygon = append(polygon[:flat], polygon[flat+1:]...)
+			continue
+		}
+
+		// self intersecting or degenerated, cut the first convex corner anyway
+		faces = append(faces, Face{polygon[n-1], polygon[0], polygon[1]})
+		polygon = polygon[1:]
+	}
+
+	if len(polygon) == 3 {
+		faces = append(faces, Face{polygon[0], polygon[1], polygon[2]})
+	}
+
+	return faces
+}
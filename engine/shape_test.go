@@ -0,0 +1,290 @@
+package engine
+
+import (
+	m "math"
+	"testing"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+func testRect(x0, y0, x1, y1 float64) []math.Vector {
+	return []math.Vector{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+}
+
+// signed volume, positive for closed meshes with outward facing triangles
+func testVolume(g *Geometry) float64 {
+	var v float64
+	for _, f := range g.faces {
+		a, b, c := g.vertices[f.A].position, g.vertices[f.B].position, g.vertices[f.C].position
+		a[3], b[3], c[3] = 0, 0, 0
+		v += a.Dot(b.Cross(c)) / 6
+	}
+	return v
+}
+
+// every edge of a closed mesh is shared by two faces in opposite directions
+func testClosed(g *Geometry) bool {
+	key := func(i int) [3]float64 {
+		p := g.vertices[i].position
+		return [3]float64{m.Round(p[0]*1e6) / 1e6, m.Round(p[1]*1e6) / 1e6, m.Round(p[2]*1e6) / 1e6}
+	}
+
+	edges := make(map[[2][3]float64]int)
+	for _, f := range g.faces {
+		for _, e := range [][2]int{{f.A, f.B}, {f.B, f.C}, {f.C, f.A}} {
+			edges[[2][3]float64{key(e[0]), key(e[1])}]++
+		}
+	}
+	for e, n := range edges {
+		if n != 1 || edges[[2][3]float64{e[1], e[0]}] != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTriangulate(t *testing.T) {
+	tests := []struct {
+		contour []math.Vector
+		holes   [][]math.Vector
+		area    float64
+	}{
+		{testRect(0, 0, 1, 1), nil, 1},
+		{[]math.Vector{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}, nil, 3}, // concave
+		{[]math.Vector{{0, 0}, {1, 0}, {2, 0}, {2, 2}, {0, 2}}, nil, 4},         // collinear
+		{[]math.Vector{{1, 0}, {2, 0}, {3, 0}, {3, 1}, {2, 1}, {1, 1}, {0, 1}, {0, 0}}, nil, 3},
+		{testRect(0, 0, 4, 4), [][]math.Vector{reversed(testRect(1, 1, 3, 3))}, 12},
+		{testRect(0, 0, 6, 3), [][]math.Vector{
+			reversed(testRect(1, 1, 2, 2)),
+			reversed(testRect(4, 1, 5, 2)),
+		}, 16},
+	}
+
+	for _, c := range tests {
+		points := append([]math.Vector(nil), c.contour...)
+		for _, h := range c.holes {
+			points = append(points, h...)
+		}
+
+		var area float64
+		used := make([]bool, len(points))
+		for _, f := range Triangulate(c.contour, c.holes) {
+			used[f.A], used[f.B], used[f.C] = true, true, true
+			a := cross2(points[f.A], points[f.B], points[f.C]) / 2
+			if a <= 0 {
+				t.Errorf("Triangulate(%v, %v) face %v is not counter clockwise", c.contour, c.holes, f)
+			}
+			area += a
+		}
+		if m.Abs(area-c.area) > 1e-9 {
+			t.Errorf("Triangulate(%v, %v) area != %v (got %v)", c.contour, c.holes, c.area, area)
+		}
+
+		// collinear points are kept to avoid t-junctions
+		for i, u := range used {
+			if !u {
+				t.Errorf("Triangulate(%v, %v) point %v is not used", c.contour, c.holes, points[i])
+			}
+		}
+	}
+}
+
+func reversed(pts []math.Vector) []math.Vector {
+	reversePoints(pts)
+	return pts
+}
+
+func TestPath_Points(t *testing.T) {
+	circle := NewPath()
+	circle.Arc(0, 0, 1, 0, 2*math.Pi, false)
+
+	quad := NewPath()
+	quad.MoveTo(0, 0)
+	quad.QuadraticCurveTo(1, 1, 2, 0)
+
+	tests := []struct {
+		path   *Path
+		points int
+		area   float64 // of the closed outline
+	}{
+		{NewPathFromPoints(append(testRect(0, 0, 1, 1), math.Vector{0, 0})), 4, 1},
+		{circle, 24, 3.1058285412302498},
+		{quad, pathDivisions + 1, -2.0 / 3},
+	}
+
+	for _, c := range tests {
+		pts := c.path.Points()
+		if len(pts) != c.points {
+			t.Errorf("Points() length != %v (got %v)", c.points, len(pts))
+		}
+		if a := signedArea(pts); m.Abs(a-c.area) > 1e-2 {
+			t.Errorf("Points() area != %v (got %v)", c.area, a)
+		}
+	}
+}
+
+func TestNewExtrudeGeometry(t *testing.T) {
+	square := NewShapeFromPoints(reversed(testRect(-1, -1, 1, 1))) // clockwise input
+	holed := NewShapeFromPoints(testRect(-2, -2, 2, 2))
+	holed.AddHole(NewPathFromPoints(testRect(-1, -1, 1, 1)))
+
+	tests := []struct {
+		shape  *Shape
+		opts   ExtrudeOptions
+		volume float64
+		max    math.Vector
+	}{
+		{square, ExtrudeOptions{Depth: 2}, 8, math.Vector{1, 1, 2}},
+		{square, ExtrudeOptions{Depth: 2, Steps: 3}, 8, math.Vector{1, 1, 2}},
+		{holed, ExtrudeOptions{Depth: 1}, 12, math.Vector{2, 2, 1}},
+		{square, ExtrudeOptions{Depth: 2, BevelThickness: 0.5, BevelSize: 0.5, BevelSegments: 1}, 18 + 2*19.0/6, math.Vector{1.5, 1.5, 2.5}},
+	}
+
+	for _, c := range tests {
+		g, err := NewExtrudeGeometry(c.shape, c.opts)
+		if err != nil {
+			t.Errorf("NewExtrudeGeometry(%+v) failed: %v", c.opts, err)
+			continue
+		}
+
+		if v := testVolume(g); m.Abs(v-c.volume) > 1e-9 {
+			t.Errorf("NewExtrudeGeometry(%+v) volume != %v (got %v)", c.opts, c.volume, v)
+		}
+		if !testClosed(g) {
+			t.Errorf("NewExtrudeGeometry(%+v) is not closed", c.opts)
+		}
+		if max := g.Boundary().Max; !max.Equals(math.Vector{c.max[0], c.max[1], c.max[2], 1}, 9) {
+			t.Errorf("NewExtrudeGeometry(%+v) max != %v (got %v)", c.opts, c.max, max)
+		}
+
+		// normals agree with the face winding
+		for _, f := range g.faces {
+			a, b, cc := g.vertices[f.A], g.vertices[f.B], g.vertices[f.C]
+			if n, _ := faceNormal(a.position, b.position, cc.position); n.Dot(a.normal) <= 0 {
+				t.Errorf("NewExtrudeGeometry(%+v) face %v normal %v against %v", c.opts, f, a.normal, n)
+				break
+			}
+		}
+	}
+}
+
+func TestNewLatheGeometry(t *testing.T) {
+	// cylinder with closed ends
+	profile := []math.Vector{{0, 0}, {1, 0}, {1, 2}, {0, 2}}
+	segments := 64
+	volume := float64(segments) / 2 * m.Sin(2*math.Pi/float64(segments)) * 2
+
+	g, err := NewLatheGeometry(profile, segments, 0, 2*math.Pi)
+	if err != nil {
+		t.Fatalf("NewLatheGeometry() failed: %v", err)
+	}
+
+	if v := testVolume(g); m.Abs(v-volume) > 1e-9 {
+		t.Errorf("NewLatheGeometry() volume != %v (got %v)", volume, v)
+	}
+	if !testClosed(g) {
+		t.Errorf("NewLatheGeometry() is not closed")
+	}
+
+	// flat caps and a smooth mantle
+	for _, f := range g.faces {
+		n, _ := faceNormal(g.vertices[f.A].position, g.vertices[f.B].position, g.vertices[f.C].position)
+		for _, i := range []int{f.A, f.B, f.C} {
+			want := math.Vector{0, m.Copysign(1, n[1]), 0}
+			if p := g.vertices[i].position; m.Abs(n[1]) < 0.5 {
+				want = math.Vector{p[0], 0, p[2]}.Normalize()
+			}
+			if g.vertices[i].normal.DistanceTo(want) > 1e-6 {
+				t.Errorf("NewLatheGeometry() normal of face %v != %v (got %v)", f, want, g.vertices[i].normal)
+			}
+		}
+	}
+}
+
+func TestNewLatheGeometry_Limit(t *testing.T) {
+	profile := []math.Vector{{1, 0}, {1, 1}, {1, 2}, {1, 3}}
+
+	tests := []struct {
+		segments int
+		valid    bool
+	}{
+		{16383, true}, // 16384 * 4 vertices
+		{16384, false},
+		{1 << 40, false},
+	}
+
+	for _, c := range tests {
+		if _, err := NewLatheGeometry(profile, c.segments, 0, math.Pi); (err == nil) != c.valid {
+			t.Errorf("NewLatheGeometry(%v segments) error %v", c.segments, err)
+		}
+	}
+}
+
+func TestNewTubeGeometry(t *testing.T) {
+	line := CurveFunc(func(t float64) math.Vector { return math.Vector{0, 0, 4 * t} })
+	circle := CurveFunc(func(t float64) math.Vector {
+		return math.Vector{3 * m.Cos(2*math.Pi*t), 3 * m.Sin(2*math.Pi*t), 0}
+	})
+	knot := NewCatmullRomCurve([]math.Vector{{0, 0, 0}, {2, 1, 0}, {2, 2, 2}, {0, 1, 3}}, true)
+
+	tests := []struct {
+		name   string
+		path   Curve
+		closed bool
+	}{
+		{"line", line, false},
+		{"circle", circle, true},
+		{"knot", knot, true},
+	}
+
+	for _, c := range tests {
+		g, err := NewTubeGeometry(c.path, 64, 0.1, 12, c.closed)
+		if err != nil {
+			t.Errorf("NewTubeGeometry(%v) failed: %v", c.name, err)
+			continue
+		}
+
+		if len(g.vertices) != 65*13 || len(g.faces) != 64*12*2 {
+			t.Errorf("NewTubeGeometry(%v) vertices %v, faces %v", c.name, len(g.vertices), len(g.faces))
+		}
+
+		for i, v := range g.vertices {
+			center := c.path.Point(float64(i/13) / 64)
+			if d := v.position.DistanceTo(center); m.Abs(d-0.1) > 1e-6 {
+				t.Errorf("NewTubeGeometry(%v) vertex %v distance != 0.1 (got %v)", c.name, i, d)
+				break
+			}
+			if d := v.position.Sub(center).Normalize(); d.DistanceTo(v.normal) > 1e-6 {
+				t.Errorf("NewTubeGeometry(%v) normal %v != %v (got %v)", c.name, i, d, v.normal)
+				break
+			}
+		}
+
+		for _, f := range g.faces {
+			a, b, cc := g.vertices[f.A], g.vertices[f.B], g.vertices[f.C]
+			if n, _ := faceNormal(a.position, b.position, cc.position); n.Dot(a.normal) <= 0 {
+				t.Errorf("NewTubeGeometry(%v) face %v faces inwards", c.name, f)
+				break
+			}
+		}
+	}
+}
+
+func TestNewTubeGeometry_Limit(t *testing.T) {
+	line := CurveFunc(func(t float64) math.Vector { return math.Vector{0, 0, 4 * t} })
+
+	tests := []struct {
+		tubular, radial int
+		valid           bool
+	}{
+		{4095, 15, true}, // 4096 * 16 vertices
+		{4096, 15, false},
+		{1 << 40, 3, false},
+	}
+
+	for _, c := range tests {
+		if _, err := NewTubeGeometry(line, c.tubular, 0.1, c.radial, false); (err == nil) != c.valid {
+			t.Errorf("NewTubeGeometry(%v, %v segments) error %v", c.tubular, c.radial, err)
+		}
+	}
+}
@@ -0,0 +1,497 @@
+package engine
+
+import (
+	m "math"
+	"sort"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// quadric error metric mesh simplification with half edge collapses
+// http://mgarland.org/files/papers/quadrics.pdf
+//
+// vertices only move onto neighbors and keep their attributes, borders and
+// uv/normal seams only collapse along themselves
+
+const simplifyEdgeWeight = 10.0 // of border and seam constraint planes
+
+// symmetric 4x4 matrix
+type quadric [10]float64
+
+// quadric of the plane n·p + d = 0
+func planeQuadric(n math.Vector, d, w float64) quadric {
+	a, b, c := n[0], n[1], n[2]
+	return quadric{
+		a * a * w, a * b * w, a * c * w, a * d * w,
+		b * b * w, b * c * w, b * d * w,
+		c * c * w, c * d * w,
+		d * d * w,
+	}
+}
+
+func (q quadric) add(o quadric) quadric {
+	for i := range q {
+		q[i] += o[i]
+	}
+	return q
+}
+
+// squared distance sum of p to the planes
+func (q quadric) error(p math.Vector) float64 {
+	x, y, z := p[0], p[1], p[2]
+	return q[0]*x*x + 2*q[1]*x*y + 2*q[2]*x*z + 2*q[3]*x +
+		q[4]*y*y + 2*q[5]*y*z + 2*q[6]*y +
+		q[7]*z*z + 2*q[8]*z +
+		q[9]
+}
+
+// collapse restrictions of a position
+const (
+	simplifyManifold = iota
+	simplifyBorder   // moves only along its two border edges
+	simplifySeam     // moves only along its two seam edges
+	simplifyLocked
+)
+
+type simplifyEdge struct {
+	faces   int
+	border  bool
+	seam    bool
+	corners [2][2]int // vertex indices of the first two faces
+}
+
+type simplifyCollapse struct {
+	u, v int // positions
+	cost float64
+}
+
+type simplifier struct {
+	vertices  []Vertex
+	faces     []Face
+	removed   []bool
+	live      int
+	pos       []int // vertex to position
+	positions []math.Vector
+	quadrics  []quadric
+	adjacency [][]int // position to faces, may contain removed faces
+	kind      []int
+	edges     map[[2]int]*simplifyEdge
+
+	stamp   []int // face visit marker
+	current int
+}
+
+func newSimplifier(g *Geometry) *simplifier {
+	s := &simplifier{
+		vertices: g.vertices,
+		faces:    append([]Face(nil), g.faces...),
+		removed:  make([]bool, len(g.faces)),
+		live:     len(g.faces),
+		pos:      make([]int, len(g.vertices)),
+		stamp:    make([]int, len(g.faces)),
+	}
+
+	// weld positions like MergeVertices, attribute seams share a position
+	scale := m.Pow(10, float64(DefaultMergePrecision.Position))
+	lookup := make(map[[3]uint64]int)
+	for i, v := range g.vertices {
+		key := [3]uint64{
+			quantize(v.position[0], scale),
+			quantize(v.position[1], scale),
+			quantize(v.position[2], scale),
+		}
+		p, found := lookup[key]
+		if !found {
+			p = len(s.positions)
+			lookup[key] = p
+			s.positions = append(s.positions, v.position)
+		}
+		s.pos[i] = p
+	}
+
+	s.quadrics = make([]quadric, len(s.positions))
+	s.adjacency = make([][]int, len(s.positions))
+	s.kind = make([]int, len(s.positions))
+
+	for i, f := range s.faces {
+		for _, v := range [3]int{f.A, f.B, f.C} {
+			s.adjacency[s.pos[v]] = append(s.adjacency[s.pos[v]], i)
+		}
+	}
+
+	s.classify()
+
+	// area weighted face planes
+	for _, f := range s.faces {
+		a, b, c := s.pos[f.A], s.pos[f.B], s.pos[f.C]
+		n, area := faceNormal(s.positions[a], s.positions[b], s.positions[c])
+		if area == 0 {
+			continue
+		}
+
+		q := planeQuadric(n, -n.Dot(s.position(a)), area*0.5)
+		s.quadrics[a] = s.quadrics[a].add(q)
+		s.quadrics[b] = s.quadrics[b].add(q)
+		s.quadrics[c] = s.quadrics[c].add(q)
+	}
+
+	// planes perpendicular to the faces at borders and seams
+	for _, f := range s.faces {
+		idx := [3]int{s.pos[f.A], s.pos[f.B], s.pos[f.C]}
+		n, area := faceNormal(s.positions[idx[0]], s.positions[idx[1]], s.positions[idx[2]])
+		if area == 0 {
+			continue
+		}
+
+		for j := 0; j < 3; j++ {
+			a, b := idx[j], idx[(j+1)%3]
+			e := s.edges[edgeKey(a, b)]
+			if !e.border && !e.seam {
+				continue
+			}
+
+			dir := s.position(b).Sub(s.position(a))
+			length := dir.Length()
+			if length == 0 {
+				continue
+			}
+
+			p := dir.Cross(n).Normalize()
+			p[3] = 0
+			q := planeQuadric(p, -p.Dot(s.position(a)), length*length*simplifyEdgeWeight)
+			s.quadrics[a] = s.quadrics[a].add(q)
+			s.quadrics[b] = s.quadrics[b].add(q)
+		}
+	}
+
+	return s
+}
+
+// position without w
+func (s *simplifier) position(p int) math.Vector {
+	v := s.positions[p]
+	v[3] = 0
+	return v
+}
+
+func edgeKey(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// find border and seam edges and the collapse restrictions of all positions
+func (s *simplifier) classify() {
+	s.edges = make(map[[2]int]*simplifyEdge)
+
+	for i, f := range s.faces {
+		if s.removed[i] {
+			continue
+		}
+
+		v := [3]int{f.A, f.B, f.C}
+		for j := 0; j < 3; j++ {
+			a, b := v[j], v[(j+1)%3]
+			if s.pos[a] > s.pos[b] {
+				a, b = b, a
+			}
+
+			key := edgeKey(s.pos[a], s.pos[b])
+			e, found := s.edges[key]
+			if !found {
+				e = &simplifyEdge{}
+				s.edges[key] = e
+			}
+
+			if e.faces < 2 {
+				e.corners[e.faces] = [2]int{a, b}
+			}
+			e.faces++
+		}
+	}
+
+	borders := make([]int, len(s.positions))
+	seams := make([]int, len(s.positions))
+	for i := range s.kind {
+		s.kind[i] = simplifyManifold
+	}
+
+	for key, e := range s.edges {
+		switch {
+		case e.faces == 1:
+			e.border = true
+			borders[key[0]]++
+			borders[key[1]]++
+		case e.faces == 2 && e.corners[0] != e.corners[1]:
+			e.seam = true
+			seams[key[0]]++
+			seams[key[1]]++
+		case e.faces > 2:
+			s.kind[key[0]] = simplifyLocked
+			s.kind[key[1]] = simplifyLocked
+		}
+	}
+
+	// attribute discontinuities without seam edges, e.g. cone tips
+	indices := make([]int, len(s.positions))
+	seen := make([]bool, len(s.vertices))
+	for i, f := range s.faces {
+		if s.removed[i] {
+			continue
+		}
+		for _, v := range [3]int{f.A, f.B, f.C} {
+			if !seen[v] {
+				seen[v] = true
+				indices[s.pos[v]]++
+			}
+		}
+	}
+
+	for p := range s.kind {
+		switch {
+		case s.kind[p] == simplifyLocked:
+		case borders[p] > 0 && seams[p] > 0:
+			s.kind[p] = simplifyLocked
+		case borders[p] > 0:
+			s.kind[p] = simplifyBorder
+			if borders[p] != 2 || indices[p] != 1 {
+				s.kind[p] = simplifyLocked
+			}
+		case seams[p] > 0:
+			s.kind[p] = simplifySeam
+			if seams[p] != 2 {
+				s.kind[p] = simplifyLocked
+			}
+		case indices[p] > 1:
+			s.kind[p] = simplifyLocked
+		}
+	}
+}
+
+// may position u move onto its neighbor v
+func (s *simplifier) allowed(u, v int, e *simplifyEdge) bool {
+	switch s.kind[u] {
+	case simplifyManifold:
+		return true
+	case simplifyBorder:
+		return e.border
+	case simplifySeam:
+		return e.seam
+	}
+	return false
+}
+
+// live faces around position u, each once
+func (s *simplifier) around(u int, fn func(i int)) {
+	s.current++
+	for _, i := range s.adjacency[u] {
+		if s.removed[i] || s.stamp[i] == s.current {
+			continue
+		}
+		s.stamp[i] = s.current
+		fn(i)
+	}
+}
+
+// move position u onto v, returns false if the collapse would break the mesh
+func (s *simplifier) collapse(u, v int) bool {
+	mapping := make(map[int]int, 2) // vertex indices of u to v
+	valid := true
+	shared := 0                     // faces on the edge
+	neighbors := make(map[int]bool) // positions around u
+
+	// faces on the edge determine the new vertex indices
+	s.around(u, func(i int) {
+		f := s.faces[i]
+		a, b := -1, -1
+		for _, x := range [3]int{f.A, f.B, f.C} {
+			switch s.pos[x] {
+			case u:
+				a = x
+			case v:
+				b = x
+			default:
+				neighbors[s.pos[x]] = true
+			}
+		}
+		if b == -1 {
+			return
+		}
+		shared++
+
+		if to, found := mapping[a]; found && to != b {
+			valid = false
+		}
+		mapping[a] = b
+	})
+	if !valid || shared == 0 {
+		return false
+	}
+
+	// link condition, only the opposite corners of the edge may be common neighbors
+	common := 0
+	s.around(v, func(i int) {
+		f := s.faces[i]
+		for _, x := range [3]int{f.A, f.B, f.C} {
+			if p := s.pos[x]; neighbors[p] {
+				delete(neighbors, p)
+				common++
+			}
+		}
+	})
+	if common != shared {
+		return false
+	}
+
+	// remaining faces must keep their orientation
+	target := s.position(v)
+	s.around(u, func(i int) {
+		f := s.faces[i]
+		p := [3]math.Vector{}
+		moved := p
+
+		for j, x := range [3]int{f.A, f.B, f.C} {
+			if s.pos[x] == v {
+				return // degenerates
+			}
+			p[j] = s.position(s.pos[x])
+			moved[j] = p[j]
+			if s.pos[x] == u {
+				if _, found := mapping[x]; !found {
+					valid = false
+				}
+				moved[j] = target
+			}
+		}
+
+		before, _ := faceNormal(p[0], p[1], p[2])
+		after, area := faceNormal(moved[0], moved[1], moved[2])
+		if area == 0 || before.Dot(after) < 0.2 {
+			valid = false
+		}
+	})
+	if !valid {
+		return false
+	}
+
+	// apply
+	s.around(u, func(i int) {
+		f := &s.faces[i]
+		for _, x := range [3]int{f.A, f.B, f.C} {
+			if s.pos[x] == v {
+				s.removed[i] = true
+				s.live--
+				return
+			}
+		}
+
+		switch {
+		case s.pos[f.A] == u:
+			f.A = mapping[f.A]
+		case s.pos[f.B] == u:
+			f.B = mapping[f.B]
+		default:
+			f.C = mapping[f.C]
+		}
+	})
+
+	s.adjacency[v] = append(s.adjacency[v], s.adjacency[u]...)
+	s.adjacency[u] = nil
+	s.quadrics[v] = s.quadrics[v].add(s.quadrics[u])
+
+	return true
+}
+
+// one round of independent cheapest collapses
+func (s *simplifier) pass(target int) bool {
+	s.classify()
+
+	var candidates []simplifyCollapse
+	for key, e := range s.edges {
+		a, b := key[0], key[1]
+		q := s.quadrics[a].add(s.quadrics[b])
+
+		if s.allowed(a, b, e) {
+			candidates = append(candidates, simplifyCollapse{a, b, q.error(s.position(b))})
+		}
+		if s.allowed(b, a, e) {
+			candidates = append(candidates, simplifyCollapse{b, a, q.error(s.position(a))})
+		}
+	}
+	sort.Sort(simplifyCollapses(candidates))
+
+	locked := make(map[int]bool)
+	collapsed := false
+
+	for _, c := range candidates {
+		if s.live <= target {
+			break
+		}
+		if locked[c.u] || locked[c.v] {
+			continue
+		}
+
+		if s.collapse(c.u, c.v) {
+			locked[c.u], locked[c.v] = true, true
+			collapsed = true
+		}
+	}
+
+	return collapsed
+}
+
+type simplifyCollapses []simplifyCollapse
+
+func (c simplifyCollapses) Len() int      { return len(c) }
+func (c simplifyCollapses) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c simplifyCollapses) Less(i, j int) bool {
+	if c[i].cost != c[j].cost {
+		return c[i].cost < c[j].cost
+	}
+	if c[i].u != c[j].u {
+		return c[i].u < c[j].u
+	}
+	return c[i].v < c[j].v
+}
+
+// new geometry with about ratio of the faces, e.g. for a chain of detail levels
+func (g *Geometry) Simplify(ratio float64) *Geometry {
+	if ratio < 0 {
+		ratio = 0
+	}
+	if ratio >= 1 || len(g.faces) == 0 {
+		return g.Clone()
+	}
+
+	s := newSimplifier(g)
+	target := int(float64(len(g.faces)) * ratio)
+
+	for s.live > target && s.pass(target) {
+	}
+
+	// compact used vertices
+	result := NewGeometry()
+	remap := make(map[int]int)
+
+	index := func(v int) int {
+		if i, found := remap[v]; found {
+			return i
+		}
+		remap[v] = result.AddVertex(s.vertices[v])
+		return remap[v]
+	}
+
+	for i, f := range s.faces {
+		if s.removed[i] {
+			continue
+		}
+		result.AddIndexedFace(index(f.A), index(f.B), index(f.C))
+	}
+
+	result.hasTangents = g.hasTangents
+	result.hint = g.hint
+	result.ComputeBoundary()
+
+	return result
+}
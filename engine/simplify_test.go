@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+func TestGeometry_Simplify(t *testing.T) {
+	grid := testGrid(5000, 0)
+	grid.MergeVertices()
+	grid.ComputeBoundary()
+
+	tests := []struct {
+		name      string
+		geo       *Geometry
+		ratio     float64
+		precision int // of the boundary
+	}{
+		{"grid", grid, 0.25, 6},
+		{"sphere", NewSphereGeometry(1, 48, 32), 0.3, 1},
+		{"cube", NewCubeGeometry(1), 0.5, 6}, // nothing to remove without changing the shape
+	}
+
+	for _, c := range tests {
+		s := c.geo.Simplify(c.ratio)
+
+		if len(s.faces) > len(c.geo.faces) || (c.name != "cube" && len(s.faces) > int(float64(len(c.geo.faces))*(c.ratio+0.05))) {
+			t.Errorf("Simplify(%v, %v) faces %v of %v", c.name, c.ratio, len(s.faces), len(c.geo.faces))
+		}
+
+		// borders and silhouettes are kept
+		if !s.Boundary().Equals(c.geo.Boundary(), c.precision) {
+			t.Errorf("Simplify(%v, %v) boundary != %v (got %v)", c.name, c.ratio, c.geo.Boundary(), s.Boundary())
+		}
+
+		// only original vertices, no flipped faces
+		original := make(map[Vertex]bool)
+		for _, v := range c.geo.vertices {
+			original[v] = true
+		}
+		for _, v := range s.vertices {
+			if !original[v] {
+				t.Errorf("Simplify(%v, %v) created vertex %v", c.name, c.ratio, v.position)
+				break
+			}
+		}
+
+		for _, f := range s.faces {
+			a, b, cc := s.vertices[f.A], s.vertices[f.B], s.vertices[f.C]
+			n, area := faceNormal(a.position, b.position, cc.position)
+			if area == 0 || n.Dot(a.normal) <= 0 {
+				t.Errorf("Simplify(%v, %v) face %v is degenerated or flipped", c.name, c.ratio, f)
+				break
+			}
+		}
+	}
+
+	// usage hint of the source
+	dynamic := NewSphereGeometry(1, 16, 12)
+	dynamic.SetUsage(DynamicDrawUsage)
+	if u := dynamic.Simplify(0.5).Usage(); u != DynamicDrawUsage {
+		t.Errorf("Simplify() usage != %v (got %v)", DynamicDrawUsage, u)
+	}
+
+	// uv seam of the sphere stays closed
+	s := NewSphereGeometry(1, 48, 32).Simplify(0.3)
+	left, right := make(map[[3]float64]bool), make(map[[3]float64]bool)
+	for _, v := range s.vertices {
+		p := [3]float64{math.Round(v.position[0], 4), math.Round(v.position[1], 4), math.Round(v.position[2], 4)}
+		switch {
+		case v.position[1] == 1 || v.position[1] == -1: // poles
+		case v.uv[0] == 0:
+			left[p] = true
+		case v.uv[0] == 1:
+			right[p] = true
+		}
+	}
+	if len(left) == 0 || len(left) != len(right) {
+		t.Errorf("Simplify(sphere) seam has %v left and %v right vertices", len(left), len(right))
+	}
+	for p := range left {
+		if !right[p] {
+			t.Errorf("Simplify(sphere) seam is open at %v", p)
+		}
+	}
+}
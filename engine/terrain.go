@@ -0,0 +1,383 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	m "math"
+	"os"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// regular grid of height samples
+type Heightmap struct {
+	width, depth int
+	heights      []float64
+}
+
+// heights of a function sampled at integer coordinates
+func NewHeightmap(width, depth int, f func(x, z float64) float64) *Heightmap {
+	h := &Heightmap{
+		width:   width,
+		depth:   depth,
+		heights: make([]float64, width*depth),
+	}
+
+	for z := 0; z < depth; z++ {
+		for x := 0; x < width; x++ {
+			h.heights[z*width+x] = f(float64(x), float64(z))
+		}
+	}
+
+	return h
+}
+
+// luminance of the pixels from 0 to 1
+func NewHeightmapFromImage(img image.Image) *Heightmap {
+	b := img.Bounds()
+
+	return NewHeightmap(b.Dx(), b.Dy(), func(x, z float64) float64 {
+		c := color.Gray16Model.Convert(img.At(b.Min.X+int(x), b.Min.Y+int(z))).(color.Gray16)
+		return float64(c.Y) / 0xffff
+	})
+}
+
+func LoadHeightmap(path string) (*Heightmap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewHeightmapFromImage(img), nil
+}
+
+func (h *Heightmap) Size() (width, depth int) {
+	return h.width, h.depth
+}
+
+// sample clamped to the borders
+func (h *Heightmap) Height(x, z int) float64 {
+	x = maxInt(0, minInt(h.width-1, x))
+	z = maxInt(0, minInt(h.depth-1, z))
+
+	return h.heights[z*h.width+x]
+}
+
+// bilinear interpolation between the samples
+func (h *Heightmap) HeightAt(x, z float64) float64 {
+	x0, z0 := m.Floor(x), m.Floor(z)
+	fx, fz := x-x0, z-z0
+	ix, iz := int(x0), int(z0)
+
+	top := h.Height(ix, iz)*(1-fx) + h.Height(ix+1, iz)*fx
+	bottom := h.Height(ix, iz+1)*(1-fx) + h.Height(ix+1, iz+1)*fx
+
+	return top*(1-fz) + bottom*fz
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+type TerrainOptions struct {
+	CellSize float64 // distance between samples along x and z
+	Height   float64 // scale of the sample values
+	UVScale  float64 // world units per texture repetition
+
+	ChunkSize   int     // cells per chunk side, a power of two
+	LODDistance float64 // camera distance of the first reduction, doubled for each further level
+}
+
+func (o TerrainOptions) withDefaults() TerrainOptions {
+	if o.CellSize <= 0 {
+		o.CellSize = 1
+	}
+	if o.Height == 0 {
+		o.Height = 1
+	}
+	if o.UVScale <= 0 {
+		o.UVScale = 1
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = 32
+	}
+	if o.LODDistance <= 0 {
+		o.LODDistance = 2 * float64(o.ChunkSize) * o.CellSize
+	}
+	return o
+}
+
+// edges of a patch next to a coarser neighbour
+const (
+	stitchNorth = 1 << iota // -z
+	stitchEast              // +x
+	stitchSouth             // +z
+	stitchWest              // -x
+)
+
+// patch of cx*cz cells from sample x0, z0 with every step-th sample, centered around the origin.
+// odd vertices of stitched edges are collapsed onto their neighbours to match a coarser patch
+func terrainPatch(h *Heightmap, o TerrainOptions, x0, z0, cx, cz, step, stitch int) *Geometry {
+	geo := NewGeometry()
+	nx, nz := cx/step, cz/step
+
+	offsetX := float64(h.width-1) / 2 * o.CellSize
+	offsetZ := float64(h.depth-1) / 2 * o.CellSize
+	color := math.Color{1, 1, 1}
+
+	for j := 0; j <= nz; j++ {
+		for i := 0; i <= nx; i++ {
+			x, z := x0+i*step, z0+j*step
+			wx, wz := float64(x)*o.CellSize, float64(z)*o.CellSize
+
+			// full resolution normals, identical on all levels
+			dx := (h.Height(x+1, z) - h.Height(x-1, z)) * o.Height / (2 * o.CellSize)
+			dz := (h.Height(x, z+1) - h.Height(x, z-1)) * o.Height / (2 * o.CellSize)
+
+			geo.AddVertex(NewVertex(
+				math.Vector{wx - offsetX, h.Height(x, z) * o.Height, wz - offsetZ},
+				math.Vector{-dx, 1, -dz}.Normalize(),
+				math.Vector{wx / o.UVScale, wz / o.UVScale},
+				color,
+			))
+		}
+	}
+
+	index := func(i, j int) int {
+		switch {
+		case j == 0 && stitch&stitchNorth != 0 && i%2 == 1,
+			j == nz && stitch&stitchSouth != 0 && i%2 == 1:
+			i--
+		case i == 0 && stitch&stitchWest != 0 && j%2 == 1,
+			i == nx && stitch&stitchEast != 0 && j%2 == 1:
+			j--
+		}
+		return j*(nx+1) + i
+	}
+
+	for j := 0; j < nz; j++ {
+		for i := 0; i < nx; i++ {
+			a, b, c, d := index(i, j), index(i, j+1), index(i+1, j+1), index(i+1, j)
+
+			if a != b && b != c && a != c {
+				geo.AddIndexedFace(a, b, c)
+			}
+			if a != c && c != d && a != d {
+				geo.AddIndexedFace(a, c, d)
+			}
+		}
+	}
+
+	geo.ComputeBoundary()
+
+	return geo
+}
+
+// single full resolution geometry of the whole heightmap
+func NewTerrainGeometry(h *Heightmap, opts TerrainOptions) (*Geometry, error) {
+	if h.width < 2 || h.depth < 2 {
+		return nil, errors.New("heightmap needs at least 2x2 samples")
+	}
+	if n := h.width * h.depth; n > m.MaxUint16+1 {
+		return nil, fmt.Errorf("too many vertices: %v, use a terrain with chunks", n)
+	}
+
+	return terrainPatch(h, opts.withDefaults(), 0, 0, h.width-1, h.depth-1, 1, 0), nil
+}
+
+type terrainChunk struct {
+	mesh *Mesh
+
+	x0, z0, cx, cz int // samples
+	maxLevel       int
+	bounds         math.Boundary
+
+	level, stitch int
+	geometries    map[int]*Geometry // by level and stitch
+}
+
+// heightmap split into chunks that are culled individually and reduce their resolution
+// with the distance to the camera (geomipmapping), call Update before rendering
+type Terrain struct {
+	*Group
+
+	heightmap *Heightmap
+	opts      TerrainOptions
+	material  *Material
+
+	chunks        []*terrainChunk
+	columns, rows int
+}
+
+func NewTerrain(h *Heightmap, mat *Material, opts TerrainOptions) (*Terrain, error) {
+	opts = opts.withDefaults()
+
+	switch {
+	case h.width < 2 || h.depth < 2:
+		return nil, errors.New("heightmap needs at least 2x2 samples")
+	case opts.ChunkSize&(opts.ChunkSize-1) != 0:
+		return nil, fmt.Errorf("chunk size %v is not a power of two", opts.ChunkSize)
+	case (opts.ChunkSize+1)*(opts.ChunkSize+1) > m.MaxUint16+1:
+		return nil, fmt.Errorf("chunk size %v is too large", opts.ChunkSize)
+	}
+
+	t := &Terrain{
+		Group:     NewGroup(),
+		heightmap: h,
+		opts:      opts,
+		material:  mat,
+		columns:   (h.width - 2 + opts.ChunkSize) / opts.ChunkSize,
+		rows:      (h.depth - 2 + opts.ChunkSize) / opts.ChunkSize,
+	}
+
+	for r := 0; r < t.rows; r++ {
+		for c := 0; c < t.columns; c++ {
+			ch := &terrainChunk{
+				x0:         c * opts.ChunkSize,
+				z0:         r * opts.ChunkSize,
+				level:      -1,
+				geometries: make(map[int]*Geometry),
+			}
+			ch.cx = minInt(opts.ChunkSize, h.width-1-ch.x0)
+			ch.cz = minInt(opts.ChunkSize, h.depth-1-ch.z0)
+
+			// smaller border chunks allow only steps dividing both sides
+			for s := 2; s <= opts.ChunkSize && ch.cx%s == 0 && ch.cz%s == 0; s *= 2 {
+				ch.maxLevel++
+			}
+
+			ch.bounds = terrainPatch(h, opts, ch.x0, ch.z0, ch.cx, ch.cz, 1, 0).Boundary()
+			t.chunks = append(t.chunks, ch)
+		}
+	}
+
+	// coarsest levels until the first update
+	far := math.Vector{m.Inf(1), m.Inf(1), m.Inf(1), 1}
+	t.updateLevels(far)
+
+	for _, ch := range t.chunks {
+		ch.mesh = NewMesh(ch.geometries[ch.level<<4|ch.stitch], mat)
+		t.AddChild(ch.mesh)
+	}
+
+	return t, nil
+}
+
+func (t *Terrain) Heightmap() *Heightmap {
+	return t.heightmap
+}
+
+func (t *Terrain) Material() *Material {
+	return t.material
+}
+
+// height of the surface at x, z in terrain space
+func (t *Terrain) HeightAt(x, z float64) float64 {
+	sx := x/t.opts.CellSize + float64(t.heightmap.width-1)/2
+	sz := z/t.opts.CellSize + float64(t.heightmap.depth-1)/2
+
+	return t.heightmap.HeightAt(sx, sz) * t.opts.Height
+}
+
+// select the level of detail of each chunk by its distance to the camera
+func (t *Terrain) Update(camera Camera) {
+	eye := camera.MatrixWorld().ExtractPosition()
+	eye[3] = 1
+	eye = t.MatrixWorld().Inverse().Transform(eye)
+
+	t.updateLevels(eye)
+}
+
+func (t *Terrain) neighbour(c, r int) *terrainChunk {
+	if c < 0 || r < 0 || c >= t.columns || r >= t.rows {
+		return nil
+	}
+	return t.chunks[r*t.columns+c]
+}
+
+func (t *Terrain) updateLevels(eye math.Vector) {
+	levels := make([]int, len(t.chunks))
+
+	for i, ch := range t.chunks {
+		p := eye.Clamp(ch.bounds.Min, ch.bounds.Max)
+		p[3] = eye[3]
+		d := p.DistanceTo(eye)
+		if m.IsNaN(d) {
+			d = m.Inf(1)
+		}
+
+		l := 0
+		for l < ch.maxLevel && d >= t.opts.LODDistance*float64(int(1)<<uint(l)) {
+			l++
+		}
+		levels[i] = l
+	}
+
+	// neighbours differ by one level at most
+	for changed := true; changed; {
+		changed = false
+		for i := range t.chunks {
+			c, r := i%t.columns, i/t.columns
+			for _, n := range [][2]int{{c, r - 1}, {c + 1, r}, {c, r + 1}, {c - 1, r}} {
+				if o := t.neighbour(n[0], n[1]); o != nil && levels[i] > levels[n[1]*t.columns+n[0]]+1 {
+					levels[i] = levels[n[1]*t.columns+n[0]] + 1
+					changed = true
+				}
+			}
+		}
+	}
+
+	for i, ch := range t.chunks {
+		c, r := i%t.columns, i/t.columns
+
+		stitch := 0
+		for s, n := range [][2]int{{c, r - 1}, {c + 1, r}, {c, r + 1}, {c - 1, r}} {
+			if o := t.neighbour(n[0], n[1]); o != nil && levels[n[1]*t.columns+n[0]] > levels[i] {
+				stitch |= 1 << uint(s)
+			}
+		}
+
+		if levels[i] == ch.level && stitch == ch.stitch {
+			continue
+		}
+		ch.level, ch.stitch = levels[i], stitch
+
+		key := ch.level<<4 | ch.stitch
+		geo, found := ch.geometries[key]
+		if !found {
+			geo = terrainPatch(t.heightmap, t.opts, ch.x0, ch.z0, ch.cx, ch.cz, 1<<uint(ch.level), ch.stitch)
+			ch.geometries[key] = geo
+		}
+
+		if ch.mesh != nil {
+			ch.mesh.SetGeometry(geo)
+		}
+	}
+}
+
+// releases the chunk geometries, the material belongs to the caller
+func (t *Terrain) Dispose() {
+	for _, ch := range t.chunks {
+		for _, g := range ch.geometries {
+			g.Dispose()
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+	m "math"
+	"testing"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+func TestHeightmap_HeightAt(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 2, 2))
+	img.SetGray(1, 0, color.Gray{255})
+	img.SetGray(1, 1, color.Gray{255})
+	h := NewHeightmapFromImage(img)
+
+	tests := []struct {
+		x, z     float64
+		expected float64
+	}{
+		{0, 0, 0},
+		{1, 0, 1},
+		{0.5, 0.5, 0.5},
+		{0.25, 1, 0.25},
+		{-3, 0, 0}, // clamped
+		{5, 5, 1},
+	}
+
+	for _, c := range tests {
+		if r := h.HeightAt(c.x, c.z); m.Abs(r-c.expected) > 1e-9 {
+			t.Errorf("HeightAt(%v, %v) != %v (got %v)", c.x, c.z, c.expected, r)
+		}
+	}
+}
+
+func testHills(x, z float64) float64 {
+	return m.Sin(x*0.3) * m.Cos(z*0.2)
+}
+
+func TestTerrainPatch(t *testing.T) {
+	h := NewHeightmap(17, 17, testHills)
+	opts := TerrainOptions{CellSize: 2}.withDefaults()
+
+	for stitch := 0; stitch < 16; stitch++ {
+		g := terrainPatch(h, opts, 0, 0, 16, 16, 2, stitch)
+
+		// covers the whole patch, facing up
+		var area float64
+		for _, f := range g.faces {
+			a, b, c := g.vertices[f.A].position, g.vertices[f.B].position, g.vertices[f.C].position
+			a[1], b[1], c[1] = 0, 0, 0
+			n := b.Sub(a).Cross(c.Sub(a))
+			if n[1] <= 0 {
+				t.Errorf("terrainPatch(stitch %v) face %v is not facing up", stitch, f)
+			}
+			area += n[1] / 2
+		}
+		if m.Abs(area-32*32) > 1e-9 {
+			t.Errorf("terrainPatch(stitch %v) area != %v (got %v)", stitch, 32*32, area)
+		}
+	}
+}
+
+// positions of used vertices along the edge between two chunks
+func testEdge(g *Geometry, on func(p math.Vector) bool) map[[2]float64]bool {
+	edge := make(map[[2]float64]bool)
+	for _, f := range g.faces {
+		for _, i := range []int{f.A, f.B, f.C} {
+			if p := g.vertices[i].position; on(p) {
+				edge[[2]float64{p[0], p[2]}] = true
+			}
+		}
+	}
+	return edge
+}
+
+func TestTerrain_Update(t *testing.T) {
+	tests := []struct {
+		width, depth  int
+		columns, rows int
+		farthest      int // level
+	}{
+		{129, 97, 8, 6, 4},
+		{100, 70, 7, 5, 0}, // border chunks of 3x5 cells
+	}
+
+	for _, c := range tests {
+		terrain, err := NewTerrain(NewHeightmap(c.width, c.depth, testHills), nil, TerrainOptions{ChunkSize: 16, LODDistance: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(terrain.chunks) != c.columns*c.rows || len(terrain.Children()) != c.columns*c.rows {
+			t.Fatalf("NewTerrain(%v, %v) chunks != %v (got %v)", c.width, c.depth, c.columns*c.rows, len(terrain.chunks))
+		}
+
+		// above the first corner
+		x, z := -float64(c.width-1)/2, -float64(c.depth-1)/2
+
+		camera := NewPerspectiveCamera(45, 1, 0.1, 1000)
+		camera.SetPosition(math.Vector{x, 5, z})
+		camera.UpdateMatrixWorld(false)
+		terrain.UpdateMatrixWorld(false)
+
+		terrain.Update(camera)
+
+		if l := terrain.chunks[0].level; l != 0 {
+			t.Errorf("Update() level of the nearest chunk != 0 (got %v)", l)
+		}
+		if l := terrain.chunks[len(terrain.chunks)-1].level; l != c.farthest {
+			t.Errorf("Update() level of the farthest chunk != %v (got %v)", c.farthest, l)
+		}
+
+		stitched := 0
+		for i, ch := range terrain.chunks {
+			col, row := i%terrain.columns, i/terrain.columns
+			if ch.stitch != 0 {
+				stitched++
+			}
+			if ch.mesh.Geometry() != ch.geometries[ch.level<<4|ch.stitch] {
+				t.Errorf("Update() chunk %v has a stale geometry", i)
+			}
+
+			// crack free edges to the east and south
+			for _, n := range []struct {
+				o    *terrainChunk
+				axis int
+			}{
+				{terrain.neighbour(col+1, row), 0},
+				{terrain.neighbour(col, row+1), 2},
+			} {
+				if n.o == nil {
+					continue
+				}
+				if d := ch.level - n.o.level; d > 1 || d < -1 {
+					t.Errorf("Update() chunks %v and %v differ by %v levels", i, n.o, d)
+				}
+
+				at := ch.bounds.Max[n.axis]
+				on := func(p math.Vector) bool { return m.Abs(p[n.axis]-at) < 1e-9 }
+				a, b := testEdge(ch.mesh.Geometry(), on), testEdge(n.o.mesh.Geometry(), on)
+				if len(a) != len(b) {
+					t.Errorf("Update() edge of chunk %v (level %v) has %v vertices, neighbour (level %v) %v",
+						i, ch.level, len(a), n.o.level, len(b))
+					continue
+				}
+				for p := range a {
+					if !b[p] {
+						t.Errorf("Update() edge vertex %v of chunk %v is missing in its neighbour", p, i)
+						break
+					}
+				}
+			}
+		}
+
+		if stitched == 0 {
+			t.Errorf("Update() no chunk next to a coarser one")
+		}
+
+		if y := terrain.HeightAt(x+1.5, z); m.Abs(y-(testHills(1, 0)+testHills(2, 0))/2) > 1e-9 {
+			t.Errorf("HeightAt(%v, %v) != %v (got %v)", x+1.5, z, (testHills(1, 0)+testHills(2, 0))/2, y)
+		}
+	}
+}
+
+func TestNewTerrainGeometry(t *testing.T) {
+	tests := []struct {
+		width, depth int
+		valid        bool
+	}{
+		{1, 10, false},
+		{16, 16, true},
+		{256, 256, true},
+		{257, 256, false},
+	}
+
+	for _, c := range tests {
+		g, err := NewTerrainGeometry(NewHeightmap(c.width, c.depth, testHills), TerrainOptions{})
+		if (err == nil) != c.valid {
+			t.Errorf("NewTerrainGeometry(%v, %v) error %v", c.width, c.depth, err)
+		}
+		if err == nil && (len(g.vertices) != c.width*c.depth || len(g.faces) != (c.width-1)*(c.depth-1)*2) {
+			t.Errorf("NewTerrainGeometry(%v, %v) vertices %v, faces %v", c.width, c.depth, len(g.vertices), len(g.faces))
+		}
+	}
+}
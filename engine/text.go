@@ -0,0 +1,485 @@
+package engine
+
+import (
+	m "math"
+	"unicode"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+type TextAlign int
+
+const (
+	AlignLeft TextAlign = iota
+	AlignCenter
+	AlignRight
+)
+
+// lengths are in units of the font size
+type TextLayout struct {
+	MaxWidth    float64 // wrap words at this width, no wrapping if zero
+	Align       TextAlign
+	LineSpacing float64 // multiple of the line height, 1 if zero
+	TabWidth    int     // in spaces, 4 if zero
+}
+
+// glyph and its pen position in pixels
+type glyphPlacement struct {
+	glyph Glyph
+	x, y  float64
+	index int // of the rune in the text
+}
+
+func (f *Font) lineHeight(l TextLayout) float64 {
+	spacing := l.LineSpacing
+	if spacing == 0 {
+		spacing = 1
+	}
+	return (f.ascent - f.descent) * spacing
+}
+
+func (f *Font) kerning(a, b Glyph) float64 {
+	if f.font == nil {
+		return 0
+	}
+	return float64(f.font.Kerning(f.scale, a.index, b.index)) / 64
+}
+
+// pen positions of the runes of a single line and its width in pixels
+func (f *Font) advances(line []rune, l TextLayout) (xs []float64, width float64) {
+	tab := l.TabWidth
+	if tab == 0 {
+		tab = 4
+	}
+	tabWidth := float64(tab) * f.charset[' '].advance
+
+	xs = make([]float64, len(line))
+	var prev Glyph
+	var kern bool
+	var x float64
+
+	for i, r := range line {
+		if r == '\t' {
+			xs[i] = x
+			if tabWidth > 0 {
+				x = (m.Floor(x/tabWidth+1e-9) + 1) * tabWidth
+			}
+			kern = false
+			continue
+		}
+
+		g, found := f.charset[r]
+		if !found {
+			xs[i] = x
+			continue
+		}
+
+		if kern {
+			x += f.kerning(prev, g)
+		}
+		xs[i] = x
+		x += g.advance
+		prev, kern = g, true
+	}
+
+	return xs, x
+}
+
+// split paragraphs into lines no wider than the max width, breaking at spaces if possible.
+// lines are parts of the runes of the text starting at the returned indices
+func (f *Font) wrap(text string, l TextLayout) (lines [][]rune, starts []int) {
+	runes := []rune(text)
+	maxWidth := l.MaxWidth * f.size
+
+	width := func(line []rune) float64 {
+		_, w := f.advances(trimSpaceRight(line), l)
+		return w
+	}
+	add := func(start, end int) {
+		lines = append(lines, trimSpaceRight(runes[start:end]))
+		starts = append(starts, start)
+	}
+
+	for p := 0; p <= len(runes); {
+		end := p
+		for end < len(runes) && runes[end] != '\n' {
+			end++
+		}
+		next := end + 1
+		if end > p && runes[end-1] == '\r' {
+			end--
+		}
+
+		if maxWidth <= 0 {
+			add(p, end)
+			p = next
+			continue
+		}
+
+		start, stop := p, p // current line
+		at := p
+		for _, word := range splitWords(runes[p:end]) {
+			from, to := at, at+len(word)
+			at = to
+
+			if len(trimSpaceRight(runes[start:stop])) == 0 || width(runes[start:to]) <= maxWidth {
+				stop = to
+			} else if unicode.IsSpace(word[0]) {
+				stop = to // trailing spaces do not count
+				continue
+			} else {
+				add(start, stop)
+				start, stop = from, to
+			}
+
+			// break words wider than a line between characters
+			for stop-start > 1 && width(runes[start:stop]) > maxWidth {
+				n := stop - start - 1
+				for n > 1 && width(runes[start:start+n]) > maxWidth {
+					n--
+				}
+				add(start, start+n)
+				start += n
+			}
+		}
+		add(start, stop)
+		p = next
+	}
+
+	return lines, starts
+}
+
+// alternating runs of spaces and words
+func splitWords(runes []rune) [][]rune {
+	var words [][]rune
+	start := 0
+
+	for i := 1; i <= len(runes); i++ {
+		if i == len(runes) || unicode.IsSpace(runes[i]) != unicode.IsSpace(runes[i-1]) {
+			words = append(words, runes[start:i])
+			start = i
+		}
+	}
+
+	return words
+}
+
+func trimSpaceRight(line []rune) []rune {
+	n := len(line)
+	for n > 0 && unicode.IsSpace(line[n-1]) {
+		n--
+	}
+	return line[:n]
+}
+
+// glyph positions and bounds in units of the font size, the first baseline is at y = 0
+func (f *Font) layout(text string, l TextLayout) ([]glyphPlacement, math.Boundary) {
+	lines, starts := f.wrap(text, l)
+	lineHeight := f.lineHeight(l)
+
+	xs := make([][]float64, len(lines))
+	widths := make([]float64, len(lines))
+	var maxWidth float64
+
+	for i, line := range lines {
+		xs[i], widths[i] = f.advances(line, l)
+		maxWidth = m.Max(maxWidth, widths[i])
+	}
+	if l.MaxWidth > 0 {
+		maxWidth = l.MaxWidth * f.size
+	}
+
+	bounds := math.NewBoundary()
+	var placed []glyphPlacement
+
+	for i, line := range lines {
+		var offset float64
+		switch l.Align {
+		case AlignCenter:
+			offset = (maxWidth - widths[i]) / 2
+		case AlignRight:
+			offset = maxWidth - widths[i]
+		}
+
+		y := -float64(i) * lineHeight
+		bounds.AddPoint(math.Vector{offset / f.size, (y + f.ascent) / f.size, 0})
+		bounds.AddPoint(math.Vector{(offset + widths[i]) / f.size, (y + f.descent) / f.size, 0})
+
+		for j, r := range line {
+			if g, found := f.charset[r]; found {
+				placed = append(placed, glyphPlacement{g, offset + xs[i][j], y, starts[i] + j})
+			}
+		}
+	}
+
+	return placed, bounds
+}
+
+// bounds of the text without building it, in units of the font size
+func (f *Font) Measure(text string, l TextLayout) math.Boundary {
+	_, bounds := f.layout(text, l)
+	return bounds
+}
+
+// text mesh in units of the font size with the first baseline at y = 0,
+// glyphs of further atlas pages are children of the mesh
+func (f *Font) Print(text string, l TextLayout) *Mesh {
+	geos := make([]*Geometry, len(f.pages))
+	for i := range geos {
+		geos[i] = NewGeometry()
+	}
+
+	placed, _ := f.layout(text, l)
+	color := math.Color{1, 1, 1}
+
+	for _, p := range placed {
+		f.addGlyph(geos[p.glyph.page], p.glyph, p.x, p.y, color)
+	}
+
+	var mesh *Mesh
+	for i, geo := range geos {
+		if len(geo.faces) == 0 && !(i == len(geos)-1 && mesh == nil) {
+			continue
+		}
+		geo.ComputeBoundary()
+
+		if page := NewMesh(geo, f.pages[i]); mesh == nil {
+			mesh = page
+		} else {
+			mesh.AddChild(page)
+		}
+	}
+
+	return mesh
+}
+
+const (
+	textMinCapacity = 16                    // glyph quads reserved per mesh before the first growth
+	textMaxCapacity = (m.MaxUint16 + 1) / 4 // glyph quads of a mesh within the 16 bit indices
+)
+
+type textPage struct {
+	mesh           *Mesh
+	capacity, used int // glyph quads
+
+	next *textPage // glyphs beyond the capacity limit, same font page
+}
+
+// text that can change every frame, e.g. counters in a hud. the glyphs are written into
+// dynamic geometries that are reused and only grow, the font materials are shared
+type Text struct {
+	*Group
+
+	font   *Font
+	layout TextLayout
+	text   string
+	colors []math.Color
+	bounds math.Boundary
+
+	pages []*textPage // by font page, created when first used
+
+	style     *TextStyle  // of the font if nil
+	materials []*Material // copies of the font materials with the own style
+}
+
+func NewText(font *Font, text string, l TextLayout) *Text {
+	t := &Text{
+		Group:  NewGroup(),
+		font:   font,
+		layout: l,
+		pages:  make([]*textPage, len(font.pages)),
+
+		materials: make([]*Material, len(font.pages)),
+	}
+	t.update(text)
+
+	return t
+}
+
+func (t *Text) Font() *Font {
+	return t.font
+}
+
+func (t *Text) Text() string {
+	return t.text
+}
+
+func (t *Text) SetText(text string) {
+	if text == t.text {
+		return
+	}
+	t.update(text)
+}
+
+func (t *Text) Layout() TextLayout {
+	return t.layout
+}
+
+func (t *Text) SetLayout(l TextLayout) {
+	if l == t.layout {
+		return
+	}
+	t.layout = l
+	t.update(t.text)
+}
+
+// colors of the characters by rune index of the text, white if missing
+func (t *Text) Colors() []math.Color {
+	return t.colors
+}
+
+func (t *Text) SetColors(colors []math.Color) {
+	t.colors = colors
+	t.update(t.text)
+}
+
+func (t *Text) Style() TextStyle {
+	if t.style == nil {
+		return t.font.Style()
+	}
+	return *t.style
+}
+
+// own style instead of the one of the font, needs own materials
+func (t *Text) SetStyle(s TextStyle) {
+	t.style = &s
+
+	for i, page := range t.pages {
+		for ; page != nil; page = page.next {
+			page.mesh.SetMaterial(t.material(i))
+		}
+	}
+}
+
+func (t *Text) material(page int) *Material {
+	if t.style == nil || t.font.pages[page] == nil {
+		return t.font.pages[page]
+	}
+
+	if t.materials[page] == nil {
+		t.materials[page] = t.font.pages[page].Clone()
+	}
+	t.style.apply(t.materials[page])
+
+	return t.materials[page]
+}
+
+// bounds of the text in units of the font size, see Measure
+func (t *Text) Bounds() math.Boundary {
+	return t.bounds
+}
+
+func (t *Text) update(text string) {
+	t.text = text
+
+	placed, bounds := t.font.layout(text, t.layout)
+	t.bounds = bounds
+
+	glyphs := make([][]glyphPlacement, len(t.pages))
+	for _, p := range placed {
+		if p.glyph.w > 0 && p.glyph.h > 0 {
+			glyphs[p.glyph.page] = append(glyphs[p.glyph.page], p)
+		}
+	}
+
+	for i, page := range t.pages {
+		if page == nil {
+			if len(glyphs[i]) == 0 {
+				continue
+			}
+			page = t.newPage(i)
+			t.pages[i] = page
+		}
+
+		t.write(i, page, glyphs[i])
+	}
+}
+
+func (t *Text) newPage(i int) *textPage {
+	geo := NewGeometry()
+	geo.SetUsage(DynamicDrawUsage)
+	page := &textPage{mesh: NewMesh(geo, t.material(i))}
+	t.AddChild(page.mesh)
+
+	return page
+}
+
+// overwrite the quads of a page, unused ones collapse to the origin. Glyphs beyond
+// the capacity limit are written into the next mesh of the same font page
+func (t *Text) write(i int, page *textPage, glyphs []glyphPlacement) {
+	geo := page.mesh.Geometry()
+
+	var rest []glyphPlacement
+	if len(glyphs) > textMaxCapacity {
+		glyphs, rest = glyphs[:textMaxCapacity], glyphs[textMaxCapacity:]
+	}
+
+	if len(glyphs) > page.capacity {
+		capacity := page.capacity
+		if capacity == 0 {
+			capacity = textMinCapacity
+		}
+		for capacity < len(glyphs) {
+			capacity *= 2
+		}
+		capacity = minInt(capacity, textMaxCapacity)
+
+		normal := math.Vector{0, 0, 1}
+		color := math.Color{1, 1, 1}
+		for q := page.capacity; q < capacity; q++ {
+			a := geo.AddVertex(NewVertex(math.Vector{}, normal, math.Vector{}, color))
+			b := geo.AddVertex(NewVertex(math.Vector{}, normal, math.Vector{}, color))
+			c := geo.AddVertex(NewVertex(math.Vector{}, normal, math.Vector{}, color))
+			d := geo.AddVertex(NewVertex(math.Vector{}, normal, math.Vector{}, color))
+
+			geo.AddIndexedFace(a, b, c)
+			geo.AddIndexedFace(c, d, a)
+		}
+		page.capacity = capacity
+	}
+
+	// only the quads used now or before need to be uploaded
+	n := maxInt(len(glyphs), page.used)
+	positions := make([]math.Vector, 4*n)
+	uvs := make([]math.Vector, 4*n)
+	colors := make([]math.Color, 4*n)
+
+	for q, p := range glyphs {
+		ps, us := t.font.glyphQuad(p.glyph, p.x, p.y)
+		copy(positions[4*q:], ps[:])
+		copy(uvs[4*q:], us[:])
+
+		color := math.Color{1, 1, 1}
+		if p.index < len(t.colors) {
+			color = t.colors[p.index]
+		}
+		for v := 0; v < 4; v++ {
+			colors[4*q+v] = color
+		}
+	}
+
+	geo.SetPositions(0, positions...)
+	geo.SetUVs(0, uvs...)
+	geo.SetColors(0, colors...)
+	geo.ComputeBoundary()
+	page.used = len(glyphs)
+
+	// refresh the bounding volume in the scene
+	page.mesh.SetGeometry(geo)
+
+	if len(rest) > 0 && page.next == nil {
+		page.next = t.newPage(i)
+	}
+	if page.next != nil {
+		t.write(i, page.next, rest)
+	}
+}
+
+// releases the geometries, the materials belong to the font
+func (t *Text) Dispose() {
+	for _, page := range t.pages {
+		for ; page != nil; page = page.next {
+			page.mesh.Geometry().Dispose()
+		}
+	}
+}
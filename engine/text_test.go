@@ -0,0 +1,280 @@
+package engine
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/der-antikeks/gisp/math"
+)
+
+// monospace font of 10 pixels per em
+func testFont() *Font {
+	charset := map[rune]Glyph{' ': {advance: 10}}
+	for _, r := range "abcdefghijklmnopqrstuvwxyz" {
+		charset[r] = Glyph{x: 1, y: 2, w: 12, h: 12, left: -1, top: 9, advance: 10}
+	}
+
+	return &Font{
+		pages:     []*Material{nil},
+		pageSizes: []image.Point{{64, 64}},
+		charset:   charset,
+		size:      10,
+		ascent:    8,
+		descent:   -2,
+	}
+}
+
+func TestFont_Wrap(t *testing.T) {
+	tests := []struct {
+		text     string
+		maxWidth float64
+		lines    []string
+	}{
+		{"a  b", 0, []string{"a  b"}},
+		{"ab\ncd\n", 0, []string{"ab", "cd", ""}},
+		{"aaa bbb ccc", 7, []string{"aaa bbb", "ccc"}},
+		{"aaa   bbb", 3, []string{"aaa", "bbb"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"ab abcdefgh", 4, []string{"ab", "abcd", "efgh"}},
+		{"  ab cd", 4, []string{"  ab", "cd"}},
+	}
+
+	f := testFont()
+	for _, c := range tests {
+		lines, _ := f.wrap(c.text, TextLayout{MaxWidth: c.maxWidth})
+
+		equal := len(lines) == len(c.lines)
+		for i := 0; equal && i < len(lines); i++ {
+			equal = string(lines[i]) == c.lines[i]
+		}
+		if !equal {
+			got := make([]string, len(lines))
+			for i, l := range lines {
+				got[i] = string(l)
+			}
+			t.Errorf("wrap(%q, %v) != %q (got %q)", c.text, c.maxWidth, c.lines, got)
+		}
+	}
+}
+
+func TestFont_Measure(t *testing.T) {
+	tests := []struct {
+		text     string
+		layout   TextLayout
+		min, max math.Vector
+	}{
+		{"ab", TextLayout{}, math.Vector{0, -0.2}, math.Vector{2, 0.8}},
+		{"ab\nabc", TextLayout{}, math.Vector{0, -1.2}, math.Vector{3, 0.8}},
+		{"ab\nabc", TextLayout{LineSpacing: 2}, math.Vector{0, -2.2}, math.Vector{3, 0.8}},
+		{"aaa bbb ccc", TextLayout{MaxWidth: 7}, math.Vector{0, -1.2}, math.Vector{7, 0.8}},
+		{"ab", TextLayout{MaxWidth: 7, Align: AlignRight}, math.Vector{5, -0.2}, math.Vector{7, 0.8}},
+		{"a\nabc", TextLayout{Align: AlignCenter}, math.Vector{0, -1.2}, math.Vector{3, 0.8}},
+		{"a\tb", TextLayout{}, math.Vector{0, -0.2}, math.Vector{5, 0.8}},
+		{"ab\tb", TextLayout{TabWidth: 1}, math.Vector{0, -0.2}, math.Vector{4, 0.8}},
+	}
+
+	f := testFont()
+	for _, c := range tests {
+		b := f.Measure(c.text, c.layout)
+		min, max := c.min, c.max
+		min[3], max[3] = 1, 1
+
+		if !b.Min.Equals(min, 6) || !b.Max.Equals(max, 6) {
+			t.Errorf("Measure(%q, %+v) != %v, %v (got %v, %v)", c.text, c.layout, min, max, b.Min, b.Max)
+		}
+	}
+}
+
+func TestFont_Print(t *testing.T) {
+	f := testFont()
+	mesh := f.Print("ab c\nd", TextLayout{Align: AlignRight})
+	geo := mesh.Geometry()
+
+	if len(geo.vertices) != 4*4 || len(geo.faces) != 4*2 {
+		t.Fatalf("Print() vertices %v, faces %v", len(geo.vertices), len(geo.faces))
+	}
+
+	// glyph boxes exceed the advances
+	min, max := math.Vector{-0.1, -1.3, 0, 1}, math.Vector{4.1, 0.9, 0, 1}
+	if b := geo.Boundary(); !b.Min.Equals(min, 6) || !b.Max.Equals(max, 6) {
+		t.Errorf("Print() boundary != %v, %v (got %v, %v)", min, max, b.Min, b.Max)
+	}
+
+	// right aligned second line
+	if p := geo.vertices[12+1].position; !p.Equals(math.Vector{2.9, -0.1}, 6) {
+		t.Errorf("Print() upper left corner of the last glyph != %v (got %v)", math.Vector{2.9, -0.1}, p)
+	}
+
+	if uv := geo.vertices[1].uv; !uv.Equals(math.Vector{1.0 / 64, 2.0 / 64}, 6) {
+		t.Errorf("Print() uv of the upper left corner != %v (got %v)", math.Vector{1.0 / 64, 2.0 / 64}, uv)
+	}
+}
+
+func TestFont_Dispose(t *testing.T) {
+	f := testFont()
+	f.pages = []*Material{
+		{uniforms: map[string]interface{}{"distanceFieldMap": NewTextureFromImage(image.NewRGBA(image.Rect(0, 0, 4, 4)))}},
+		{uniforms: map[string]interface{}{"distanceFieldMap": NewTextureFromImage(image.NewRGBA(image.Rect(0, 0, 4, 4)))}},
+	}
+
+	// pages without program of their own
+	f.Dispose()
+}
+
+func TestText_SetText(t *testing.T) {
+	f := testFont()
+	text := NewText(f, "ab", TextLayout{})
+
+	if len(text.Children()) != 1 {
+		t.Fatalf("NewText() children != 1 (got %v)", len(text.Children()))
+	}
+	page := text.pages[0]
+	geo := page.mesh.Geometry()
+
+	tests := []struct {
+		text           string
+		used, capacity int
+	}{
+		{"abc de", 5, textMinCapacity},
+		{"a", 1, textMinCapacity},
+		{"", 0, textMinCapacity},
+		{"abcdefghijklmnopq", 17, 2 * textMinCapacity},
+		{"abcdefghijklmnopqrstuvwxyz abcdefghijklmnopqrstuvwxyz", 52, 4 * textMinCapacity},
+		{"ab", 2, 4 * textMinCapacity},
+	}
+
+	for _, c := range tests {
+		text.SetText(c.text)
+
+		if text.Text() != c.text || page.used != c.used || page.capacity != c.capacity {
+			t.Errorf("SetText(%q) used %v, capacity %v (got %v, %v)", c.text, c.used, c.capacity, page.used, page.capacity)
+		}
+		if page.mesh.Geometry() != geo {
+			t.Errorf("SetText(%q) replaced the geometry", c.text)
+		}
+		if len(geo.vertices) != 4*c.capacity || len(geo.faces) != 2*c.capacity {
+			t.Errorf("SetText(%q) vertices %v, faces %v", c.text, len(geo.vertices), len(geo.faces))
+		}
+
+		// same glyphs as printed, unused quads collapsed
+		printed := f.Print(c.text, TextLayout{}).Geometry()
+		for i, v := range geo.vertices {
+			want := math.Vector{}
+			if i < len(printed.vertices) {
+				want = printed.vertices[i].position
+			}
+			if v.position.DistanceTo(want) > 1e-9 {
+				t.Errorf("SetText(%q) vertex %v != %v (got %v)", c.text, i, want, v.position)
+				break
+			}
+		}
+
+		if b := text.Bounds(); b != f.Measure(c.text, TextLayout{}) {
+			t.Errorf("SetText(%q) bounds != %v (got %v)", c.text, f.Measure(c.text, TextLayout{}), b)
+		}
+	}
+}
+
+func TestText_SetTextLimit(t *testing.T) {
+	text := NewText(testFont(), "", TextLayout{})
+
+	tests := []struct {
+		glyphs int
+		used   []int // by mesh of the page
+	}{
+		{textMaxCapacity, []int{textMaxCapacity}},
+		{textMaxCapacity + 1, []int{textMaxCapacity, 1}},
+		{2*textMaxCapacity + 5, []int{textMaxCapacity, textMaxCapacity, 5}},
+		{3, []int{3, 0, 0}},
+	}
+
+	for _, c := range tests {
+		text.SetText(strings.Repeat("a", c.glyphs))
+
+		var used []int
+		for page := text.pages[0]; page != nil; page = page.next {
+			used = append(used, page.used)
+			if err := page.mesh.Geometry().Validate(); err != nil {
+				t.Errorf("SetText(%v glyphs) invalid geometry: %v", c.glyphs, err)
+			}
+		}
+		if fmt.Sprint(used) != fmt.Sprint(c.used) {
+			t.Errorf("SetText(%v glyphs) used %v (got %v)", c.glyphs, c.used, used)
+		}
+	}
+	if n := len(text.Children()); n != 3 {
+		t.Errorf("SetText() children != 3 (got %v)", n)
+	}
+}
+
+func TestText_SetColors(t *testing.T) {
+	red, green, blue := math.Color{1, 0, 0}, math.Color{0, 1, 0}, math.Color{0, 0, 1}
+	white := math.Color{1, 1, 1}
+
+	tests := []struct {
+		text     string
+		maxWidth float64
+		colors   []math.Color
+		expected []math.Color // by glyph quad
+	}{
+		{"ab\ncd", 0, []math.Color{red, green, blue, red}, []math.Color{red, green, red, white}},
+		{"ab  cd", 2, []math.Color{red, green, blue, blue, red, green}, []math.Color{red, green, red, green}},
+		{"abcd", 1, []math.Color{blue, blue, green}, []math.Color{blue, blue, green, white}},
+	}
+
+	for _, c := range tests {
+		text := NewText(testFont(), c.text, TextLayout{MaxWidth: c.maxWidth})
+		text.SetColors(c.colors)
+		geo := text.pages[0].mesh.Geometry()
+
+		for q, e := range c.expected {
+			for v := 0; v < 4; v++ {
+				if r := geo.vertices[4*q+v].color; r != e {
+					t.Errorf("SetColors(%q, %v) quad %v != %v (got %v)", c.text, c.colors, q, e, r)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestText_SetStyle(t *testing.T) {
+	f := testFont()
+	f.pages[0] = &Material{uniforms: make(map[string]interface{})}
+	for n, v := range programLibrary["font"].uniforms {
+		f.pages[0].uniforms[n] = v
+	}
+	f.SetStyle(TextStyle{Color: math.Color{1, 1, 1}, Smoothing: 0.25})
+
+	shared := NewText(f, "ab", TextLayout{})
+	styled := NewText(f, "ab", TextLayout{})
+
+	s := f.Style()
+	s.OutlineWidth = 2
+	s.ShadowOpacity = 0.5
+	s.ShadowOffset = math.Vector{1, 2}
+	styled.SetStyle(s)
+
+	if m := shared.pages[0].mesh.Material(); m != f.pages[0] {
+		t.Errorf("NewText() material is not the one of the font")
+	}
+
+	m := styled.pages[0].mesh.Material()
+	if m == f.pages[0] {
+		t.Fatalf("SetStyle() changed the material of the font")
+	}
+	if w := m.Uniform("outlineWidth"); w != 2.0 {
+		t.Errorf("SetStyle() outlineWidth != 2 (got %v)", w)
+	}
+	if o := m.Uniform("shadowOffset"); o != (math.Vector{1, 2}) {
+		t.Errorf("SetStyle() shadowOffset != %v (got %v)", math.Vector{1, 2}, o)
+	}
+	if w := f.pages[0].Uniform("outlineWidth"); w != 0.0 {
+		t.Errorf("SetStyle() outlineWidth of the font != 0 (got %v)", w)
+	}
+	if styled.Style() != s || shared.Style() != f.Style() {
+		t.Errorf("Style() != the set style")
+	}
+}
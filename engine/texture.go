@@ -16,16 +16,163 @@ type Texture interface {
 	Dispose()
 }
 
+// color space of the texture data
+type ColorSpace int
+
+const (
+	SRGB   ColorSpace = iota // gamma encoded colors, converted to linear on sampling
+	Linear                   // raw data like normals, distances or lookup tables
+)
+
+// texture coordinate wrapping
+type TextureWrap int
+
+const (
+	RepeatWrapping         TextureWrap = gl.REPEAT
+	ClampToEdgeWrapping    TextureWrap = gl.CLAMP_TO_EDGE
+	MirroredRepeatWrapping TextureWrap = gl.MIRRORED_REPEAT
+)
+
+// texture magnification and minification, mipmap filters are only valid for minification
+type TextureFilter int
+
+const (
+	NearestFilter              TextureFilter = gl.NEAREST
+	LinearFilter               TextureFilter = gl.LINEAR
+	NearestMipMapNearestFilter TextureFilter = gl.NEAREST_MIPMAP_NEAREST
+	NearestMipMapLinearFilter  TextureFilter = gl.NEAREST_MIPMAP_LINEAR
+	LinearMipMapNearestFilter  TextureFilter = gl.LINEAR_MIPMAP_NEAREST
+	LinearMipMapLinearFilter   TextureFilter = gl.LINEAR_MIPMAP_LINEAR
+)
+
+// sampling parameters shared by all texture types
+type sampler struct {
+	wrapS, wrapT, wrapR  TextureWrap
+	magFilter, minFilter TextureFilter
+	anisotropy           float64
+	colorSpace           ColorSpace
+	needsUpdate          bool
+}
+
+func newSampler(wrap TextureWrap, min TextureFilter) sampler {
+	return sampler{
+		wrapS:      wrap,
+		wrapT:      wrap,
+		wrapR:      wrap,
+		magFilter:  LinearFilter,
+		minFilter:  min,
+		anisotropy: 1,
+
+		colorSpace:  SRGB,
+		needsUpdate: true,
+	}
+}
+
+func (t *sampler) SetColorSpace(c ColorSpace) {
+	t.colorSpace = c
+	t.needsUpdate = true
+}
+
+func (t *sampler) ColorSpace() ColorSpace {
+	return t.colorSpace
+}
+
+func (t *sampler) SetWrap(s, r TextureWrap) {
+	t.wrapS, t.wrapT = s, r
+	t.needsUpdate = true
+}
+
+func (t *sampler) Wrap() (s, r TextureWrap) {
+	return t.wrapS, t.wrapT
+}
+
+// wrapping of the third coordinate of volume and cube textures
+func (t *sampler) SetWrapR(r TextureWrap) {
+	t.wrapR = r
+	t.needsUpdate = true
+}
+
+func (t *sampler) WrapR() TextureWrap {
+	return t.wrapR
+}
+
+// NearestFilter for both keeps pixel art sharp
+func (t *sampler) SetFilter(mag, min TextureFilter) {
+	t.magFilter, t.minFilter = mag, min
+	t.needsUpdate = true
+}
+
+func (t *sampler) Filter() (mag, min TextureFilter) {
+	return t.magFilter, t.minFilter
+}
+
+// anisotropic filtering, 1 disables it, clamped to the maximum supported value
+func (t *sampler) SetAnisotropy(a float64) {
+	if a < 1 {
+		a = 1
+	}
+	t.anisotropy = a
+	t.needsUpdate = true
+}
+
+func (t *sampler) Anisotropy() float64 {
+	return t.anisotropy
+}
+
+func (t *sampler) mipmapped() bool {
+	return t.minFilter != NearestFilter && t.minFilter != LinearFilter
+}
+
+// srgb textures are decoded to linear on sampling
+func (t *sampler) internalFormat() int {
+	if t.colorSpace == SRGB {
+		return gl.SRGB8_ALPHA8
+	}
+	return gl.RGBA
+}
+
+// set texture parameters of the bound texture
+func (t *sampler) apply(target gl.GLenum) {
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_S, int(t.wrapS))
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_T, int(t.wrapT))
+	gl.TexParameteri(target, gl.TEXTURE_WRAP_R, int(t.wrapR))
+
+	gl.TexParameteri(target, gl.TEXTURE_MAG_FILTER, int(t.magFilter))
+	gl.TexParameteri(target, gl.TEXTURE_MIN_FILTER, int(t.minFilter))
+
+	if max := maxAnisotropy(); max > 1 {
+		a := t.anisotropy
+		if a > max {
+			a = max
+		}
+		gl.TexParameterf(target, gl.TEXTURE_MAX_ANISOTROPY_EXT, float32(a))
+	}
+}
+
 type ImageTexture struct {
 	Texture
+	sampler
 
 	buffer      gl.Texture
 	initialized bool
 
-	image                []*image.RGBA
-	wrapS, wrapT         int
-	magFilter, minFilter int
-	needsUpdate          bool
+	image       []*image.RGBA
+	compressed  []ddsLevel
+	compression ddsFormat
+	regions     []textureRegion // changed since the last upload
+
+	path string // loaded from
+}
+
+type textureRegion struct {
+	level int
+	rect  image.Rectangle
+}
+
+func newImageTexture() *ImageTexture {
+	return &ImageTexture{
+		sampler: newSampler(RepeatWrapping, LinearMipMapLinearFilter),
+	}
 }
 
 func LoadTexture(path string) (*ImageTexture, error) {
@@ -36,31 +183,117 @@ func LoadTexture(path string) (*ImageTexture, error) {
 	}
 	defer file.Close()
 
-	// decode png
+	// decode png, jpeg, tga or bmp
 	im, _, err := image.Decode(file)
 	if err != nil {
 		return nil, err
 	}
 
-	t := &ImageTexture{
-		image: make([]*image.RGBA, 1),
+	t := NewTextureFromImage(im)
+	t.path = path
+
+	return t, nil
+}
 
-		wrapS:     gl.REPEAT,
-		wrapT:     gl.REPEAT,
-		magFilter: gl.LINEAR,
-		minFilter: gl.LINEAR_MIPMAP_LINEAR,
+// texture of a copy of the image, mipmaps are generated unless set with SetMipmaps
+func NewTextureFromImage(im image.Image) *ImageTexture {
+	t := newImageTexture()
+	t.image = []*image.RGBA{toRGBA(im)}
 
-		needsUpdate: true,
+	return t
+}
+
+// convert to rgba with origin at 0,0
+func toRGBA(im image.Image) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, im.Bounds().Dx(), im.Bounds().Dy()))
+	draw.Draw(img, img.Bounds(), im, im.Bounds().Min, draw.Src)
+
+	return img
+}
+
+// size of the base level
+func (t *ImageTexture) Size() (w, h int) {
+	switch {
+	case len(t.image) > 0:
+		return t.image[0].Bounds().Dx(), t.image[0].Bounds().Dy()
+	case len(t.compressed) > 0:
+		return t.compressed[0].width, t.compressed[0].height
 	}
-	t.image[0] = image.NewRGBA(im.Bounds())
+	return 0, 0
+}
 
-	// convert to rgba
-	draw.Draw(t.image[0], im.Bounds(), im, image.Pt(0, 0), draw.Src)
+// file the texture was loaded from, empty for generated textures
+func (t *ImageTexture) Path() string {
+	return t.path
+}
 
-	return t, nil
+// number of stored mip levels, 1 if generated
+func (t *ImageTexture) Levels() int {
+	if len(t.compressed) > 0 {
+		return len(t.compressed)
+	}
+	return len(t.image)
+}
+
+// image of a mip level, changes have to be announced with UpdateRegion
+func (t *ImageTexture) Image(level int) *image.RGBA {
+	if level < 0 || level >= len(t.image) {
+		return nil
+	}
+	return t.image[level]
+}
+
+// replace the generated mip levels 1..n with precomputed ones, no levels restore generation
+func (t *ImageTexture) SetMipmaps(levels ...image.Image) {
+	if len(t.image) == 0 {
+		return
+	}
+
+	t.image = t.image[:1]
+	for _, l := range levels {
+		t.image = append(t.image, toRGBA(l))
+	}
+	t.needsUpdate = true
+}
+
+// copy src into the region r of a mip level and upload only the changed pixels,
+// generated mipmaps are regenerated
+func (t *ImageTexture) UpdateRegion(level int, r image.Rectangle, src image.Image, sp image.Point) {
+	dst := t.Image(level)
+	if dst == nil {
+		return
+	}
+
+	r = r.Intersect(dst.Bounds())
+	if r.Empty() {
+		return
+	}
+
+	draw.Draw(dst, r, src, sp, draw.Src)
+	t.regions = append(t.regions, textureRegion{level, r})
 }
 
-func LoadCompressedTexture(path string) (*ImageTexture, error) { return nil, nil }
+// load a dds file with dxt1, dxt3 or dxt5 compression and optional mipmaps
+func LoadCompressedTexture(path string) (*ImageTexture, error) {
+	// load file
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	format, levels, err := decodeDDS(file)
+	if err != nil {
+		return nil, err
+	}
+
+	t := newImageTexture()
+	t.compressed = levels
+	t.compression = format
+	t.path = path
+
+	return t, nil
+}
 
 // init texture buffers
 func (t *ImageTexture) init() {
@@ -79,15 +312,26 @@ func (t *ImageTexture) update() {
 	t.buffer.Bind(gl.TEXTURE_2D)
 
 	// set texture parameters
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, t.wrapS)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, t.wrapT)
+	t.apply(gl.TEXTURE_2D)
 
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, t.magFilter)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, t.minFilter)
+	if len(t.compressed) > 0 {
+		if s3tcSupported() {
+			t.updateCompressed()
+			return
+		}
+
+		// decompress once on the cpu
+		t.image = make([]*image.RGBA, len(t.compressed))
+		for i, l := range t.compressed {
+			t.image[i] = decodeDXT(t.compression, l)
+		}
+		t.compressed = nil
+	}
 
 	// give image(s) to opengl
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
 	for level, img := range t.image {
-		gl.TexImage2D(gl.TEXTURE_2D, level, gl.RGBA,
+		gl.TexImage2D(gl.TEXTURE_2D, level, t.internalFormat(),
 			img.Bounds().Dx(), img.Bounds().Dy(),
 			0, gl.RGBA, gl.UNSIGNED_BYTE, img.Pix)
 	}
@@ -95,11 +339,69 @@ func (t *ImageTexture) update() {
 	// generate mipmaps
 	if len(t.image) == 1 {
 		gl.GenerateMipmap(gl.TEXTURE_2D)
+	} else {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, len(t.image)-1)
+	}
+
+	t.regions = t.regions[:0]
+	t.needsUpdate = false
+}
+
+// upload precompressed mipmap chain
+func (t *ImageTexture) updateCompressed() {
+	format := t.compression.glFormat(t.colorSpace)
+
+	for level, l := range t.compressed {
+		gl.CompressedTexImage2D(gl.TEXTURE_2D, level, format,
+			l.width, l.height, 0, len(l.data), l.data)
 	}
 
+	// incomplete chains are limited, mipmaps of compressed textures are not generated
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, len(t.compressed)-1)
+
 	t.needsUpdate = false
 }
 
+// upload changed regions of an already bound texture
+func (t *ImageTexture) updateRegions() {
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+
+	for _, r := range t.regions {
+		img := t.image[r.level]
+
+		// row length in pixels of the whole image
+		gl.PixelStorei(gl.UNPACK_ROW_LENGTH, img.Stride/4)
+		gl.TexSubImage2D(gl.TEXTURE_2D, r.level,
+			r.rect.Min.X, r.rect.Min.Y, r.rect.Dx(), r.rect.Dy(),
+			gl.RGBA, gl.UNSIGNED_BYTE, img.Pix[img.PixOffset(r.rect.Min.X, r.rect.Min.Y):])
+	}
+	gl.PixelStorei(gl.UNPACK_ROW_LENGTH, 0)
+
+	if len(t.image) == 1 {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+
+	t.regions = t.regions[:0]
+}
+
+var anisotropySupport struct {
+	checked bool
+	max     float64
+}
+
+// needs a current context
+func maxAnisotropy() float64 {
+	if !anisotropySupport.checked {
+		var max [1]float32
+		gl.GetFloatv(gl.MAX_TEXTURE_MAX_ANISOTROPY_EXT, max[:])
+
+		anisotropySupport.max = float64(max[0])
+		anisotropySupport.checked = true
+	}
+
+	return anisotropySupport.max
+}
+
 // cleanup
 func (t *ImageTexture) Dispose() {
 	if t.buffer != 0 {
@@ -109,13 +411,17 @@ func (t *ImageTexture) Dispose() {
 
 // bind texture in Texture Unit slot
 func (t *ImageTexture) Bind(slot int) {
+	gl.ActiveTexture(gl.TEXTURE0 + gl.GLenum(slot))
+
 	if t.needsUpdate {
 		t.update()
 	} else {
 		t.buffer.Bind(gl.TEXTURE_2D)
-	}
 
-	gl.ActiveTexture(gl.TEXTURE0 + gl.GLenum(slot))
+		if len(t.regions) > 0 {
+			t.updateRegions()
+		}
+	}
 }
 
 func (t *ImageTexture) Unbind() {
@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestImageTexture_UpdateRegion(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(10, 10, 18, 14))
+	src.Set(10, 10, color.NRGBA{255, 0, 0, 255})
+
+	tex := NewTextureFromImage(src)
+	if w, h := tex.Size(); w != 8 || h != 4 {
+		t.Errorf("Size() != 8, 4 (got %v, %v)", w, h)
+	}
+	if c := tex.Image(0).At(0, 0); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Image(0).At(0, 0) != red (got %v)", c)
+	}
+
+	tex.SetMipmaps(image.NewRGBA(image.Rect(0, 0, 4, 2)), image.NewRGBA(image.Rect(0, 0, 2, 1)))
+	if l := tex.Levels(); l != 3 {
+		t.Errorf("Levels() != 3 (got %v)", l)
+	}
+
+	tests := []struct {
+		level   int
+		rect    image.Rectangle
+		regions int
+		changed image.Rectangle
+	}{
+		{0, image.Rect(2, 1, 4, 3), 1, image.Rect(2, 1, 4, 3)},
+		{1, image.Rect(2, 0, 10, 10), 1, image.Rect(2, 0, 4, 2)}, // clipped
+		{0, image.Rect(20, 20, 30, 30), 0, image.Rectangle{}},    // outside
+		{5, image.Rect(0, 0, 1, 1), 0, image.Rectangle{}},        // missing level
+	}
+
+	blue := image.NewUniform(color.RGBA{0, 0, 255, 255})
+	for _, c := range tests {
+		tex.regions = tex.regions[:0]
+		tex.UpdateRegion(c.level, c.rect, blue, image.Point{})
+
+		if len(tex.regions) != c.regions {
+			t.Errorf("UpdateRegion(%v, %v) regions != %v (got %v)", c.level, c.rect, c.regions, len(tex.regions))
+			continue
+		}
+		if c.regions > 0 {
+			if r := tex.regions[0].rect; r != c.changed {
+				t.Errorf("UpdateRegion(%v, %v) != %v (got %v)", c.level, c.rect, c.changed, r)
+			}
+			if p := tex.Image(c.level).At(c.changed.Min.X, c.changed.Min.Y); p != blue.C {
+				t.Errorf("UpdateRegion(%v, %v) pixel != blue (got %v)", c.level, c.rect, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+)
+
+// truevision targa decoder, uncompressed and run length encoded
+// true color (24/32 bit) and grayscale images, color mapped images are not supported
+// http://www.dca.fa.cdcrafts.com/tga.pdf
+
+const (
+	tgaTrueColor    = 2
+	tgaGray         = 3
+	tgaTrueColorRLE = 10
+	tgaGrayRLE      = 11
+
+	tgaRightToLeft = 0x10
+	tgaTopToBottom = 0x20
+
+	tgaMaxDimensions = 1 << 14 // checked before allocating the image
+)
+
+type tgaHeader struct {
+	IDLength        uint8
+	ColorMapType    uint8
+	ImageType       uint8
+	ColorMapOrigin  uint16
+	ColorMapLength  uint16
+	ColorMapDepth   uint8
+	XOrigin         uint16
+	YOrigin         uint16
+	Width           uint16
+	Height          uint16
+	PixelDepth      uint8
+	ImageDescriptor uint8
+}
+
+func init() {
+	for _, t := range []byte{tgaTrueColor, tgaGray, tgaTrueColorRLE, tgaGrayRLE} {
+		image.RegisterFormat("tga", string([]byte{'?', 0, t}), decodeTGA, decodeTGAConfig)
+	}
+}
+
+func readTGAHeader(r io.Reader) (tgaHeader, error) {
+	var h tgaHeader
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return h, err
+	}
+
+	if h.ColorMapType != 0 {
+		return h, errors.New("tga: color mapped images are not supported")
+	}
+
+	if h.Width > tgaMaxDimensions || h.Height > tgaMaxDimensions {
+		return h, fmt.Errorf("tga: dimensions %vx%v exceed %v", h.Width, h.Height, tgaMaxDimensions)
+	}
+
+	switch h.ImageType {
+	case tgaTrueColor, tgaTrueColorRLE:
+		if h.PixelDepth != 24 && h.PixelDepth != 32 {
+			return h, fmt.Errorf("tga: unsupported pixel depth %v", h.PixelDepth)
+		}
+	case tgaGray, tgaGrayRLE:
+		if h.PixelDepth != 8 {
+			return h, fmt.Errorf("tga: unsupported grayscale depth %v", h.PixelDepth)
+		}
+	default:
+		return h, fmt.Errorf("tga: unsupported image type %v", h.ImageType)
+	}
+
+	return h, nil
+}
+
+func decodeTGAConfig(r io.Reader) (image.Config, error) {
+	h, err := readTGAHeader(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	m := color.NRGBAModel
+	if h.PixelDepth == 8 {
+		m = color.GrayModel
+	}
+
+	return image.Config{
+		ColorModel: m,
+		Width:      int(h.Width),
+		Height:     int(h.Height),
+	}, nil
+}
+
+func decodeTGA(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+
+	h, err := readTGAHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	// skip image id
+	if _, err := io.CopyN(ioutil.Discard, br, int64(h.IDLength)); err != nil {
+		return nil, err
+	}
+
+	w, ht := int(h.Width), int(h.Height)
+	bpp := int(h.PixelDepth) / 8
+	rle := h.ImageType == tgaTrueColorRLE || h.ImageType == tgaGrayRLE
+
+	// pixels in file order
+	data := make([]byte, w*ht*bpp)
+	if rle {
+		err = readTGARLE(br, data, bpp)
+	} else {
+		_, err = io.ReadFull(br, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var img image.Image
+	var pix []byte
+	var stride, channels int
+
+	if bpp == 1 {
+		g := image.NewGray(image.Rect(0, 0, w, ht))
+		img, pix, stride, channels = g, g.Pix, g.Stride, 1
+	} else {
+		n := image.NewNRGBA(image.Rect(0, 0, w, ht))
+		img, pix, stride, channels = n, n.Pix, n.Stride, 4
+	}
+
+	// default origin is bottom left
+	for y := 0; y < ht; y++ {
+		dy := ht - 1 - y
+		if h.ImageDescriptor&tgaTopToBottom != 0 {
+			dy = y
+		}
+
+		for x := 0; x < w; x++ {
+			dx := x
+			if h.ImageDescriptor&tgaRightToLeft != 0 {
+				dx = w - 1 - x
+			}
+
+			s := data[(y*w+x)*bpp:]
+			d := pix[dy*stride+dx*channels:]
+
+			switch bpp {
+			case 1:
+				d[0] = s[0]
+			case 3:
+				d[0], d[1], d[2], d[3] = s[2], s[1], s[0], 255
+			case 4:
+				d[0], d[1], d[2], d[3] = s[2], s[1], s[0], s[3]
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// run length packets may cross scanlines
+func readTGARLE(r *bufio.Reader, data []byte, bpp int) error {
+	pixel := make([]byte, bpp)
+
+	for i := 0; i < len(data); {
+		c, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		n := (int(c&0x7f) + 1) * bpp
+		if i+n > len(data) {
+			return errors.New("tga: run length packet exceeds image")
+		}
+
+		if c&0x80 != 0 {
+			// repeated pixel
+			if _, err := io.ReadFull(r, pixel); err != nil {
+				return err
+			}
+			for j := 0; j < n; j += bpp {
+				copy(data[i+j:], pixel)
+			}
+		} else {
+			// raw pixels
+			if _, err := io.ReadFull(r, data[i:i+n]); err != nil {
+				return err
+			}
+		}
+
+		i += n
+	}
+
+	return nil
+}
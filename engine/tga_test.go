@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"testing"
+)
+
+func loadNRGBA(path string, t *testing.T) *image.NRGBA {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	im, _, err := image.Decode(file)
+	if err != nil {
+		t.Fatalf("image.Decode(%v) error: %v", path, err)
+	}
+
+	img := image.NewNRGBA(im.Bounds())
+	draw.Draw(img, img.Bounds(), im, image.Pt(0, 0), draw.Src)
+	return img
+}
+
+func TestDecodeTGA_Asset(t *testing.T) {
+	// rle compressed copy of the png
+	tga := loadNRGBA("../assets/fighter/fighter.tga", t)
+	png := loadNRGBA("../assets/fighter/fighter.png", t)
+
+	if !tga.Bounds().Eq(png.Bounds()) || !bytes.Equal(tga.Pix, png.Pix) {
+		t.Errorf("fighter.tga differs from fighter.png")
+	}
+}
+
+func TestDecodeTGA(t *testing.T) {
+	red, blue := []byte{0, 0, 255}, []byte{255, 0, 0} // bgr
+
+	tests := []struct {
+		name       string
+		header     []byte // type, depth, descriptor
+		data       []byte
+		topLeft    color.Color
+		bottomLeft color.Color
+	}{
+		{
+			"24 bit bottom up",
+			[]byte{tgaTrueColor, 24, 0},
+			bytes.Join([][]byte{red, red, blue, blue}, nil),
+			color.NRGBA{0, 0, 255, 255},
+			color.NRGBA{255, 0, 0, 255},
+		},
+		{
+			"32 bit top down",
+			[]byte{tgaTrueColor, 32, tgaTopToBottom | 8},
+			[]byte{0, 0, 255, 128, 0, 0, 255, 128, 255, 0, 0, 64, 255, 0, 0, 64},
+			color.NRGBA{255, 0, 0, 128},
+			color.NRGBA{0, 0, 255, 64},
+		},
+		{
+			"24 bit rle across scanlines",
+			[]byte{tgaTrueColorRLE, 24, 0},
+			bytes.Join([][]byte{{0x00}, red, {0x82}, blue}, nil),
+			color.NRGBA{0, 0, 255, 255},
+			color.NRGBA{255, 0, 0, 255},
+		},
+		{
+			"8 bit gray rle right to left",
+			[]byte{tgaGrayRLE, 8, tgaRightToLeft},
+			[]byte{0x81, 10, 0x01, 20, 30},
+			color.Gray{30},
+			color.Gray{10},
+		},
+	}
+
+	for _, c := range tests {
+		file := []byte{0, 0, c.header[0], 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 2, 0, c.header[1], c.header[2]}
+		img, format, err := image.Decode(bytes.NewReader(append(file, c.data...)))
+		if err != nil || format != "tga" {
+			t.Errorf("image.Decode(%v) != tga (got %v, %v)", c.name, format, err)
+			continue
+		}
+
+		if tl := img.At(0, 0); tl != c.topLeft {
+			t.Errorf("%v top left != %v (got %v)", c.name, c.topLeft, tl)
+		}
+		if bl := img.At(0, 1); bl != c.bottomLeft {
+			t.Errorf("%v bottom left != %v (got %v)", c.name, c.bottomLeft, bl)
+		}
+	}
+}
+
+func TestDecodeTGA_Dimensions(t *testing.T) {
+	file := []byte{0, 0, tgaTrueColor, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 32, 0}
+	if _, _, err := image.Decode(bytes.NewReader(file)); err == nil {
+		t.Errorf("image.Decode(65535x65535) != error")
+	}
+}
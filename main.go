@@ -16,6 +16,7 @@ import (
 var (
 	renderer *engine.Renderer
 	controls engine.Control
+	fpsText  *engine.Text
 )
 
 func main() {
@@ -90,40 +91,15 @@ func main() {
 	renderer.AddPass(pass)
 
 	// main loop
-	lastTime := time.Now()
-	meanDelta := 0.0
-	frames := 0
-
-	renderTicker := time.Tick(time.Duration(1000/72) * time.Millisecond)
-	worldUpdateTicker := time.Tick(time.Duration(5) * time.Second)
-	worldCleanupTicker := time.Tick(time.Duration(10) * time.Second)
-
-	for renderer.Running() {
-		select {
-		case <-worldUpdateTicker: //go updateWorld()
-		case <-worldCleanupTicker: //go cleanupWorld()
-		case <-renderTicker:
-			//default: // override fps limiter
-
-			// calc fps
-			currentTime := time.Now()
-			delta := currentTime.Sub(lastTime)
-			lastTime = currentTime
-
-			meanDelta = meanDelta*0.9 + delta.Seconds()*0.1
-
-			if frames++; frames%50 == 0 {
-				fmt.Println("fps: ", 1.0/meanDelta)
-				frames = 0
-			}
-
-			// animate
-			update(delta)
-
-			// draw
-			renderer.Render()
+	app := engine.NewApp(renderer)
+	app.SetFrameLimit(72)
+	app.SetUpdateCallback(update)
+	app.SetRenderCallback(func(alpha float64) {
+		if stats := app.Stats(); stats.Frames%50 == 0 {
+			fpsText.SetText(fmt.Sprintf("fps: %.1f", stats.FPS))
 		}
-	}
+	})
+	app.Run()
 }
 
 func onKeyPress(key glfw.Key, action glfw.Action, mods glfw.ModifierKey) {
@@ -270,13 +246,13 @@ func generateScene() (*engine.Scene, engine.Camera) {
 	moon.AddChild(moon2)
 
 	// font
-	font, err := engine.LoadFont("assets/luxisr.ttf")
+	font, err := engine.LoadFont("assets/luxisr.ttf", engine.FontOptions{})
 	if err != nil {
 		log.Fatalf("could not load font: %v\n", err)
 	}
 	fontMesh := font.Printf("Another Font Test!")
 	fontMesh.SetPosition(math.Vector{0, 0, 1})
-	fontMesh.SetScale(math.Vector{5, 5, 5})
+	fontMesh.SetScale(math.Vector{0.5, 0.5, 0.5})
 	rotatingCube.AddChild(fontMesh)
 
 	// billboard
@@ -331,17 +307,21 @@ func generateHud() (*engine.Scene, engine.Camera) {
 	planeL.SetPosition(math.Vector{-75, -75, 0})
 
 	// font
-	font, err := engine.LoadFont("assets/luxisr.ttf")
+	font, err := engine.LoadFont("assets/luxisr.ttf", engine.FontOptions{})
 	if err != nil {
 		log.Fatalf("could not load font: %v\n", err)
 	}
 	fontMesh := font.Printf("Testing Font 012345679")
 	fontMesh.SetPosition(math.Vector{-100, 90, 0})
-	fontMesh.SetScale(math.Vector{100, 100, 100})
+	fontMesh.SetScale(math.Vector{8, 8, 8})
+
+	fpsText = engine.NewText(font, "fps:", engine.TextLayout{})
+	fpsText.SetPosition(math.Vector{-100, 70, 0})
+	fpsText.SetScale(math.Vector{8, 8, 8})
 
 	// scene
 	scene := engine.NewScene()
-	scene.AddChild(planeR, planeL, fontMesh)
+	scene.AddChild(planeR, planeL, fontMesh, fpsText)
 
 	return scene, camera
 }
@@ -54,3 +54,48 @@ func (b Boundary) Size() Vector {
 func (b Boundary) Sphere() (center Vector, radius float64) {
 	return b.Center(), b.Size().Length() * 0.5
 }
+
+func BoundaryFromSphere(center Vector, radius float64) Boundary {
+	return Boundary{
+		Min: Vector{center[0] - radius, center[1] - radius, center[2] - radius, 1},
+		Max: Vector{center[0] + radius, center[1] + radius, center[2] + radius, 1},
+	}
+}
+
+func (b Boundary) Expand(d float64) Boundary {
+	return Boundary{
+		Min: Vector{b.Min[0] - d, b.Min[1] - d, b.Min[2] - d, 1},
+		Max: Vector{b.Max[0] + d, b.Max[1] + d, b.Max[2] + d, 1},
+	}
+}
+
+func (b Boundary) Union(a Boundary) Boundary {
+	return Boundary{
+		Min: Vector{math.Min(b.Min[0], a.Min[0]), math.Min(b.Min[1], a.Min[1]), math.Min(b.Min[2], a.Min[2]), 1},
+		Max: Vector{math.Max(b.Max[0], a.Max[0]), math.Max(b.Max[1], a.Max[1]), math.Max(b.Max[2], a.Max[2]), 1},
+	}
+}
+
+func (b Boundary) ContainsPoint(p Vector) bool {
+	return p[0] >= b.Min[0] && p[0] <= b.Max[0] &&
+		p[1] >= b.Min[1] && p[1] <= b.Max[1] &&
+		p[2] >= b.Min[2] && p[2] <= b.Max[2]
+}
+
+// is a completely inside of b
+func (b Boundary) ContainsBoundary(a Boundary) bool {
+	return a.Min[0] >= b.Min[0] && a.Max[0] <= b.Max[0] &&
+		a.Min[1] >= b.Min[1] && a.Max[1] <= b.Max[1] &&
+		a.Min[2] >= b.Min[2] && a.Max[2] <= b.Max[2]
+}
+
+func (b Boundary) IntersectsBoundary(a Boundary) bool {
+	return a.Max[0] >= b.Min[0] && a.Min[0] <= b.Max[0] &&
+		a.Max[1] >= b.Min[1] && a.Min[1] <= b.Max[1] &&
+		a.Max[2] >= b.Min[2] && a.Min[2] <= b.Max[2]
+}
+
+func (b Boundary) SurfaceArea() float64 {
+	s := b.Size()
+	return 2.0 * (s[0]*s[1] + s[1]*s[2] + s[2]*s[0])
+}
@@ -241,3 +241,158 @@ func TestBoundary_Sphere(t *testing.T) {
 		}
 	}
 }
+
+func TestBoundaryFromSphere(t *testing.T) {
+	tests := []struct {
+		Center   Vector
+		Radius   float64
+		Expected Boundary
+	}{
+		{
+			Vector{0, 0, 0},
+			0,
+			Boundary{Vector{0, 0, 0, 1}, Vector{0, 0, 0, 1}},
+		},
+		{
+			Vector{1, 2, 3},
+			2,
+			Boundary{Vector{-1, 0, 1, 1}, Vector{3, 4, 5, 1}},
+		},
+	}
+
+	for _, c := range tests {
+		if r := BoundaryFromSphere(c.Center, c.Radius); !r.Equals(c.Expected, 6) {
+			t.Errorf("BoundaryFromSphere(%v, %v) != %v (got %v)", c.Center, c.Radius, c.Expected, r)
+		}
+	}
+}
+
+func TestBoundary_Expand(t *testing.T) {
+	tests := []struct {
+		B        Boundary
+		D        float64
+		Expected Boundary
+	}{
+		{
+			BoundaryFromPoints(Vector{0, 0, 0}),
+			1,
+			Boundary{Vector{-1, -1, -1, 1}, Vector{1, 1, 1, 1}},
+		},
+		{
+			BoundaryFromPoints(Vector{-1, 0, 0}, Vector{1, 2, 3}),
+			0.5,
+			Boundary{Vector{-1.5, -0.5, -0.5, 1}, Vector{1.5, 2.5, 3.5, 1}},
+		},
+	}
+
+	for _, c := range tests {
+		if r := c.B.Expand(c.D); !r.Equals(c.Expected, 6) {
+			t.Errorf("Boundary(%v).Expand(%v) != %v (got %v)", c.B, c.D, c.Expected, r)
+		}
+	}
+}
+
+func TestBoundary_Union(t *testing.T) {
+	tests := []struct {
+		A, B     Boundary
+		Expected Boundary
+	}{
+		{
+			NewBoundary(),
+			BoundaryFromPoints(Vector{1, 2, 3}),
+			Boundary{Vector{1, 2, 3, 1}, Vector{1, 2, 3, 1}},
+		},
+		{
+			BoundaryFromPoints(Vector{-1, 0, 0}, Vector{0, 1, 0}),
+			BoundaryFromPoints(Vector{2, -2, -1}, Vector{3, 0, 1}),
+			Boundary{Vector{-1, -2, -1, 1}, Vector{3, 1, 1, 1}},
+		},
+	}
+
+	for _, c := range tests {
+		if r := c.A.Union(c.B); !r.Equals(c.Expected, 6) {
+			t.Errorf("Boundary(%v).Union(%v) != %v (got %v)", c.A, c.B, c.Expected, r)
+		}
+	}
+}
+
+func TestBoundary_ContainsPoint(t *testing.T) {
+	b := BoundaryFromPoints(Vector{-1, -1, -1}, Vector{1, 1, 1})
+
+	tests := []struct {
+		Point    Vector
+		Expected bool
+	}{
+		{Vector{0, 0, 0}, true},
+		{Vector{1, 1, 1}, true},
+		{Vector{-1, 0.5, 0}, true},
+		{Vector{1.1, 0, 0}, false},
+		{Vector{0, -2, 0}, false},
+	}
+
+	for _, c := range tests {
+		if r := b.ContainsPoint(c.Point); r != c.Expected {
+			t.Errorf("Boundary(%v).ContainsPoint(%v) != %v (got %v)", b, c.Point, c.Expected, r)
+		}
+	}
+}
+
+func TestBoundary_ContainsBoundary(t *testing.T) {
+	b := BoundaryFromPoints(Vector{-1, -1, -1}, Vector{1, 1, 1})
+
+	tests := []struct {
+		A        Boundary
+		Expected bool
+	}{
+		{BoundaryFromPoints(Vector{0, 0, 0}), true},
+		{BoundaryFromPoints(Vector{-1, -1, -1}, Vector{1, 1, 1}), true},
+		{BoundaryFromPoints(Vector{-0.5, 0, 0}, Vector{0.5, 0.5, 0.5}), true},
+		{BoundaryFromPoints(Vector{0, 0, 0}, Vector{2, 0, 0}), false},
+		{BoundaryFromPoints(Vector{2, 2, 2}, Vector{3, 3, 3}), false},
+	}
+
+	for _, c := range tests {
+		if r := b.ContainsBoundary(c.A); r != c.Expected {
+			t.Errorf("Boundary(%v).ContainsBoundary(%v) != %v (got %v)", b, c.A, c.Expected, r)
+		}
+	}
+}
+
+func TestBoundary_IntersectsBoundary(t *testing.T) {
+	b := BoundaryFromPoints(Vector{-1, -1, -1}, Vector{1, 1, 1})
+
+	tests := []struct {
+		A        Boundary
+		Expected bool
+	}{
+		{BoundaryFromPoints(Vector{0, 0, 0}), true},
+		{BoundaryFromPoints(Vector{0, 0, 0}, Vector{2, 0, 0}), true},
+		{BoundaryFromPoints(Vector{1, 1, 1}, Vector{2, 2, 2}), true},
+		{BoundaryFromPoints(Vector{-3, -3, -3}, Vector{3, 3, 3}), true},
+		{BoundaryFromPoints(Vector{2, 2, 2}, Vector{3, 3, 3}), false},
+		{BoundaryFromPoints(Vector{-3, 0, 0}, Vector{-2, 0, 0}), false},
+	}
+
+	for _, c := range tests {
+		if r := b.IntersectsBoundary(c.A); r != c.Expected {
+			t.Errorf("Boundary(%v).IntersectsBoundary(%v) != %v (got %v)", b, c.A, c.Expected, r)
+		}
+	}
+}
+
+func TestBoundary_SurfaceArea(t *testing.T) {
+	tests := []struct {
+		B        Boundary
+		Expected float64
+	}{
+		{BoundaryFromPoints(Vector{0, 0, 0}), 0},
+		{BoundaryFromPoints(Vector{-1, -1, -1}, Vector{1, 1, 1}), 24},
+		{BoundaryFromPoints(Vector{0, 0, 0}, Vector{1, 2, 3}), 22},
+	}
+
+	for _, c := range tests {
+		if r := c.B.SurfaceArea(); !NearlyEquals(r, c.Expected, 0.000001) {
+			t.Errorf("Boundary(%v).SurfaceArea() != %v (got %v)", c.B, c.Expected, r)
+		}
+	}
+}
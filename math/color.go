@@ -88,3 +88,36 @@ func (c Color) Normalize() Color {
 		B: c.B / 255.0,
 	}
 }
+
+// convert gamma encoded srgb to linear color space
+// http://en.wikipedia.org/wiki/SRGB
+func (c Color) Linear() Color {
+	return Color{
+		R: srgbToLinear(c.R),
+		G: srgbToLinear(c.G),
+		B: srgbToLinear(c.B),
+	}
+}
+
+// convert linear to gamma encoded srgb color space
+func (c Color) SRGB() Color {
+	return Color{
+		R: linearToSRGB(c.R),
+		G: linearToSRGB(c.G),
+		B: linearToSRGB(c.B),
+	}
+}
+
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1.0/2.4) - 0.055
+}
@@ -198,3 +198,55 @@ func TestColor_Normalize(t *testing.T) {
 	}
 
 }
+
+func TestColor_Linear(t *testing.T) {
+	tests := []struct {
+		Value    Color
+		Expected Color
+	}{
+		{
+			Color{0, 0, 0},
+			Color{0, 0, 0},
+		},
+		{
+			Color{1, 1, 1},
+			Color{1, 1, 1},
+		},
+		{
+			Color{0.5, 0.04, 0.8},
+			Color{0.21404114, 0.04 / 12.92, 0.60382734},
+		},
+	}
+
+	for _, c := range tests {
+		if r := c.Value.Linear(); !r.Equals(c.Expected, 6) {
+			t.Errorf("Color(%v).Linear() != %v (got %v)", c.Value, c.Expected, r)
+		}
+	}
+}
+
+func TestColor_SRGB(t *testing.T) {
+	tests := []struct {
+		Value    Color
+		Expected Color
+	}{
+		{
+			Color{0, 0, 0},
+			Color{0, 0, 0},
+		},
+		{
+			Color{1, 1, 1},
+			Color{1, 1, 1},
+		},
+		{
+			Color{0.21404114, 0.002, 0.60382734},
+			Color{0.5, 0.002 * 12.92, 0.8},
+		},
+	}
+
+	for _, c := range tests {
+		if r := c.Value.SRGB(); !r.Equals(c.Expected, 6) {
+			t.Errorf("Color(%v).SRGB() != %v (got %v)", c.Value, c.Expected, r)
+		}
+	}
+}
@@ -52,3 +52,24 @@ func (f Frustum) IntersectsSphere(center Vector, radius float64) bool {
 
 	return true
 }
+
+// conservative test, may report boxes near the frustum corners as intersecting
+func (f Frustum) IntersectsBoundary(b Boundary) bool {
+	for _, p := range f {
+		// corner of the box farthest along the plane normal
+		var v Vector
+		for i := 0; i < 3; i++ {
+			if p.normal[i] >= 0 {
+				v[i] = b.Max[i]
+			} else {
+				v[i] = b.Min[i]
+			}
+		}
+
+		if v.Dot(p.normal)+p.distance < 0 {
+			return false
+		}
+	}
+
+	return true
+}
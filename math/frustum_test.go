@@ -9,3 +9,27 @@ func TestPlane_Normalize(t *testing.T)          {}
 func TestFrustumFromMatrix(t *testing.T)        {}
 func TestFrustum_ContainsPoint(t *testing.T)    {}
 func TestFrustum_IntersectsSphere(t *testing.T) {}
+
+func TestFrustum_IntersectsBoundary(t *testing.T) {
+	// unit cube
+	f := FrustumFromMatrix(NewOrthoMatrix(-1, 1, -1, 1, -1, 1))
+
+	tests := []struct {
+		B        Boundary
+		Expected bool
+	}{
+		{BoundaryFromPoints(Vector{0, 0, 0}), true},
+		{BoundaryFromPoints(Vector{-0.5, -0.5, -0.5}, Vector{0.5, 0.5, 0.5}), true},
+		{BoundaryFromPoints(Vector{-5, -5, -5}, Vector{5, 5, 5}), true},
+		{BoundaryFromPoints(Vector{0.5, 0, 0}, Vector{2, 0.5, 0.5}), true},
+		{BoundaryFromPoints(Vector{2, 0, 0}, Vector{3, 1, 1}), false},
+		{BoundaryFromPoints(Vector{0, -3, 0}, Vector{0, -2, 0}), false},
+		{BoundaryFromPoints(Vector{0, 0, 1.5}, Vector{0, 0, 2}), false},
+	}
+
+	for _, c := range tests {
+		if r := f.IntersectsBoundary(c.B); r != c.Expected {
+			t.Errorf("Frustum.IntersectsBoundary(%v) != %v (got %v)", c.B, c.Expected, r)
+		}
+	}
+}
@@ -334,3 +334,25 @@ func NewOrthoMatrix(left, right, bottom, top, near, far float64) Matrix {
 func ComposeMatrix(position Vector, rotation Quaternion, scale Vector) Matrix {
 	return Identity().Translate(position).Mul(rotation.RotationMatrix()).Scale(scale)
 }
+
+// inverse of ComposeMatrix for matrices without shear, a mirroring is moved to the x scale
+func (self Matrix) Decompose() (position Vector, rotation Quaternion, scale Vector) {
+	position = self.ExtractPosition()
+	scale = self.ExtractScale()
+	if self.Determinant() < 0 {
+		scale[0] = -scale[0]
+	}
+
+	r := Identity()
+	for c := 0; c < 3; c++ {
+		if scale[c] == 0 {
+			continue
+		}
+		for i := 0; i < 3; i++ {
+			r[c*4+i] = self[c*4+i] / scale[c]
+		}
+	}
+	rotation = QuaternionFromRotationMatrix(r).Normalize()
+
+	return
+}
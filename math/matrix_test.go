@@ -836,6 +836,28 @@ func TestMatrix_ComposeMatrix(t *testing.T) {
 	}
 }
 
+func TestMatrix_Decompose(t *testing.T) {
+	tests := []struct {
+		Position Vector
+		Rotation Quaternion
+		Scale    Vector
+	}{
+		{Vector{0, 0, 0}, Quaternion{0, 0, 0, 1}, Vector{1, 1, 1}},
+		{Vector{10, -2, 3}, QuaternionFromEuler(Vector{0, 90 * DEG2RAD, 0}, DefaultOrder), Vector{2, 2, 2}},
+		{Vector{1, 2, 3}, QuaternionFromAxisAngle(Vector{1, 2, 3}, 2.5), Vector{1, 3, 0.5}},
+		{Vector{0, 0, 0}, QuaternionFromAxisAngle(Vector{0, 1, 1}, -1), Vector{-2, 1, 1}}, // mirrored
+	}
+
+	for _, c := range tests {
+		p, r, s := ComposeMatrix(c.Position, c.Rotation, c.Scale).Decompose()
+
+		// q and -q are the same rotation, compared by the composed matrices
+		if p.DistanceTo(c.Position) > 1e-6 || s.DistanceTo(c.Scale) > 1e-6 || !ComposeMatrix(p, r, s).Equals(ComposeMatrix(c.Position, c.Rotation, c.Scale), 4) {
+			t.Errorf("Decompose(P: %v, R: %v, S: %v) != (got P: %v, R: %v, S: %v)", c.Position, c.Rotation, c.Scale, p, r, s)
+		}
+	}
+}
+
 // TODO:
 func TestMatrix_String(t *testing.T)          {}
 func TestMatrix_Float32(t *testing.T)         {}
@@ -0,0 +1,77 @@
+package math
+
+import (
+	"math"
+)
+
+type Ray struct {
+	Origin, Direction Vector
+}
+
+func NewRay(origin, direction Vector) Ray {
+	return Ray{
+		Origin:    Vector{origin[0], origin[1], origin[2], 1},
+		Direction: Vector{direction[0], direction[1], direction[2], 0}.Normalize(),
+	}
+}
+
+func (r Ray) At(t float64) Vector {
+	return r.Origin.Add(r.Direction.MulScalar(t))
+}
+
+// returns the distance along the ray to the entry point of the box,
+// or 0 if the origin lies within it
+// http://www.cs.utah.edu/~awilliam/box/box.pdf
+func (r Ray) IntersectsBoundary(b Boundary) (float64, bool) {
+	tmin, tmax := 0.0, math.Inf(1)
+
+	for i := 0; i < 3; i++ {
+		if r.Direction[i] == 0 {
+			if r.Origin[i] < b.Min[i] || r.Origin[i] > b.Max[i] {
+				return 0, false
+			}
+			continue
+		}
+
+		inv := 1.0 / r.Direction[i]
+		t1 := (b.Min[i] - r.Origin[i]) * inv
+		t2 := (b.Max[i] - r.Origin[i]) * inv
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+
+		tmin = math.Max(tmin, t1)
+		tmax = math.Min(tmax, t2)
+		if tmin > tmax {
+			return 0, false
+		}
+	}
+
+	return tmin, true
+}
+
+// returns the distance along the ray to the first intersection with the sphere
+func (r Ray) IntersectsSphere(center Vector, radius float64) (float64, bool) {
+	oc := Vector{center[0] - r.Origin[0], center[1] - r.Origin[1], center[2] - r.Origin[2]}
+	d := Vector{r.Direction[0], r.Direction[1], r.Direction[2]}
+
+	tca := oc.Dot(d)
+	d2 := oc.Dot(oc) - tca*tca
+	r2 := radius * radius
+	if d2 > r2 {
+		return 0, false
+	}
+
+	thc := math.Sqrt(r2 - d2)
+	t0, t1 := tca-thc, tca+thc
+	if t1 < 0 {
+		// sphere behind origin
+		return 0, false
+	}
+	if t0 < 0 {
+		// origin inside sphere
+		return 0, true
+	}
+
+	return t0, true
+}
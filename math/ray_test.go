@@ -0,0 +1,91 @@
+package math
+
+import (
+	"testing"
+)
+
+func TestNewRay(t *testing.T) {
+	tests := []struct {
+		Origin, Direction Vector
+		Expected          Ray
+	}{
+		{
+			Vector{0, 0, 0}, Vector{0, 0, 5},
+			Ray{Vector{0, 0, 0, 1}, Vector{0, 0, 1, 0}},
+		},
+		{
+			Vector{1, 2, 3, 0}, Vector{3, 0, 4, 1},
+			Ray{Vector{1, 2, 3, 1}, Vector{0.6, 0, 0.8, 0}},
+		},
+	}
+
+	for _, c := range tests {
+		if r := NewRay(c.Origin, c.Direction); !r.Origin.Equals(c.Expected.Origin, 6) || !r.Direction.Equals(c.Expected.Direction, 6) {
+			t.Errorf("NewRay(%v, %v) != %v (got %v)", c.Origin, c.Direction, c.Expected, r)
+		}
+	}
+}
+
+func TestRay_At(t *testing.T) {
+	r := NewRay(Vector{1, 0, 0}, Vector{0, 1, 0})
+
+	tests := []struct {
+		T        float64
+		Expected Vector
+	}{
+		{0, Vector{1, 0, 0, 1}},
+		{2.5, Vector{1, 2.5, 0, 1}},
+		{-1, Vector{1, -1, 0, 1}},
+	}
+
+	for _, c := range tests {
+		if p := r.At(c.T); !p.Equals(c.Expected, 6) {
+			t.Errorf("Ray(%v).At(%v) != %v (got %v)", r, c.T, c.Expected, p)
+		}
+	}
+}
+
+func TestRay_IntersectsBoundary(t *testing.T) {
+	b := BoundaryFromPoints(Vector{-1, -1, -1}, Vector{1, 1, 1})
+
+	tests := []struct {
+		R           Ray
+		ExpDistance float64
+		ExpHit      bool
+	}{
+		{NewRay(Vector{0, 0, -5}, Vector{0, 0, 1}), 4, true},
+		{NewRay(Vector{0, 0, 0}, Vector{0, 0, 1}), 0, true},
+		{NewRay(Vector{-5, 0.5, 0}, Vector{1, 0, 0}), 4, true},
+		{NewRay(Vector{0, 0, -5}, Vector{0, 0, -1}), 0, false},
+		{NewRay(Vector{0, 2, -5}, Vector{0, 0, 1}), 0, false},
+		{NewRay(Vector{-5, -5, -5}, Vector{1, 1, 1}), 4 * 1.7320508075688772, true},
+	}
+
+	for _, c := range tests {
+		if d, hit := c.R.IntersectsBoundary(b); hit != c.ExpHit || !NearlyEquals(d, c.ExpDistance, 0.000001) {
+			t.Errorf("Ray(%v).IntersectsBoundary(%v) != %v, %v (got %v, %v)", c.R, b, c.ExpDistance, c.ExpHit, d, hit)
+		}
+	}
+}
+
+func TestRay_IntersectsSphere(t *testing.T) {
+	center, radius := Vector{0, 0, 0, 1}, 1.0
+
+	tests := []struct {
+		R           Ray
+		ExpDistance float64
+		ExpHit      bool
+	}{
+		{NewRay(Vector{0, 0, -5}, Vector{0, 0, 1}), 4, true},
+		{NewRay(Vector{0, 0, 0}, Vector{1, 0, 0}), 0, true},
+		{NewRay(Vector{0, 0, -5}, Vector{0, 0, -1}), 0, false},
+		{NewRay(Vector{0, 1.5, -5}, Vector{0, 0, 1}), 0, false},
+		{NewRay(Vector{0, 1, -5}, Vector{0, 0, 1}), 5, true},
+	}
+
+	for _, c := range tests {
+		if d, hit := c.R.IntersectsSphere(center, radius); hit != c.ExpHit || !NearlyEquals(d, c.ExpDistance, 0.000001) {
+			t.Errorf("Ray(%v).IntersectsSphere(%v, %v) != %v, %v (got %v, %v)", c.R, center, radius, c.ExpDistance, c.ExpHit, d, hit)
+		}
+	}
+}
@@ -151,5 +151,6 @@ func (self Vector) DistanceTo(v Vector) float64 {
 }
 
 func (self Vector) DistanceToSquared(v Vector) float64 {
-	return self.Dot(v)
+	d := self.Sub(v)
+	return d.Dot(d)
 }
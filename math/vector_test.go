@@ -315,10 +315,29 @@ func BenchmarkVector_MulScalar(b *testing.B) {
 	}
 }
 
+func TestVector_DistanceTo(t *testing.T) {
+	tests := []struct {
+		A, B     Vector
+		Expected float64
+	}{
+		{Vector{0, 0, 0, 0}, Vector{0, 0, 0, 0}, 0},
+		{Vector{1, 0, 0, 0}, Vector{0, 0, 0, 0}, 1},
+		{Vector{1, 2, 3, 0}, Vector{4, 6, 3, 0}, 5},
+		{Vector{-1, -1, -1, 0}, Vector{1, 1, 1, 0}, math.Sqrt(12)},
+	}
+
+	for _, c := range tests {
+		if r := c.A.DistanceTo(c.B); !NearlyEquals(r, c.Expected, 1e-6) {
+			t.Errorf("Vector(%v).DistanceTo(Vector(%v)) != %v (got %v)", c.A, c.B, c.Expected, r)
+		}
+		if r := c.A.DistanceToSquared(c.B); !NearlyEquals(r, c.Expected*c.Expected, 1e-6) {
+			t.Errorf("Vector(%v).DistanceToSquared(Vector(%v)) != %v (got %v)", c.A, c.B, c.Expected*c.Expected, r)
+		}
+	}
+}
+
 // TODO:
-func TestVector_String(t *testing.T)            {}
-func TestVector_Float64(t *testing.T)           {}
-func TestVector_Float32(t *testing.T)           {}
-func TestVector_Clamp(t *testing.T)             {}
-func TestVector_DistanceTo(t *testing.T)        {}
-func TestVector_DistanceToSquared(t *testing.T) {}
+func TestVector_String(t *testing.T)  {}
+func TestVector_Float64(t *testing.T) {}
+func TestVector_Float32(t *testing.T) {}
+func TestVector_Clamp(t *testing.T)   {}